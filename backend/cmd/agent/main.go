@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"devops/internal/agent"
+)
+
+func main() {
+	var cfg agent.Config
+	flag.StringVar(&cfg.Server, "server", os.Getenv("DEVOPS_AGENT_SERVER"), "后端地址，如 https://devops.example.com")
+	flag.StringVar(&cfg.Credential, "credential", os.Getenv("DEVOPS_AGENT_CREDENTIAL"), "代理凭证，为空时从凭证文件读取或使用注册令牌注册")
+	flag.StringVar(&cfg.EnrollToken, "enroll-token", os.Getenv("DEVOPS_AGENT_ENROLL_TOKEN"), "注册令牌，首次启动时换取代理凭证")
+	flag.StringVar(&cfg.Host, "host", os.Getenv("DEVOPS_AGENT_HOST"), "注册时上报的服务器地址，默认为连接后端的本机地址")
+	flag.StringVar(&cfg.StateFile, "state-file", "/var/lib/devops-agent/credential", "注册后保存凭证的文件")
+	flag.DurationVar(&cfg.Interval, "interval", 30*time.Second, "采集间隔")
+	flag.DurationVar(&cfg.HeartbeatInterval, "heartbeat", time.Minute, "心跳间隔")
+	flag.IntVar(&cfg.BufferSize, "buffer", 2880, "后端不可达时最多缓存的样本数")
+	flag.IntVar(&cfg.BatchSize, "batch", 500, "单次上报的最大样本数")
+	flag.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "单次请求的超时时间")
+	flag.Parse()
+
+	a, err := agent.New(cfg)
+	if err != nil {
+		log.Fatalf("启动监控代理失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("监控代理 %s 已启动，每 %s 上报到 %s", agent.Version, cfg.Interval, cfg.Server)
+	if err := a.Run(ctx); err != nil {
+		log.Fatalf("监控代理运行失败: %v", err)
+	}
+	log.Println("监控代理已停止")
+}
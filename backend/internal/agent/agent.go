@@ -0,0 +1,360 @@
+// Package agent 部署在被管理主机上的监控代理
+//
+// 代理首次启动时用注册令牌换取自己的凭证并保存到本地，之后使用凭证通信。
+// 代理使用 monitor 包的本机采集器定时采集系统指标推送到后端，
+// 后端不可达时样本缓存在内存中，恢复后按采集顺序补报。
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"devops/internal/monitor"
+)
+
+// 后端代理接口路径
+const (
+	EnrollPath    = "/api/agent/enroll"
+	HeartbeatPath = "/api/agent/heartbeat"
+	IngestPath    = "/api/agent/metrics"
+)
+
+// Version 代理版本，构建时通过 -ldflags "-X devops/internal/agent.Version=..." 设置
+var Version = "dev"
+
+// capabilities 代理支持的功能
+var capabilities = []string{"metrics"}
+
+// 上报失败后的重试间隔，从 minRetryDelay 开始翻倍，不超过采集间隔
+const minRetryDelay = 5 * time.Second
+
+// errRejected 后端拒绝了请求的数据，重试也不会成功
+var errRejected = errors.New("后端拒绝了请求的数据")
+
+// errUnauthorized 注册令牌或代理凭证无效
+var errUnauthorized = errors.New("注册令牌或代理凭证无效")
+
+// Config 代理配置
+type Config struct {
+	Server            string        // 后端地址，如 https://devops.example.com
+	Credential        string        // 代理凭证，为空时从 StateFile 读取或用注册令牌注册
+	EnrollToken       string        // 注册令牌
+	Host              string        // 注册时上报的服务器地址，为空时使用连接后端的本机地址
+	StateFile         string        // 注册后保存凭证的文件
+	Interval          time.Duration // 采集间隔
+	HeartbeatInterval time.Duration // 心跳间隔
+	BufferSize        int           // 后端不可达时最多缓存的样本数，超出时丢弃最旧的样本
+	BatchSize         int           // 单次上报的最大样本数
+	Timeout           time.Duration // 单次请求的超时时间
+}
+
+// Agent 监控代理
+type Agent struct {
+	config    Config
+	collector *monitor.Collector
+	client    *http.Client
+	buffer    []monitor.SystemMetrics
+}
+
+// New 创建监控代理
+func New(cfg Config) (*Agent, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("未配置后端地址")
+	}
+	if cfg.Credential == "" && cfg.EnrollToken == "" && cfg.StateFile == "" {
+		return nil, errors.New("未配置代理凭证或注册令牌")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = time.Minute
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 2880
+	}
+	if cfg.BatchSize <= 0 || cfg.BatchSize > monitor.MaxIngestBatch {
+		cfg.BatchSize = monitor.MaxIngestBatch
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	cfg.Server = strings.TrimRight(cfg.Server, "/")
+
+	return &Agent{
+		config:    cfg,
+		collector: monitor.NewCollector(0),
+		client:    &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Run 运行代理直到 ctx 取消
+func (a *Agent) Run(ctx context.Context) error {
+	if err := a.loadCredential(ctx); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+	heartbeat := time.NewTicker(a.config.HeartbeatInterval)
+	defer heartbeat.Stop()
+	a.heartbeat(ctx)
+
+	retry := time.NewTimer(0)
+	retry.Stop()
+	delay := minRetryDelay
+
+	a.collect(ctx)
+	for {
+		if err := a.flush(ctx); err != nil {
+			log.Printf("上报监控数据失败，已缓存 %d 个样本，%s 后重试: %v", len(a.buffer), delay, err)
+			retry.Reset(delay)
+			delay = min(delay*2, a.config.Interval)
+		} else {
+			retry.Stop()
+			delay = minRetryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			a.collect(ctx)
+		case <-heartbeat.C:
+			a.heartbeat(ctx)
+		case <-retry.C:
+		}
+	}
+}
+
+// collect 采集一个样本放入缓存
+func (a *Agent) collect(ctx context.Context) {
+	metrics, err := a.collector.CollectSystemMetrics(ctx)
+	if err != nil {
+		log.Printf("采集监控数据失败: %v", err)
+		return
+	}
+	a.add(*metrics)
+}
+
+// add 将样本放入缓存，超出容量时丢弃最旧的样本
+func (a *Agent) add(metrics monitor.SystemMetrics) {
+	a.buffer = append(a.buffer, metrics)
+	if over := len(a.buffer) - a.config.BufferSize; over > 0 {
+		log.Printf("缓存已满，丢弃最旧的 %d 个样本", over)
+		a.buffer = append(a.buffer[:0], a.buffer[over:]...)
+	}
+}
+
+// flush 按批次上报缓存中的样本，遇到失败时停止，未上报的样本留在缓存中
+func (a *Agent) flush(ctx context.Context) error {
+	for len(a.buffer) > 0 {
+		n := min(len(a.buffer), a.config.BatchSize)
+		if err := a.push(ctx, a.buffer[:n]); err != nil {
+			if !errors.Is(err, errRejected) {
+				return err
+			}
+			log.Printf("丢弃 %d 个样本: %v", n, err)
+		}
+		a.buffer = append(a.buffer[:0], a.buffer[n:]...)
+	}
+	return nil
+}
+
+// ingestRequest 上报请求，与后端 api.IngestMetricsRequest 一致
+type ingestRequest struct {
+	Samples []monitor.SystemMetrics `json:"samples"`
+}
+
+// push 上报一批样本
+func (a *Agent) push(ctx context.Context, samples []monitor.SystemMetrics) error {
+	return a.post(ctx, IngestPath, ingestRequest{Samples: samples}, nil)
+}
+
+// agentInfo 代理注册和心跳时上报的自身信息，与后端 api.AgentInfoRequest 一致
+type agentInfo struct {
+	Hostname     string   `json:"hostname"`
+	Version      string   `json:"version"`
+	OS           string   `json:"os"`
+	Arch         string   `json:"arch"`
+	Capabilities []string `json:"capabilities"`
+}
+
+func newAgentInfo() agentInfo {
+	hostname, _ := os.Hostname()
+	return agentInfo{
+		Hostname:     hostname,
+		Version:      Version,
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Capabilities: capabilities,
+	}
+}
+
+// heartbeat 发送心跳，失败时只记录日志
+func (a *Agent) heartbeat(ctx context.Context) {
+	if err := a.post(ctx, HeartbeatPath, newAgentInfo(), nil); err != nil {
+		log.Printf("发送心跳失败: %v", err)
+	}
+}
+
+// enrollRequest 注册请求，与后端 api.EnrollAgentRequest 一致
+type enrollRequest struct {
+	agentInfo
+	Token string `json:"token"`
+	Host  string `json:"host"`
+}
+
+// loadCredential 确定代理凭证：优先使用配置，其次读取状态文件，都没有时用注册令牌注册
+// 注册失败时按退避间隔重试，直到成功或 ctx 取消
+func (a *Agent) loadCredential(ctx context.Context) error {
+	if a.config.Credential != "" {
+		return nil
+	}
+	if a.config.StateFile != "" {
+		data, err := os.ReadFile(a.config.StateFile)
+		if err == nil && strings.TrimSpace(string(data)) != "" {
+			a.config.Credential = strings.TrimSpace(string(data))
+			return nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("读取凭证文件失败: %w", err)
+		}
+	}
+	if a.config.EnrollToken == "" {
+		return errors.New("没有代理凭证，且未配置注册令牌")
+	}
+
+	delay := minRetryDelay
+	for {
+		err := a.enroll(ctx)
+		if err == nil || errors.Is(err, errRejected) || errors.Is(err, errUnauthorized) {
+			return err
+		}
+		log.Printf("注册失败，%s 后重试: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, a.config.Interval)
+	}
+}
+
+// enroll 用注册令牌换取凭证并保存到状态文件
+func (a *Agent) enroll(ctx context.Context) error {
+	host := a.config.Host
+	if host == "" {
+		var err error
+		if host, err = localAddress(a.config.Server); err != nil {
+			return fmt.Errorf("获取本机地址失败: %w", err)
+		}
+	}
+
+	var resp struct {
+		Data struct {
+			AgentID    uint   `json:"agent_id"`
+			ServerID   uint   `json:"server_id"`
+			Credential string `json:"credential"`
+		} `json:"data"`
+	}
+	req := enrollRequest{agentInfo: newAgentInfo(), Token: a.config.EnrollToken, Host: host}
+	if err := a.post(ctx, EnrollPath, req, &resp); err != nil {
+		return err
+	}
+	if resp.Data.Credential == "" {
+		return errors.New("后端未返回代理凭证")
+	}
+
+	a.config.Credential = resp.Data.Credential
+	if a.config.StateFile != "" {
+		if err := os.MkdirAll(filepath.Dir(a.config.StateFile), 0o700); err != nil {
+			return fmt.Errorf("%w: 创建凭证目录失败: %v", errRejected, err)
+		}
+		if err := os.WriteFile(a.config.StateFile, []byte(resp.Data.Credential+"\n"), 0o600); err != nil {
+			return fmt.Errorf("%w: 保存凭证失败: %v", errRejected, err)
+		}
+	}
+	log.Printf("注册成功，代理 %d 关联服务器 %d", resp.Data.AgentID, resp.Data.ServerID)
+	return nil
+}
+
+// localAddress 返回连接后端时使用的本机地址
+func localAddress(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	// UDP 不会真正发送数据，只用于确定路由选择的本机地址
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// post 向后端发送请求，out 不为空时解析响应
+// 后端返回 400 时返回 errRejected，返回 401 时返回 errUnauthorized
+func (a *Agent) post(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.Server+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.config.Credential != "" {
+		req.Header.Set("Authorization", "Bearer "+a.config.Credential)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("后端返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		switch resp.StatusCode {
+		case http.StatusBadRequest:
+			return fmt.Errorf("%w: %v", errRejected, err)
+		case http.StatusUnauthorized:
+			return fmt.Errorf("%w: %v", errUnauthorized, err)
+		}
+		return err
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"devops/internal/monitor"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sample(i int) monitor.SystemMetrics {
+	return monitor.SystemMetrics{
+		Timestamp: time.Unix(int64(i), 0),
+		Processes: i,
+	}
+}
+
+func TestAgentBufferDropsOldest(t *testing.T) {
+	a, err := New(Config{Server: "http://localhost", Credential: "t", BufferSize: 3})
+	require.NoError(t, err)
+
+	for i := 1; i <= 5; i++ {
+		a.add(sample(i))
+	}
+	require.Len(t, a.buffer, 3)
+	assert.Equal(t, 3, a.buffer[0].Processes)
+	assert.Equal(t, 5, a.buffer[2].Processes)
+}
+
+func TestAgentFlush(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	var received [][]monitor.SystemMetrics
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, IngestPath, r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req ingestRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if status == http.StatusOK {
+			received = append(received, req.Samples)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	a, err := New(Config{Server: server.URL + "/", Credential: "secret", BatchSize: 2})
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		a.add(sample(i))
+	}
+	ctx := context.Background()
+
+	// 后端不可用时样本保留在缓存中
+	assert.Error(t, a.flush(ctx))
+	assert.Len(t, a.buffer, 3)
+
+	// 恢复后按批次依次上报
+	status = http.StatusOK
+	require.NoError(t, a.flush(ctx))
+	assert.Empty(t, a.buffer)
+	require.Len(t, received, 2)
+	assert.Len(t, received[0], 2)
+	assert.Equal(t, 1, received[0][0].Processes)
+	assert.Equal(t, 3, received[1][0].Processes)
+
+	// 被拒绝的样本直接丢弃，不阻塞后续上报
+	status = http.StatusBadRequest
+	a.add(sample(4))
+	require.NoError(t, a.flush(ctx))
+	assert.Empty(t, a.buffer)
+}
+
+func TestAgentEnroll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, EnrollPath, r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		var req enrollRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Token != "enroll" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "10.0.0.5", req.Host)
+		assert.Equal(t, Version, req.Version)
+		assert.Contains(t, req.Capabilities, "metrics")
+		w.Write([]byte(`{"code":200,"data":{"agent_id":1,"server_id":2,"credential":"issued"}}`))
+	}))
+	defer server.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "agent", "credential")
+	a, err := New(Config{Server: server.URL, EnrollToken: "enroll", Host: "10.0.0.5", StateFile: stateFile})
+	require.NoError(t, err)
+	require.NoError(t, a.loadCredential(context.Background()))
+	assert.Equal(t, "issued", a.config.Credential)
+
+	// 再次启动时从凭证文件读取，不再注册
+	b, err := New(Config{Server: "http://127.0.0.1:1", StateFile: stateFile})
+	require.NoError(t, err)
+	require.NoError(t, b.loadCredential(context.Background()))
+	assert.Equal(t, "issued", b.config.Credential)
+
+	// 注册令牌无效时不再重试
+	c, err := New(Config{Server: server.URL, EnrollToken: "wrong", Host: "10.0.0.5"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.loadCredential(context.Background()), errUnauthorized)
+}
+
+func TestNewAgentRequiresServerAndCredential(t *testing.T) {
+	_, err := New(Config{Credential: "t"})
+	assert.Error(t, err)
+	_, err = New(Config{Server: "http://localhost"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops/internal/model"
+	"devops/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// agentItem 代理列表项，附带当前是否在线
+type agentItem struct {
+	model.Agent
+	Online bool `json:"online"`
+}
+
+// agentInfo 将请求中的代理信息转换为服务层结构
+func agentInfo(c *gin.Context, req AgentInfoRequest) monitor.AgentInfo {
+	return monitor.AgentInfo{
+		Hostname:     req.Hostname,
+		Version:      req.Version,
+		OS:           req.OS,
+		Arch:         req.Arch,
+		Capabilities: req.Capabilities,
+		IP:           c.ClientIP(),
+	}
+}
+
+// authenticateAgent 校验请求携带的代理凭证，失败时写入响应
+func (h *MonitorHandler) authenticateAgent(c *gin.Context) (*model.Agent, bool) {
+	credential := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	agent, err := h.monitorService.AuthenticateAgent(credential)
+	if err != nil {
+		if errors.Is(err, monitor.ErrInvalidAgentCredential) {
+			c.JSON(http.StatusUnauthorized, Response{
+				Code:    401,
+				Message: err.Error(),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	return agent, true
+}
+
+// EnrollAgent 代理用注册令牌换取凭证
+func (h *MonitorHandler) EnrollAgent(c *gin.Context) {
+	var req EnrollAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := h.monitorService.Enroll(req.Token, req.Host, agentInfo(c, req.AgentInfoRequest))
+	if err != nil {
+		if errors.Is(err, monitor.ErrInvalidEnrollmentToken) {
+			c.JSON(http.StatusUnauthorized, Response{
+				Code:    401,
+				Message: err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, monitor.ErrEnrollmentDenied) {
+			c.JSON(http.StatusForbidden, Response{
+				Code:    403,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 凭证明文只返回这一次
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "注册成功",
+		Data:    enrollment,
+	})
+}
+
+// AgentHeartbeat 接收代理心跳
+func (h *MonitorHandler) AgentHeartbeat(c *gin.Context) {
+	agent, ok := h.authenticateAgent(c)
+	if !ok {
+		return
+	}
+
+	var req AgentInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.monitorService.Heartbeat(agent, agentInfo(c, req)); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "心跳已记录",
+	})
+}
+
+// IngestMetrics 接收监控代理上报的数据
+func (h *MonitorHandler) IngestMetrics(c *gin.Context) {
+	agent, ok := h.authenticateAgent(c)
+	if !ok {
+		return
+	}
+
+	var req IngestMetricsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.monitorService.Ingest(c.Request.Context(), agent, c.ClientIP(), req.Samples); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "上报成功",
+	})
+}
+
+// ListAgents 获取监控代理列表
+func (h *MonitorHandler) ListAgents(c *gin.Context) {
+	agents, err := h.monitorService.ListAgents()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	items := make([]agentItem, len(agents))
+	for i := range agents {
+		items[i] = agentItem{
+			Agent:  agents[i],
+			Online: monitor.AgentOnline(&agents[i], now),
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    items,
+	})
+}
+
+// RevokeAgent 吊销监控代理的凭证
+func (h *MonitorHandler) RevokeAgent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的代理ID",
+		})
+		return
+	}
+
+	if err := h.monitorService.RevokeAgent(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "代理凭证已吊销",
+	})
+}
+
+// IssueAgentCredential 直接为服务器生成监控代理凭证
+func (h *MonitorHandler) IssueAgentCredential(c *gin.Context) {
+	serverID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的服务器ID",
+		})
+		return
+	}
+
+	enrollment, err := h.monitorService.IssueAgentCredential(uint(serverID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 凭证明文只返回这一次
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "代理凭证已生成",
+		Data:    enrollment,
+	})
+}
+
+// ListEnrollmentTokens 获取代理注册令牌列表
+func (h *MonitorHandler) ListEnrollmentTokens(c *gin.Context) {
+	tokens, err := h.monitorService.ListEnrollmentTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    tokens,
+	})
+}
+
+// CreateEnrollmentToken 创建代理注册令牌
+func (h *MonitorHandler) CreateEnrollmentToken(c *gin.Context) {
+	var req CreateEnrollmentTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	token := &model.AgentEnrollmentToken{
+		Name:        req.Name,
+		Group:       req.Group,
+		Environment: req.Environment,
+		MaxUses:     req.MaxUses,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   c.GetUint("user_id"),
+	}
+	secret, err := h.monitorService.CreateEnrollmentToken(token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 令牌明文只返回这一次
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "注册令牌创建成功",
+		Data: map[string]interface{}{
+			"enrollment_token": token,
+			"token":            secret,
+		},
+	})
+}
+
+// RevokeEnrollmentToken 吊销代理注册令牌
+func (h *MonitorHandler) RevokeEnrollmentToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的注册令牌ID",
+		})
+		return
+	}
+
+	if err := h.monitorService.RevokeEnrollmentToken(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "注册令牌已吊销",
+	})
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"devops/internal/model"
+	"devops/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAlertRules 获取告警规则列表
+func (h *MonitorHandler) ListAlertRules(c *gin.Context) {
+	rules, err := h.monitorService.ListAlertRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    rules,
+	})
+}
+
+// GetAlertRule 获取告警规则详情
+func (h *MonitorHandler) GetAlertRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的告警规则ID",
+		})
+		return
+	}
+
+	rule, err := h.monitorService.GetAlertRule(uint(id))
+	if err != nil {
+		if errors.Is(err, monitor.ErrAlertRuleNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    rule,
+	})
+}
+
+// CreateAlertRule 创建告警规则
+func (h *MonitorHandler) CreateAlertRule(c *gin.Context) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rule := req.toModel()
+	rule.CreatedBy = c.GetUint("user_id")
+	if err := h.monitorService.CreateAlertRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "告警规则创建成功",
+		Data:    rule,
+	})
+}
+
+// UpdateAlertRule 更新告警规则
+func (h *MonitorHandler) UpdateAlertRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的告警规则ID",
+		})
+		return
+	}
+
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.monitorService.UpdateAlertRule(uint(id), req.toModel()); err != nil {
+		if errors.Is(err, monitor.ErrAlertRuleNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "告警规则更新成功",
+	})
+}
+
+// DeleteAlertRule 删除告警规则
+func (h *MonitorHandler) DeleteAlertRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的告警规则ID",
+		})
+		return
+	}
+
+	if err := h.monitorService.DeleteAlertRule(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "告警规则删除成功",
+	})
+}
+
+// ListFiringAlerts 获取触发中的告警，可按服务器过滤
+func (h *MonitorHandler) ListFiringAlerts(c *gin.Context) {
+	var serverID uint64
+	if v := c.Query("server_id"); v != "" {
+		var err error
+		if serverID, err = strconv.ParseUint(v, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Code:    400,
+				Message: "无效的服务器ID",
+			})
+			return
+		}
+	}
+
+	alerts, err := h.monitorService.ListFiringAlerts(uint(serverID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    alerts,
+	})
+}
+
+// ListAlerts 分页查询告警历史
+func (h *MonitorHandler) ListAlerts(c *gin.Context) {
+	var pageReq PageRequest
+	var query AlertQuery
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	filter := monitor.AlertFilter{
+		Status:   query.Status,
+		ServerID: query.ServerID,
+		RuleID:   query.RuleID,
+		Level:    query.Level,
+		Start:    query.Start,
+		End:      query.End,
+	}
+	alerts, total, err := h.monitorService.ListAlerts(filter, pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     alerts,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// GetAlert 获取告警详情
+func (h *MonitorHandler) GetAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的告警ID",
+		})
+		return
+	}
+
+	alert, err := h.monitorService.GetAlert(uint(id))
+	if err != nil {
+		if errors.Is(err, monitor.ErrAlertNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    alert,
+	})
+}
+
+// AcknowledgeAlert 确认告警
+func (h *MonitorHandler) AcknowledgeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的告警ID",
+		})
+		return
+	}
+
+	if err := h.monitorService.AcknowledgeAlert(uint(id), c.GetUint("user_id")); err != nil {
+		if errors.Is(err, monitor.ErrAlertNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "告警已确认",
+	})
+}
+
+// toModel 转换为告警规则模型，未指定启用状态时默认启用
+func (r *AlertRuleRequest) toModel() *model.AlertRule {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.AlertRule{
+		Name:             r.Name,
+		Scope:            r.Scope,
+		ServerID:         r.ServerID,
+		Group:            r.Group,
+		MetricType:       r.MetricType,
+		Mountpoint:       r.Mountpoint,
+		Condition:        r.Condition,
+		Threshold:        r.Threshold,
+		Duration:         r.Duration,
+		Level:            r.Level,
+		Enabled:          enabled,
+		ChannelIDs:       r.ChannelIDs,
+		NotifyEmail:      r.NotifyEmail,
+		NotifyWebhook:    r.NotifyWebhook,
+		WebhookChannelID: r.WebhookChannelID,
+		TitleTemplate:    r.TitleTemplate,
+		BodyTemplate:     r.BodyTemplate,
+	}
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// DeploymentHandler 部署处理器
+type DeploymentHandler struct {
+	deploymentService *service.DeploymentService
+}
+
+// NewDeploymentHandler 创建部署处理器
+func NewDeploymentHandler(db *gorm.DB, rdb *redis.Client, cfg config.Deploy) *DeploymentHandler {
+	return &DeploymentHandler{
+		deploymentService: service.NewDeploymentService(db, rdb, cfg),
+	}
+}
+
+// List 获取部署列表
+func (h *DeploymentHandler) List(c *gin.Context) {
+	var pageReq PageRequest
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	deployments, total, err := h.deploymentService.List(pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     deployments,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// Create 创建部署
+func (h *DeploymentHandler) Create(c *gin.Context) {
+	var req CreateDeploymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	deployment := &model.Deployment{
+		Name:          req.Name,
+		ServerID:      req.ServerID,
+		Repository:    req.Repository,
+		Branch:        req.Branch,
+		Path:          req.Path,
+		Script:        req.Script,
+		ScriptID:      req.ScriptID,
+		ScriptVersion: req.ScriptVersion,
+		Strategy:      req.Strategy,
+		Image:         req.Image,
+		ContainerName: req.ContainerName,
+		RunOptions:    req.RunOptions,
+		HealthTimeout: req.HealthTimeout,
+		CreatedBy:     c.GetUint("user_id"),
+	}
+	if deployment.Branch == "" {
+		deployment.Branch = "main"
+	}
+
+	if err := h.deploymentService.Create(deployment); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "部署创建成功",
+		Data:    deployment,
+	})
+}
+
+// GetByID 根据ID获取部署
+func (h *DeploymentHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的部署ID",
+		})
+		return
+	}
+
+	deployment, err := h.deploymentService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    deployment,
+	})
+}
+
+// Trigger 触发部署
+func (h *DeploymentHandler) Trigger(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的部署ID",
+		})
+		return
+	}
+
+	// 请求体可选
+	var req TriggerDeploymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	overrideReason, ok := freezeOverride(c, req.OverrideFreeze, req.OverrideReason)
+	if !ok {
+		return
+	}
+
+	run, err := h.deploymentService.Trigger(uint(id), c.GetUint("user_id"), service.TriggerOptions{
+		Tag:            req.Tag,
+		OverrideReason: overrideReason,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrDeploymentFrozen) {
+			c.JSON(http.StatusForbidden, Response{
+				Code:    403,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "部署已触发",
+		Data:    run,
+	})
+}
+
+// Rollback 回滚到上一个镜像标签
+func (h *DeploymentHandler) Rollback(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的部署ID",
+		})
+		return
+	}
+
+	var req TriggerDeploymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	overrideReason, ok := freezeOverride(c, req.OverrideFreeze, req.OverrideReason)
+	if !ok {
+		return
+	}
+
+	run, err := h.deploymentService.Rollback(uint(id), c.GetUint("user_id"), overrideReason)
+	if err != nil {
+		if errors.Is(err, service.ErrDeploymentFrozen) {
+			c.JSON(http.StatusForbidden, Response{
+				Code:    403,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "回滚已触发",
+		Data:    run,
+	})
+}
+
+// Preview 预览部署变更
+func (h *DeploymentHandler) Preview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的部署ID",
+		})
+		return
+	}
+
+	preview, err := h.deploymentService.Preview(c.Request.Context(), uint(id), c.Query("ref"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "获取变更预览失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    preview,
+	})
+}
+
+// ListRuns 获取部署运行记录
+func (h *DeploymentHandler) ListRuns(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的部署ID",
+		})
+		return
+	}
+
+	var pageReq PageRequest
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	runs, total, err := h.deploymentService.ListRuns(uint(id), pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     runs,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// GetRun 获取部署运行详情及日志
+func (h *DeploymentHandler) GetRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的运行ID",
+		})
+		return
+	}
+
+	run, err := h.deploymentService.GetRun(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	logs, err := h.deploymentService.GetRunLogs(run.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: gin.H{
+			"run":  run,
+			"logs": logs,
+		},
+	})
+}
+
+// CancelRun 取消部署运行
+func (h *DeploymentHandler) CancelRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的运行ID",
+		})
+		return
+	}
+
+	run, err := h.deploymentService.Cancel(uint(id), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "部署已取消",
+		Data:    run,
+	})
+}
+
+// freezeOverride 校验冻结窗口覆盖请求，仅管理员可覆盖且必须提供理由
+func freezeOverride(c *gin.Context, override bool, reason string) (string, bool) {
+	if !override {
+		return "", true
+	}
+	if c.GetString("user_role") != "admin" {
+		c.JSON(http.StatusForbidden, Response{
+			Code:    403,
+			Message: "仅管理员可以在冻结窗口内强制部署",
+		})
+		return "", false
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "强制部署需要提供理由",
+		})
+		return "", false
+	}
+	return reason, true
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"devops/internal/model"
+	"devops/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FreezeHandler 部署冻结窗口处理器
+type FreezeHandler struct {
+	freezeService *service.FreezeService
+}
+
+// NewFreezeHandler 创建部署冻结窗口处理器
+func NewFreezeHandler(db *gorm.DB) *FreezeHandler {
+	return &FreezeHandler{
+		freezeService: service.NewFreezeService(db),
+	}
+}
+
+// freezeItem 冻结窗口列表项，附带当前是否生效
+type freezeItem struct {
+	model.DeploymentFreeze
+	Active bool `json:"active"`
+}
+
+// List 获取冻结窗口列表
+func (h *FreezeHandler) List(c *gin.Context) {
+	freezes, err := h.freezeService.List(c.Query("environment"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	items := make([]freezeItem, len(freezes))
+	for i := range freezes {
+		items[i] = freezeItem{
+			DeploymentFreeze: freezes[i],
+			Active:           service.FreezeActive(&freezes[i], now),
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    items,
+	})
+}
+
+// Create 创建冻结窗口
+func (h *FreezeHandler) Create(c *gin.Context) {
+	var req DeploymentFreezeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	freeze := req.toModel()
+	freeze.CreatedBy = c.GetUint("user_id")
+	if err := h.freezeService.Create(freeze); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "冻结窗口创建成功",
+		Data:    freeze,
+	})
+}
+
+// Update 更新冻结窗口
+func (h *FreezeHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的冻结窗口ID",
+		})
+		return
+	}
+
+	var req DeploymentFreezeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.freezeService.Update(uint(id), req.toModel()); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "冻结窗口更新成功",
+	})
+}
+
+// Delete 删除冻结窗口
+func (h *FreezeHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的冻结窗口ID",
+		})
+		return
+	}
+
+	if err := h.freezeService.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "冻结窗口删除成功",
+	})
+}
+
+// toModel 转换为冻结窗口模型，未指定启用状态时默认启用
+func (r *DeploymentFreezeRequest) toModel() *model.DeploymentFreeze {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.DeploymentFreeze{
+		Name:        r.Name,
+		Environment: r.Environment,
+		Type:        r.Type,
+		StartAt:     r.StartAt,
+		EndAt:       r.EndAt,
+		StartSpec:   r.StartSpec,
+		EndSpec:     r.EndSpec,
+		Timezone:    r.Timezone,
+		Reason:      r.Reason,
+		Enabled:     enabled,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"devops/internal/config"
 	"devops/internal/monitor"
 
 	"github.com/gin-gonic/gin"
@@ -17,9 +18,9 @@ type MonitorHandler struct {
 }
 
 // NewMonitorHandler 创建监控处理器
-func NewMonitorHandler(db *gorm.DB, rdb *redis.Client) *MonitorHandler {
+func NewMonitorHandler(db *gorm.DB, rdb *redis.Client, cfg config.Monitor) *MonitorHandler {
 	return &MonitorHandler{
-		monitorService: monitor.NewService(db, rdb),
+		monitorService: monitor.NewService(db, rdb, cfg),
 	}
 }
 
@@ -170,7 +171,13 @@ func (h *MonitorHandler) AddServerToMonitor(c *gin.Context) {
 		return
 	}
 
-	h.monitorService.AddServer(req.ServerID)
+	if err := h.monitorService.AddServer(req.ServerID); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, Response{
 		Code:    200,
@@ -212,47 +219,20 @@ func (h *MonitorHandler) GetServerHistory(c *gin.Context) {
 
 	// 获取查询参数
 	timeRange := c.DefaultQuery("time_range", "1h") // 1h, 6h, 24h, 7d
-	metric := c.DefaultQuery("metric", "cpu")        // cpu, memory, disk, network
+	metric := c.DefaultQuery("metric", "cpu")        // cpu, memory, disk, load, network, net_recv, net_sent
 
-	// 这里应该从数据库或时序数据库获取历史数据
-	// 暂时返回模拟数据
-	historyData := generateMockHistoryData(uint(serverID), timeRange, metric)
+	history, err := h.monitorService.GetServerHistory(uint(serverID), metric, timeRange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, Response{
 		Code:    200,
 		Message: "获取成功",
-		Data:    historyData,
+		Data:    history,
 	})
 }
-
-// generateMockHistoryData 生成模拟历史数据
-func generateMockHistoryData(serverID uint, timeRange, metric string) map[string]interface{} {
-	// 生成模拟时间序列数据
-	data := make([]map[string]interface{}, 0)
-
-	for i := 0; i < 20; i++ {
-		point := map[string]interface{}{
-			"timestamp": "2024-01-15 " + strconv.Itoa(10+i/2) + ":" + strconv.Itoa((i%2)*30) + ":00",
-		}
-
-		switch metric {
-		case "cpu":
-			point["value"] = 20 + (i % 10) * 5
-		case "memory":
-			point["value"] = 40 + (i % 8) * 6
-		case "disk":
-			point["value"] = 60 + (i % 5) * 3
-		case "network":
-			point["value"] = (i % 15) * 10
-		}
-
-		data = append(data, point)
-	}
-
-	return map[string]interface{}{
-		"server_id":  serverID,
-		"metric":     metric,
-		"time_range": timeRange,
-		"data":       data,
-	}
-}
\ No newline at end of file
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"devops/internal/model"
+	"devops/internal/notify"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// NotifyHandler 通知渠道处理器
+type NotifyHandler struct {
+	notifyService *notify.Service
+}
+
+// NewNotifyHandler 创建通知渠道处理器
+func NewNotifyHandler(db *gorm.DB, rdb *redis.Client) *NotifyHandler {
+	return &NotifyHandler{
+		notifyService: notify.NewService(db, rdb),
+	}
+}
+
+// ListChannels 获取通知渠道列表
+func (h *NotifyHandler) ListChannels(c *gin.Context) {
+	channels, err := h.notifyService.ListChannels()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    channels,
+	})
+}
+
+// CreateChannel 创建通知渠道
+func (h *NotifyHandler) CreateChannel(c *gin.Context) {
+	var req NotifyChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	channel := req.toModel()
+	channel.CreatedBy = c.GetUint("user_id")
+	if err := h.notifyService.CreateChannel(channel); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "通知渠道创建成功",
+		Data:    channel,
+	})
+}
+
+// UpdateChannel 更新通知渠道
+func (h *NotifyHandler) UpdateChannel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的通知渠道ID",
+		})
+		return
+	}
+
+	var req NotifyChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.notifyService.UpdateChannel(uint(id), req.toModel()); err != nil {
+		if errors.Is(err, notify.ErrChannelNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "通知渠道更新成功",
+	})
+}
+
+// DeleteChannel 删除通知渠道
+func (h *NotifyHandler) DeleteChannel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的通知渠道ID",
+		})
+		return
+	}
+
+	if err := h.notifyService.DeleteChannel(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "通知渠道删除成功",
+	})
+}
+
+// TestChannel 通过通知渠道发送测试消息，同步返回发送结果
+func (h *NotifyHandler) TestChannel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的通知渠道ID",
+		})
+		return
+	}
+
+	if err := h.notifyService.TestChannel(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, notify.ErrChannelNotFound) {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "发送测试消息失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "测试消息已发送",
+	})
+}
+
+// ListDeliveries 获取通知发送记录
+func (h *NotifyHandler) ListDeliveries(c *gin.Context) {
+	var pageReq PageRequest
+	var query NotifyDeliveryQuery
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	deliveries, total, err := h.notifyService.ListDeliveries(query.ChannelID, query.Status, pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     deliveries,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// toModel 转换为通知渠道模型，未指定启用状态时默认启用
+func (r *NotifyChannelRequest) toModel() *model.NotifyChannel {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &model.NotifyChannel{
+		Name:          r.Name,
+		Type:          r.Type,
+		Config:        r.Config,
+		RateLimit:     r.RateLimit,
+		Default:       r.Default,
+		Enabled:       enabled,
+		TitleTemplate: r.TitleTemplate,
+		BodyTemplate:  r.BodyTemplate,
+	}
+}
+
+// PreviewTemplate 使用示例告警预览通知模板的渲染结果
+func (h *NotifyHandler) PreviewTemplate(c *gin.Context) {
+	var req TemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	status := req.Status
+	if status == "" {
+		status = model.AlertStatusFiring
+	}
+	title, body, err := notify.Preview(notify.Template{Title: req.TitleTemplate, Body: req.BodyTemplate}, status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "预览成功",
+		Data: gin.H{
+			"title": title,
+			"body":  body,
+		},
+	})
+}
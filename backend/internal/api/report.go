@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"devops/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultReportDays 未指定时间范围时默认统计最近的天数
+const defaultReportDays = 30
+
+// maxReportDays 单次报表允许的最大天数
+const maxReportDays = 731
+
+// ReportHandler 报表处理器
+type ReportHandler struct {
+	reportService *service.ReportService
+}
+
+// NewReportHandler 创建报表处理器
+func NewReportHandler(db *gorm.DB) *ReportHandler {
+	return &ReportHandler{
+		reportService: service.NewReportService(db),
+	}
+}
+
+// Deployments 获取部署 DORA 指标报表
+func (h *ReportHandler) Deployments(c *gin.Context) {
+	var req DeploymentReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	today := time.Now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	end := today.AddDate(0, 0, 1)
+	if req.End != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.End, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Code:    400,
+				Message: "无效的结束日期",
+			})
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -defaultReportDays)
+	if req.Start != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.Start, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Code:    400,
+				Message: "无效的开始日期",
+			})
+			return
+		}
+		start = t
+	}
+	if !end.After(start) || end.Sub(start) > maxReportDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的时间范围",
+		})
+		return
+	}
+	if req.Bucket == "" {
+		req.Bucket = service.ReportBucketDay
+	}
+
+	report, err := h.reportService.DeploymentReport(service.DeploymentReportQuery{
+		Start:        start,
+		End:          end,
+		Bucket:       req.Bucket,
+		DeploymentID: req.DeploymentID,
+		Environment:  req.Environment,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    report,
+	})
+}
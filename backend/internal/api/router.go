@@ -21,7 +21,14 @@ func NewRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 	// 创建处理器
 	authHandler := NewAuthHandler(db, rdb, cfg.JWT.Secret)
 	userHandler := NewUserHandler(db, rdb)
-	monitorHandler := NewMonitorHandler(db, rdb)
+	monitorHandler := NewMonitorHandler(db, rdb, cfg.Monitor)
+	deploymentHandler := NewDeploymentHandler(db, rdb, cfg.Deploy)
+	freezeHandler := NewFreezeHandler(db)
+	reportHandler := NewReportHandler(db)
+	scriptHandler := NewScriptHandler(db)
+	taskHandler := NewTaskHandler(db, rdb, cfg.Task)
+	workflowHandler := NewWorkflowHandler(db, rdb, cfg.Task)
+	notifyHandler := NewNotifyHandler(db, rdb)
 
 	// 健康检查端点
 	router.GET("/health", func(c *gin.Context) {
@@ -41,6 +48,14 @@ func NewRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 			auth.POST("/refresh", authHandler.RefreshToken)
 		}
 
+		// 监控代理（使用注册令牌或代理凭证认证）
+		agent := api.Group("/agent")
+		{
+			agent.POST("/enroll", monitorHandler.EnrollAgent)
+			agent.POST("/heartbeat", monitorHandler.AgentHeartbeat)
+			agent.POST("/metrics", monitorHandler.IngestMetrics)
+		}
+
 		// 需要JWT验证的路由
 		protected := api.Group("")
 		protected.Use(middleware.JWTAuth(cfg.JWT.Secret))
@@ -78,23 +93,92 @@ func NewRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 			// 部署相关
 			deployments := protected.Group("/deployments")
 			{
-				deployments.GET("", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "deployments list"})
-				})
-				deployments.POST("", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "create deployment"})
-				})
+				deployments.GET("", deploymentHandler.List)
+				deployments.POST("", deploymentHandler.Create)
+				deployments.GET("/:id", deploymentHandler.GetByID)
+				deployments.GET("/:id/preview", deploymentHandler.Preview)
+				deployments.POST("/:id/trigger", deploymentHandler.Trigger)
+				deployments.POST("/:id/rollback", deploymentHandler.Rollback)
+				deployments.GET("/:id/runs", deploymentHandler.ListRuns)
+				deployments.GET("/runs/:id", deploymentHandler.GetRun)
+				deployments.POST("/runs/:id/cancel", deploymentHandler.CancelRun)
+
+				// 冻结窗口，管理员维护
+				deployments.GET("/freezes", freezeHandler.List)
+				adminFreezes := deployments.Group("/freezes")
+				adminFreezes.Use(middleware.RequireRole("admin"))
+				{
+					adminFreezes.POST("", freezeHandler.Create)
+					adminFreezes.PUT("/:id", freezeHandler.Update)
+					adminFreezes.DELETE("/:id", freezeHandler.Delete)
+				}
+			}
+
+			// 报表相关
+			reports := protected.Group("/reports")
+			{
+				reports.GET("/deployments", reportHandler.Deployments)
+			}
+
+			// 脚本库
+			scripts := protected.Group("/scripts")
+			{
+				scripts.GET("", scriptHandler.List)
+				scripts.POST("", scriptHandler.Create)
+				scripts.GET("/:id", scriptHandler.GetByID)
+				scripts.PUT("/:id", scriptHandler.Update)
+				scripts.DELETE("/:id", scriptHandler.Delete)
+				scripts.GET("/:id/usage", scriptHandler.Usage)
+				scripts.GET("/:id/versions", scriptHandler.ListVersions)
+				scripts.GET("/:id/versions/:version", scriptHandler.GetVersion)
+				scripts.GET("/:id/diff", scriptHandler.Diff)
 			}
 
 			// 任务相关
 			tasks := protected.Group("/tasks")
 			{
-				tasks.GET("", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "tasks list"})
-				})
-				tasks.POST("", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "create task"})
-				})
+				tasks.GET("", taskHandler.List)
+				tasks.POST("", taskHandler.Create)
+				tasks.GET("/preview", taskHandler.Preview)
+				tasks.GET("/:id", taskHandler.GetByID)
+				tasks.PUT("/:id", taskHandler.Update)
+				tasks.DELETE("/:id", taskHandler.Delete)
+				tasks.POST("/:id/enable", taskHandler.Enable)
+				tasks.POST("/:id/disable", taskHandler.Disable)
+				tasks.POST("/:id/run", taskHandler.Run)
+				tasks.GET("/:id/executions", taskHandler.ListExecutions)
+				tasks.GET("/executions/:id", taskHandler.GetExecution)
+				tasks.GET("/executions/:id/output/stream", taskHandler.StreamOutput)
+				tasks.GET("/executions/:id/output/download", taskHandler.DownloadOutput)
+			}
+
+			// 工作流相关
+			workflows := protected.Group("/workflows")
+			{
+				workflows.GET("", workflowHandler.List)
+				workflows.POST("", workflowHandler.Create)
+				workflows.GET("/:id", workflowHandler.GetByID)
+				workflows.PUT("/:id", workflowHandler.Update)
+				workflows.DELETE("/:id", workflowHandler.Delete)
+				workflows.POST("/:id/enable", workflowHandler.Enable)
+				workflows.POST("/:id/disable", workflowHandler.Disable)
+				workflows.POST("/:id/run", workflowHandler.Run)
+				workflows.GET("/:id/runs", workflowHandler.ListRuns)
+				workflows.GET("/runs/:id", workflowHandler.GetRun)
+				workflows.POST("/runs/:id/rerun", workflowHandler.Rerun)
+			}
+
+			// 通知渠道，管理员维护
+			notifyGroup := protected.Group("/notify")
+			notifyGroup.Use(middleware.RequireRole("admin"))
+			{
+				notifyGroup.GET("/channels", notifyHandler.ListChannels)
+				notifyGroup.POST("/channels", notifyHandler.CreateChannel)
+				notifyGroup.PUT("/channels/:id", notifyHandler.UpdateChannel)
+				notifyGroup.DELETE("/channels/:id", notifyHandler.DeleteChannel)
+				notifyGroup.POST("/channels/:id/test", notifyHandler.TestChannel)
+				notifyGroup.GET("/deliveries", notifyHandler.ListDeliveries)
+				notifyGroup.POST("/templates/preview", notifyHandler.PreviewTemplate)
 			}
 
 			// 监控相关
@@ -107,6 +191,27 @@ func NewRouter(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				monitor.GET("/servers/:id/history", monitorHandler.GetServerHistory)
 				monitor.POST("/servers", monitorHandler.AddServerToMonitor)
 				monitor.DELETE("/servers/:id", monitorHandler.RemoveServerFromMonitor)
+				monitor.GET("/alert-rules", monitorHandler.ListAlertRules)
+				monitor.GET("/alert-rules/:id", monitorHandler.GetAlertRule)
+				monitor.GET("/alerts", monitorHandler.ListFiringAlerts)
+				monitor.GET("/alerts/history", monitorHandler.ListAlerts)
+				monitor.GET("/alerts/:id", monitorHandler.GetAlert)
+				monitor.POST("/alerts/:id/ack", monitorHandler.AcknowledgeAlert)
+
+				// 告警规则、监控代理和注册令牌，管理员维护
+				adminMonitor := monitor.Group("")
+				adminMonitor.Use(middleware.RequireRole("admin"))
+				{
+					adminMonitor.POST("/alert-rules", monitorHandler.CreateAlertRule)
+					adminMonitor.PUT("/alert-rules/:id", monitorHandler.UpdateAlertRule)
+					adminMonitor.DELETE("/alert-rules/:id", monitorHandler.DeleteAlertRule)
+					adminMonitor.GET("/agents", monitorHandler.ListAgents)
+					adminMonitor.DELETE("/agents/:id", monitorHandler.RevokeAgent)
+					adminMonitor.POST("/servers/:id/agent-credential", monitorHandler.IssueAgentCredential)
+					adminMonitor.GET("/enrollment-tokens", monitorHandler.ListEnrollmentTokens)
+					adminMonitor.POST("/enrollment-tokens", monitorHandler.CreateEnrollmentToken)
+					adminMonitor.DELETE("/enrollment-tokens/:id", monitorHandler.RevokeEnrollmentToken)
+				}
 			}
 		}
 	}
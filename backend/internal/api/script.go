@@ -0,0 +1,321 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"devops/internal/model"
+	"devops/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ScriptHandler 脚本库处理器
+type ScriptHandler struct {
+	scriptService *service.ScriptService
+}
+
+// NewScriptHandler 创建脚本库处理器
+func NewScriptHandler(db *gorm.DB) *ScriptHandler {
+	return &ScriptHandler{
+		scriptService: service.NewScriptService(db),
+	}
+}
+
+// List 获取脚本列表
+func (h *ScriptHandler) List(c *gin.Context) {
+	var pageReq PageRequest
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	scripts, total, err := h.scriptService.List(pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     scripts,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// Create 创建脚本
+func (h *ScriptHandler) Create(c *gin.Context) {
+	var req CreateScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	script := &model.Script{
+		Name:        req.Name,
+		Description: req.Description,
+		Interpreter: req.Interpreter,
+		Content:     req.Content,
+		Parameters:  taskParameters(req.Parameters),
+		CreatedBy:   c.GetUint("user_id"),
+	}
+	if err := h.scriptService.Create(script, req.Comment); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "脚本创建成功",
+		Data:    script,
+	})
+}
+
+// GetByID 根据ID获取脚本
+func (h *ScriptHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+
+	script, err := h.scriptService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    script,
+	})
+}
+
+// Update 更新脚本
+func (h *ScriptHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+
+	var req UpdateScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 构建更新字段
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Interpreter != "" {
+		updates["interpreter"] = req.Interpreter
+	}
+	if req.Content != "" {
+		updates["content"] = req.Content
+	}
+	if req.Parameters != nil {
+		updates["parameters"] = taskParameters(*req.Parameters)
+	}
+
+	script, err := h.scriptService.Update(uint(id), c.GetUint("user_id"), req.Comment, updates)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "更新成功",
+		Data:    script,
+	})
+}
+
+// Delete 删除脚本
+func (h *ScriptHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+
+	if err := h.scriptService.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// Usage 获取引用脚本的任务和部署
+func (h *ScriptHandler) Usage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+
+	usage, err := h.scriptService.Usage(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    usage,
+	})
+}
+
+// ListVersions 获取脚本的版本历史
+func (h *ScriptHandler) ListVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+
+	versions, err := h.scriptService.ListVersions(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    versions,
+	})
+}
+
+// GetVersion 获取脚本的指定版本
+func (h *ScriptHandler) GetVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的版本号",
+		})
+		return
+	}
+
+	v, err := h.scriptService.GetVersion(uint(id), version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    v,
+	})
+}
+
+// Diff 比较脚本的两个版本
+func (h *ScriptHandler) Diff(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的脚本ID",
+		})
+		return
+	}
+
+	var query ScriptDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.scriptService.Diff(uint(id), query.From, query.To)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    result,
+	})
+}
@@ -0,0 +1,672 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// defaultRetryDelay 未指定时的默认重试间隔（秒）
+const defaultRetryDelay = 10
+
+// outputKeepalive 实时输出没有新内容时发送保活注释的间隔
+const outputKeepalive = 15 * time.Second
+
+// TaskHandler 定时任务处理器
+type TaskHandler struct {
+	taskService *service.TaskService
+}
+
+// NewTaskHandler 创建定时任务处理器
+func NewTaskHandler(db *gorm.DB, rdb *redis.Client, cfg config.Task) *TaskHandler {
+	return &TaskHandler{
+		taskService: service.NewTaskService(db, rdb, cfg),
+	}
+}
+
+// taskParameters 将请求中的参数定义转换为模型
+func taskParameters(params []TaskParameterRequest) []model.TaskParameter {
+	result := make([]model.TaskParameter, len(params))
+	for i, p := range params {
+		result[i] = model.TaskParameter{
+			Name:        p.Name,
+			Type:        p.Type,
+			Required:    p.Required,
+			Default:     p.Default,
+			Options:     p.Options,
+			Description: p.Description,
+		}
+	}
+	return result
+}
+
+// taskNotifyRules 将请求中的通知规则转换为模型
+func taskNotifyRules(rules []TaskNotifyRuleRequest) []model.TaskNotifyRule {
+	result := make([]model.TaskNotifyRule, len(rules))
+	for i, r := range rules {
+		result[i] = model.TaskNotifyRule{Type: r.Type, Threshold: r.Threshold}
+	}
+	return result
+}
+
+// taskItem 任务详情，同时给出下一次触发时间的 UTC 和任务时区表示
+type taskItem struct {
+	*model.Task
+	NextRunUTC   *time.Time `json:"next_run_utc"`
+	NextRunLocal *time.Time `json:"next_run_local"`
+}
+
+func newTaskItem(task *model.Task) taskItem {
+	item := taskItem{Task: task}
+	if task.NextRun != nil {
+		utc := task.NextRun.UTC()
+		item.NextRunUTC = &utc
+		if loc, err := service.TaskLocation(task); err == nil {
+			local := task.NextRun.In(loc)
+			item.NextRunLocal = &local
+		}
+	}
+	return item
+}
+
+// List 获取任务列表
+func (h *TaskHandler) List(c *gin.Context) {
+	var pageReq PageRequest
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	tasks, total, err := h.taskService.List(pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	items := make([]taskItem, len(tasks))
+	for i := range tasks {
+		items[i] = newTaskItem(&tasks[i])
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     items,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// Create 创建任务
+func (h *TaskHandler) Create(c *gin.Context) {
+	var req CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	task := &model.Task{
+		Name:           req.Name,
+		Command:        req.Command,
+		Parameters:     taskParameters(req.Parameters),
+		ScriptID:       req.ScriptID,
+		ScriptVersion:  req.ScriptVersion,
+		ScriptParams:   req.ScriptParams,
+		CronExpr:       req.CronExpr,
+		Timezone:       req.Timezone,
+		ServerID:       req.ServerID,
+		Status:         model.TaskStatusEnabled,
+		Timeout:        req.Timeout,
+		MaxRetries:     req.MaxRetries,
+		RetryBackoff:   req.RetryBackoff,
+		RetryDelay:     defaultRetryDelay,
+		RetryExitCodes: req.RetryExitCodes,
+		CreatedBy:      c.GetUint("user_id"),
+
+		ConcurrencyPolicy: req.ConcurrencyPolicy,
+		MissedRunPolicy:   req.MissedRunPolicy,
+		StartingDeadline:  req.StartingDeadline,
+		NotifyRules:       taskNotifyRules(req.NotifyRules),
+	}
+	if task.RetryBackoff == "" {
+		task.RetryBackoff = model.TaskBackoffFixed
+	}
+	if task.ConcurrencyPolicy == "" {
+		task.ConcurrencyPolicy = model.TaskConcurrencyAllow
+	}
+	if task.MissedRunPolicy == "" {
+		task.MissedRunPolicy = model.TaskMissedSkip
+	}
+	if req.RetryDelay != nil {
+		task.RetryDelay = *req.RetryDelay
+	}
+
+	if err := h.taskService.Create(task); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "任务创建成功",
+		Data:    newTaskItem(task),
+	})
+}
+
+// GetByID 根据ID获取任务
+func (h *TaskHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的任务ID",
+		})
+		return
+	}
+
+	task, err := h.taskService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    newTaskItem(task),
+	})
+}
+
+// Update 更新任务
+func (h *TaskHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的任务ID",
+		})
+		return
+	}
+
+	var req UpdateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 构建更新字段
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Command != "" {
+		updates["command"] = req.Command
+	}
+	if req.Parameters != nil {
+		updates["parameters"] = taskParameters(*req.Parameters)
+	}
+	if req.ScriptID != nil {
+		if *req.ScriptID == 0 {
+			updates["script_id"] = nil
+		} else {
+			updates["script_id"] = req.ScriptID
+		}
+	}
+	if req.ScriptVersion != nil {
+		updates["script_version"] = *req.ScriptVersion
+	}
+	if req.ScriptParams != nil {
+		updates["script_params"] = *req.ScriptParams
+	}
+	if req.CronExpr != "" {
+		updates["cron_expr"] = req.CronExpr
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.ServerID != nil {
+		updates["server_id"] = *req.ServerID
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if req.Timeout != nil {
+		updates["timeout"] = *req.Timeout
+	}
+	if req.MaxRetries != nil {
+		updates["max_retries"] = *req.MaxRetries
+	}
+	if req.RetryBackoff != "" {
+		updates["retry_backoff"] = req.RetryBackoff
+	}
+	if req.RetryDelay != nil {
+		updates["retry_delay"] = *req.RetryDelay
+	}
+	if req.RetryExitCodes != nil {
+		updates["retry_exit_codes"] = *req.RetryExitCodes
+	}
+	if req.ConcurrencyPolicy != "" {
+		updates["concurrency_policy"] = req.ConcurrencyPolicy
+	}
+	if req.MissedRunPolicy != "" {
+		updates["missed_run_policy"] = req.MissedRunPolicy
+	}
+	if req.StartingDeadline != nil {
+		updates["starting_deadline"] = *req.StartingDeadline
+	}
+	if req.NotifyRules != nil {
+		updates["notify_rules"] = taskNotifyRules(*req.NotifyRules)
+	}
+
+	task, err := h.taskService.Update(uint(id), updates)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "更新成功",
+		Data:    newTaskItem(task),
+	})
+}
+
+// Delete 删除任务
+func (h *TaskHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的任务ID",
+		})
+		return
+	}
+
+	if err := h.taskService.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// Enable 启用任务
+func (h *TaskHandler) Enable(c *gin.Context) {
+	h.setStatus(c, model.TaskStatusEnabled, "任务已启用")
+}
+
+// Disable 禁用任务
+func (h *TaskHandler) Disable(c *gin.Context) {
+	h.setStatus(c, model.TaskStatusDisabled, "任务已禁用")
+}
+
+// setStatus 修改任务启用状态
+func (h *TaskHandler) setStatus(c *gin.Context, status int, message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的任务ID",
+		})
+		return
+	}
+
+	task, err := h.taskService.SetStatus(uint(id), status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: message,
+		Data:    newTaskItem(task),
+	})
+}
+
+// Run 立即执行任务，记录触发用户
+func (h *TaskHandler) Run(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的任务ID",
+		})
+		return
+	}
+
+	// 没有参数时允许空请求体
+	var req RunTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	execution, err := h.taskService.RunNow(uint(id), c.GetUint("user_id"), req.Params)
+	if err != nil {
+		if errors.Is(err, service.ErrTaskRunning) {
+			c.JSON(http.StatusConflict, Response{
+				Code:    409,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Code:    202,
+		Message: "任务已开始执行",
+		Data:    execution,
+	})
+}
+
+// ListExecutions 获取任务执行记录
+func (h *TaskHandler) ListExecutions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的任务ID",
+		})
+		return
+	}
+
+	var pageReq PageRequest
+	var query TaskExecutionQuery
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	executions, total, err := h.taskService.ListExecutions(uint(id), query.Status, query.Trigger, pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     executions,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// GetExecution 获取执行记录，输出内容通过实时输出或下载接口获取
+func (h *TaskHandler) GetExecution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的执行记录ID",
+		})
+		return
+	}
+
+	execution, err := h.taskService.GetExecution(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    execution,
+	})
+}
+
+// StreamOutput 以 SSE 推送执行输出：先补发已保存的分块，执行仍在运行时继续推送实时输出直到结束
+// 断线重连时通过 Last-Event-ID 从上次收到的分块之后继续
+func (h *TaskHandler) StreamOutput(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的执行记录ID",
+		})
+		return
+	}
+
+	execution, err := h.taskService.GetExecution(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var events <-chan service.OutputEvent
+	if execution.Status == model.TaskExecutionRunning {
+		var cancel func()
+		events, cancel, err = h.taskService.SubscribeOutput(ctx, execution.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Code:    500,
+				Message: err.Error(),
+			})
+			return
+		}
+		defer cancel()
+		// 订阅生效前执行可能已结束，结束消息不会再收到
+		if execution, err = h.taskService.GetExecution(execution.ID); err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Code:    404,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	after := -1
+	if last, err := strconv.Atoi(c.GetHeader("Last-Event-ID")); err == nil {
+		after = last
+	}
+	for {
+		chunks, err := h.taskService.OutputChunks(execution.ID, after, 100)
+		if err != nil {
+			writeOutputEvent(c, "error", service.OutputEvent{Seq: after, Content: err.Error()})
+			return
+		}
+		for _, chunk := range chunks {
+			writeOutputEvent(c, "output", service.OutputEvent{Seq: chunk.Seq, Content: chunk.Content})
+			after = chunk.Seq
+		}
+		if len(chunks) < 100 {
+			break
+		}
+	}
+	if execution.Status != model.TaskExecutionRunning {
+		writeOutputEvent(c, "end", service.OutputEvent{Seq: after + 1, End: true})
+		return
+	}
+
+	keepalive := time.NewTicker(outputKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepalive.C:
+			c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.End {
+				writeOutputEvent(c, "end", event)
+				return
+			}
+			// 省略标记的序号早于已发送的分块，消息ID保持为最后发送的分块，以免重连时重复补发
+			if event.Marker {
+				data, _ := json.Marshal(event)
+				fmt.Fprintf(c.Writer, "id: %d\nevent: marker\ndata: %s\n\n", after, data)
+				c.Writer.Flush()
+				continue
+			}
+			// 补发时已发送的分块
+			if event.Seq <= after {
+				continue
+			}
+			writeOutputEvent(c, "output", event)
+			after = event.Seq
+		}
+	}
+}
+
+// writeOutputEvent 写入一条 SSE 消息，以分块序号作为消息ID
+func writeOutputEvent(c *gin.Context, name string, event service.OutputEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, name, data)
+	c.Writer.Flush()
+}
+
+// DownloadOutput 下载执行保留的完整输出
+func (h *TaskHandler) DownloadOutput(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的执行记录ID",
+		})
+		return
+	}
+
+	execution, err := h.taskService.GetExecution(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="task-%d-execution-%d.log"`, execution.TaskID, execution.ID))
+	c.Status(http.StatusOK)
+	// 响应头已写出，出错时只能中断输出
+	if err := h.taskService.WriteOutput(execution.ID, c.Writer); err != nil {
+		log.Printf("下载执行 %d 的输出失败: %v", execution.ID, err)
+	}
+}
+
+// Preview 预览 cron 表达式的后续触发时间
+func (h *TaskHandler) Preview(c *gin.Context) {
+	var req CronPreviewRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	times, err := service.PreviewCron(req.Cron, req.Timezone, time.Now(), req.Count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	utcTimes := make([]time.Time, len(times))
+	for i, t := range times {
+		utcTimes[i] = t.UTC()
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: gin.H{
+			"cron":      req.Cron,
+			"timezone":  req.Timezone,
+			"times":     times,
+			"utc_times": utcTimes,
+		},
+	})
+}
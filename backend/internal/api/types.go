@@ -1,5 +1,12 @@
 package api
 
+import (
+	"time"
+
+	"devops/internal/model"
+	"devops/internal/monitor"
+)
+
 // Response 通用响应结构
 type Response struct {
 	Code    int         `json:"code"`
@@ -77,29 +84,283 @@ type UpdateServerRequest struct {
 
 // CreateDeploymentRequest 创建部署请求
 type CreateDeploymentRequest struct {
-	Name       string `json:"name" binding:"required"`
-	ServerID   uint   `json:"server_id" binding:"required"`
-	Repository string `json:"repository" binding:"required"`
-	Branch     string `json:"branch"`
-	Path       string `json:"path" binding:"required"`
-	Script     string `json:"script" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	ServerID      uint   `json:"server_id" binding:"required"`
+	Repository    string `json:"repository"`
+	Branch        string `json:"branch" binding:"max=50,startsnotwith=-,excludesall= ~^:?*[\\"`
+	Path          string `json:"path"`
+	Script        string `json:"script"`
+	ScriptID      *uint  `json:"script_id"`                      // 引用脚本库中的脚本，代替 script
+	ScriptVersion int    `json:"script_version" binding:"min=0"` // 固定的脚本版本，0表示跟随最新版本
+	Strategy      string `json:"strategy" binding:"omitempty,oneof=script docker compose"`
+	Image         string `json:"image"`
+	ContainerName string `json:"container_name"`
+	RunOptions    string `json:"run_options"`
+	HealthTimeout int    `json:"health_timeout" binding:"omitempty,min=1"`
+}
+
+// TriggerDeploymentRequest 触发部署请求
+type TriggerDeploymentRequest struct {
+	Tag            string `json:"tag"`             // 镜像标签，Docker/Compose部署使用
+	OverrideFreeze bool   `json:"override_freeze"` // 管理员在冻结窗口内强制部署
+	OverrideReason string `json:"override_reason"` // 强制部署理由
+}
+
+// DeploymentFreezeRequest 创建/更新部署冻结窗口请求
+type DeploymentFreezeRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Environment string     `json:"environment" binding:"omitempty,oneof=dev test prod"`
+	Type        string     `json:"type" binding:"required,oneof=once recurring"`
+	StartAt     *time.Time `json:"start_at"`
+	EndAt       *time.Time `json:"end_at"`
+	StartSpec   string     `json:"start_spec"`
+	EndSpec     string     `json:"end_spec"`
+	Timezone    string     `json:"timezone"`
+	Reason      string     `json:"reason"`
+	Enabled     *bool      `json:"enabled"`
+}
+
+// DeploymentReportRequest 部署报表查询请求，日期格式为 2006-01-02，结束日期包含在内
+type DeploymentReportRequest struct {
+	Start        string `form:"start"`
+	End          string `form:"end"`
+	Bucket       string `form:"bucket" binding:"omitempty,oneof=day week month"`
+	DeploymentID uint   `form:"deployment_id"`
+	Environment  string `form:"environment" binding:"omitempty,oneof=dev test prod"`
+}
+
+// TaskParameterRequest 任务参数定义
+type TaskParameterRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Type        string   `json:"type" binding:"required,oneof=string int enum secret"`
+	Required    bool     `json:"required"`
+	Default     string   `json:"default"`
+	Options     []string `json:"options"`
+	Description string   `json:"description"`
+}
+
+// TaskNotifyRuleRequest 任务通知规则
+type TaskNotifyRuleRequest struct {
+	Type      string `json:"type" binding:"required,oneof=failure consecutive recovery long_running no_success"`
+	Threshold int    `json:"threshold" binding:"min=0"` // 连续失败次数，或以秒计的时长
 }
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	Name     string `json:"name" binding:"required"`
-	Command  string `json:"command" binding:"required"`
-	CronExpr string `json:"cron_expr" binding:"required"`
-	ServerID uint   `json:"server_id" binding:"required"`
+	Name           string                 `json:"name" binding:"required"`
+	Command        string                 `json:"command"`
+	Parameters     []TaskParameterRequest `json:"parameters" binding:"dive"`
+	ScriptID       *uint                  `json:"script_id"`                      // 引用脚本库中的脚本，代替 command
+	ScriptVersion  int                    `json:"script_version" binding:"min=0"` // 固定的脚本版本，0表示跟随最新版本
+	ScriptParams   map[string]string      `json:"script_params"`                  // 覆盖的脚本参数默认值
+	CronExpr       string                 `json:"cron_expr" binding:"required"`
+	Timezone       string                 `json:"timezone"`
+	ServerID       uint                   `json:"server_id" binding:"required"`
+	Timeout        int                    `json:"timeout" binding:"min=0"`
+	MaxRetries     int                    `json:"max_retries" binding:"min=0,max=10"`
+	RetryBackoff   string                 `json:"retry_backoff" binding:"omitempty,oneof=fixed exponential"`
+	RetryDelay     *int                   `json:"retry_delay" binding:"omitempty,min=0"`
+	RetryExitCodes string                 `json:"retry_exit_codes"`
+
+	ConcurrencyPolicy string `json:"concurrency_policy" binding:"omitempty,oneof=allow forbid replace"`
+	MissedRunPolicy   string `json:"missed_run_policy" binding:"omitempty,oneof=skip run_once catch_up"`
+	StartingDeadline  int    `json:"starting_deadline" binding:"min=0"`
+
+	NotifyRules []TaskNotifyRuleRequest `json:"notify_rules" binding:"dive"`
 }
 
 // UpdateTaskRequest 更新任务请求
 type UpdateTaskRequest struct {
-	Name     string `json:"name"`
-	Command  string `json:"command"`
-	CronExpr string `json:"cron_expr"`
-	ServerID *uint  `json:"server_id"`
-	Status   *int   `json:"status" binding:"omitempty,oneof=0 1"`
+	Name           string                  `json:"name"`
+	Command        string                  `json:"command"`
+	Parameters     *[]TaskParameterRequest `json:"parameters" binding:"omitempty,dive"`
+	ScriptID       *uint                   `json:"script_id"` // 为0时取消引用脚本
+	ScriptVersion  *int                    `json:"script_version" binding:"omitempty,min=0"`
+	ScriptParams   *map[string]string      `json:"script_params"`
+	CronExpr       string                  `json:"cron_expr"`
+	Timezone       *string                 `json:"timezone"`
+	ServerID       *uint                   `json:"server_id"`
+	Status         *int                    `json:"status" binding:"omitempty,oneof=0 1"`
+	Timeout        *int                    `json:"timeout" binding:"omitempty,min=0"`
+	MaxRetries     *int                    `json:"max_retries" binding:"omitempty,min=0,max=10"`
+	RetryBackoff   string                  `json:"retry_backoff" binding:"omitempty,oneof=fixed exponential"`
+	RetryDelay     *int                    `json:"retry_delay" binding:"omitempty,min=0"`
+	RetryExitCodes *string                 `json:"retry_exit_codes"`
+
+	ConcurrencyPolicy string `json:"concurrency_policy" binding:"omitempty,oneof=allow forbid replace"`
+	MissedRunPolicy   string `json:"missed_run_policy" binding:"omitempty,oneof=skip run_once catch_up"`
+	StartingDeadline  *int   `json:"starting_deadline" binding:"omitempty,min=0"`
+
+	NotifyRules *[]TaskNotifyRuleRequest `json:"notify_rules" binding:"omitempty,dive"`
+}
+
+// RunTaskRequest 立即执行任务请求
+type RunTaskRequest struct {
+	Params map[string]string `json:"params"`
+}
+
+// TaskExecutionQuery 任务执行记录查询条件
+type TaskExecutionQuery struct {
+	Status  *int   `form:"status" binding:"omitempty,oneof=0 1 2 3 4"`
+	Trigger string `form:"trigger" binding:"omitempty,oneof=schedule manual workflow"`
+}
+
+// CronPreviewRequest cron 表达式预览请求
+type CronPreviewRequest struct {
+	Cron     string `form:"cron" binding:"required"`
+	Count    int    `form:"count,default=10" binding:"min=1,max=100"`
+	Timezone string `form:"timezone"`
+}
+
+// CreateScriptRequest 创建脚本请求
+type CreateScriptRequest struct {
+	Name        string                 `json:"name" binding:"required,max=100"`
+	Description string                 `json:"description"`
+	Interpreter string                 `json:"interpreter" binding:"required,oneof=bash sh python"`
+	Content     string                 `json:"content" binding:"required"`
+	Parameters  []TaskParameterRequest `json:"parameters" binding:"dive"`
+	Comment     string                 `json:"comment" binding:"max=255"` // 版本变更说明
+}
+
+// UpdateScriptRequest 更新脚本请求，修改解释器、内容或参数定义时生成新版本
+type UpdateScriptRequest struct {
+	Name        string                  `json:"name" binding:"max=100"`
+	Description *string                 `json:"description"`
+	Interpreter string                  `json:"interpreter" binding:"omitempty,oneof=bash sh python"`
+	Content     string                  `json:"content"`
+	Parameters  *[]TaskParameterRequest `json:"parameters" binding:"omitempty,dive"`
+	Comment     string                  `json:"comment" binding:"max=255"`
+}
+
+// ScriptDiffQuery 脚本版本比较请求，to 为0时与最新版本比较
+type ScriptDiffQuery struct {
+	From int `form:"from" binding:"required,min=1"`
+	To   int `form:"to" binding:"min=0"`
+}
+
+// WorkflowNodeRequest 工作流节点
+type WorkflowNodeRequest struct {
+	Key    string `json:"key" binding:"required,max=50"`
+	TaskID uint   `json:"task_id" binding:"required"`
+}
+
+// WorkflowEdgeRequest 工作流的边，条件默认为上游成功
+type WorkflowEdgeRequest struct {
+	From      string `json:"from" binding:"required"`
+	To        string `json:"to" binding:"required"`
+	Condition string `json:"condition" binding:"omitempty,oneof=success failure always"`
+}
+
+// CreateWorkflowRequest 创建工作流请求
+type CreateWorkflowRequest struct {
+	Name        string                `json:"name" binding:"required"`
+	Description string                `json:"description"`
+	CronExpr    string                `json:"cron_expr"`
+	Timezone    string                `json:"timezone"`
+	Nodes       []WorkflowNodeRequest `json:"nodes" binding:"required,min=1,dive"`
+	Edges       []WorkflowEdgeRequest `json:"edges" binding:"dive"`
+}
+
+// UpdateWorkflowRequest 更新工作流请求，提供 nodes 时整体替换节点和边
+type UpdateWorkflowRequest struct {
+	Name        string                `json:"name"`
+	Description *string               `json:"description"`
+	CronExpr    *string               `json:"cron_expr"`
+	Timezone    *string               `json:"timezone"`
+	Status      *int                  `json:"status" binding:"omitempty,oneof=0 1"`
+	Nodes       []WorkflowNodeRequest `json:"nodes" binding:"omitempty,min=1,dive"`
+	Edges       []WorkflowEdgeRequest `json:"edges" binding:"dive"`
+}
+
+// RerunWorkflowRequest 从失败节点重新运行请求
+type RerunWorkflowRequest struct {
+	Node string `json:"node" binding:"required"`
+}
+
+// IngestMetricsRequest 监控代理上报请求，后端不可达期间缓存的样本会一并上报
+type IngestMetricsRequest struct {
+	Samples []monitor.SystemMetrics `json:"samples" binding:"required,min=1"`
+}
+
+// AgentInfoRequest 代理上报的自身信息
+type AgentInfoRequest struct {
+	Hostname     string   `json:"hostname" binding:"max=255"`
+	Version      string   `json:"version" binding:"max=50"`
+	OS           string   `json:"os" binding:"max=50"`
+	Arch         string   `json:"arch" binding:"max=20"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// EnrollAgentRequest 代理注册请求
+type EnrollAgentRequest struct {
+	AgentInfoRequest
+	Token string `json:"token" binding:"required"`
+	Host  string `json:"host" binding:"required,max=100"` // 服务器地址，用于关联已有服务器
+}
+
+// CreateEnrollmentTokenRequest 创建代理注册令牌请求
+type CreateEnrollmentTokenRequest struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Group       string     `json:"group" binding:"max=50"`
+	Environment string     `json:"environment" binding:"omitempty,oneof=dev test prod"`
+	MaxUses     int        `json:"max_uses" binding:"min=0"` // 1 为一次性令牌，0 为不限次数
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// AlertRuleRequest 创建/更新告警规则请求
+type AlertRuleRequest struct {
+	Name             string  `json:"name" binding:"required,max=100"`
+	Scope            string  `json:"scope" binding:"required,oneof=global group server"`
+	ServerID         uint    `json:"server_id"`
+	Group            string  `json:"group" binding:"max=50"`
+	MetricType       string  `json:"metric_type" binding:"required,oneof=cpu memory disk load net_recv net_sent"`
+	Mountpoint       string  `json:"mountpoint" binding:"max=255"`
+	Condition        string  `json:"condition" binding:"required"` // >, <, >=, <=, ==, !=
+	Threshold        float64 `json:"threshold"`
+	Duration         int     `json:"duration" binding:"min=0"` // 持续时间（秒）
+	Level            string  `json:"level" binding:"omitempty,oneof=info warning critical"`
+	Enabled          *bool   `json:"enabled"`
+	ChannelIDs       []uint  `json:"channel_ids"`
+	NotifyEmail      bool    `json:"notify_email"`
+	NotifyWebhook    bool    `json:"notify_webhook"`
+	WebhookChannelID uint    `json:"webhook_channel_id"`                // 勾选 NotifyWebhook 时推送的通用 Webhook 渠道
+	TitleTemplate    string  `json:"title_template" binding:"max=1000"` // 通知标题的 text/template 模板，为空时使用渠道模板或默认格式
+	BodyTemplate     string  `json:"body_template" binding:"max=5000"`
+}
+
+// AlertQuery 告警历史查询参数，时间为 RFC3339 格式
+type AlertQuery struct {
+	Status   string     `form:"status" binding:"omitempty,oneof=firing resolved"`
+	ServerID uint       `form:"server_id"`
+	RuleID   uint       `form:"rule_id"`
+	Level    string     `form:"level" binding:"omitempty,oneof=info warning critical"`
+	Start    *time.Time `form:"start"`
+	End      *time.Time `form:"end"`
+}
+
+// NotifyChannelRequest 创建/更新通知渠道请求
+type NotifyChannelRequest struct {
+	Name          string                    `json:"name" binding:"required,max=100"`
+	Type          string                    `json:"type" binding:"required,oneof=email webhook dingtalk wecom feishu slack"`
+	Config        model.NotifyChannelConfig `json:"config"`
+	RateLimit     int                       `json:"rate_limit" binding:"min=0"` // 每分钟最多发送的通知数，0 表示不限制
+	Default       bool                      `json:"default"`
+	Enabled       *bool                     `json:"enabled"`
+	TitleTemplate string                    `json:"title_template" binding:"max=1000"` // 通知标题的 text/template 模板，为空时使用默认格式
+	BodyTemplate  string                    `json:"body_template" binding:"max=5000"`
+}
+
+// TemplatePreviewRequest 通知模板预览请求，使用示例告警渲染
+type TemplatePreviewRequest struct {
+	TitleTemplate string `json:"title_template" binding:"max=1000"`
+	BodyTemplate  string `json:"body_template" binding:"max=5000"`
+	Status        string `json:"status" binding:"omitempty,oneof=firing resolved"` // 示例告警的状态，默认为 firing
+}
+
+// NotifyDeliveryQuery 通知发送记录查询参数
+type NotifyDeliveryQuery struct {
+	ChannelID uint   `form:"channel_id"`
+	Status    string `form:"status" binding:"omitempty,oneof=sent failed rate_limited"`
 }
 
 // PageRequest 分页请求
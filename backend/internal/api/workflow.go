@@ -0,0 +1,392 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// WorkflowHandler 工作流处理器
+type WorkflowHandler struct {
+	workflowService *service.WorkflowService
+}
+
+// NewWorkflowHandler 创建工作流处理器
+func NewWorkflowHandler(db *gorm.DB, rdb *redis.Client, cfg config.Task) *WorkflowHandler {
+	return &WorkflowHandler{
+		workflowService: service.NewWorkflowService(db, rdb, cfg),
+	}
+}
+
+// workflowGraph 将请求中的节点和边转换为模型
+func workflowGraph(nodes []WorkflowNodeRequest, edges []WorkflowEdgeRequest) ([]model.WorkflowNode, []model.WorkflowEdge) {
+	resultNodes := make([]model.WorkflowNode, len(nodes))
+	for i, n := range nodes {
+		resultNodes[i] = model.WorkflowNode{Key: n.Key, TaskID: n.TaskID}
+	}
+	resultEdges := make([]model.WorkflowEdge, len(edges))
+	for i, e := range edges {
+		condition := e.Condition
+		if condition == "" {
+			condition = model.WorkflowOnSuccess
+		}
+		resultEdges[i] = model.WorkflowEdge{From: e.From, To: e.To, Condition: condition}
+	}
+	return resultNodes, resultEdges
+}
+
+// List 获取工作流列表
+func (h *WorkflowHandler) List(c *gin.Context) {
+	var pageReq PageRequest
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	workflows, total, err := h.workflowService.List(pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     workflows,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// Create 创建工作流
+func (h *WorkflowHandler) Create(c *gin.Context) {
+	var req CreateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	nodes, edges := workflowGraph(req.Nodes, req.Edges)
+	workflow := &model.Workflow{
+		Name:        req.Name,
+		Description: req.Description,
+		CronExpr:    req.CronExpr,
+		Timezone:    req.Timezone,
+		Status:      model.TaskStatusEnabled,
+		CreatedBy:   c.GetUint("user_id"),
+		Nodes:       nodes,
+		Edges:       edges,
+	}
+
+	if err := h.workflowService.Create(workflow); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Code:    201,
+		Message: "工作流创建成功",
+		Data:    workflow,
+	})
+}
+
+// GetByID 根据ID获取工作流
+func (h *WorkflowHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的工作流ID",
+		})
+		return
+	}
+
+	workflow, err := h.workflowService.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    workflow,
+	})
+}
+
+// Update 更新工作流
+func (h *WorkflowHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的工作流ID",
+		})
+		return
+	}
+
+	var req UpdateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.CronExpr != nil {
+		updates["cron_expr"] = *req.CronExpr
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+
+	var nodes []model.WorkflowNode
+	var edges []model.WorkflowEdge
+	if req.Nodes != nil {
+		nodes, edges = workflowGraph(req.Nodes, req.Edges)
+	}
+
+	workflow, err := h.workflowService.Update(uint(id), updates, nodes, edges)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "更新成功",
+		Data:    workflow,
+	})
+}
+
+// Delete 删除工作流
+func (h *WorkflowHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的工作流ID",
+		})
+		return
+	}
+
+	if err := h.workflowService.Delete(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "删除成功",
+	})
+}
+
+// Enable 启用工作流
+func (h *WorkflowHandler) Enable(c *gin.Context) {
+	h.setStatus(c, model.TaskStatusEnabled, "工作流已启用")
+}
+
+// Disable 禁用工作流
+func (h *WorkflowHandler) Disable(c *gin.Context) {
+	h.setStatus(c, model.TaskStatusDisabled, "工作流已禁用")
+}
+
+// setStatus 修改工作流启用状态
+func (h *WorkflowHandler) setStatus(c *gin.Context, status int, message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的工作流ID",
+		})
+		return
+	}
+
+	workflow, err := h.workflowService.SetStatus(uint(id), status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: message,
+		Data:    workflow,
+	})
+}
+
+// Run 手动运行工作流
+func (h *WorkflowHandler) Run(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的工作流ID",
+		})
+		return
+	}
+
+	run, err := h.workflowService.Trigger(uint(id), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Code:    202,
+		Message: "工作流已开始运行",
+		Data:    run,
+	})
+}
+
+// ListRuns 获取工作流运行记录
+func (h *WorkflowHandler) ListRuns(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的工作流ID",
+		})
+		return
+	}
+
+	var pageReq PageRequest
+	if err := c.ShouldBindQuery(&pageReq); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	runs, total, err := h.workflowService.ListRuns(uint(id), pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data: PageResponse{
+			List:     runs,
+			Total:    total,
+			Page:     pageReq.Page,
+			PageSize: pageReq.PageSize,
+		},
+	})
+}
+
+// GetRun 获取运行的执行图
+func (h *WorkflowHandler) GetRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的运行记录ID",
+		})
+		return
+	}
+
+	graph, err := h.workflowService.RunGraph(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Code:    200,
+		Message: "获取成功",
+		Data:    graph,
+	})
+}
+
+// Rerun 从失败节点重新运行
+func (h *WorkflowHandler) Rerun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "无效的运行记录ID",
+		})
+		return
+	}
+
+	var req RerunWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	run, err := h.workflowService.Rerun(uint(id), req.Node, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Code:    202,
+		Message: "工作流已开始重新运行",
+		Data:    run,
+	})
+}
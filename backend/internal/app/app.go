@@ -22,10 +22,12 @@ type Application struct {
 	rdb    *redis.Client
 
 	// 管理器组件
-	configMgr   *ConfigManager
-	databaseMgr *DatabaseManager
-	cacheMgr    *CacheManager
-	serverMgr   *ServerManager
+	configMgr    *ConfigManager
+	databaseMgr  *DatabaseManager
+	cacheMgr     *CacheManager
+	serverMgr    *ServerManager
+	schedulerMgr *SchedulerManager
+	monitorMgr   *MonitorManager
 
 	// 关闭通道
 	shutdownCh chan struct{}
@@ -70,7 +72,17 @@ func (app *Application) Run() error {
 		return fmt.Errorf("服务器启动失败: %w", err)
 	}
 
-	// 第七步：等待关闭信号
+	// 第七步：启动任务调度器
+	if err := app.startScheduler(); err != nil {
+		log.Printf("任务调度器启动失败: %v, 继续运行但定时任务不会触发", err)
+	}
+
+	// 第八步：启动监控数据采集
+	if err := app.startMonitor(); err != nil {
+		log.Printf("监控数据采集启动失败: %v, 继续运行但不会通过SSH采集监控数据", err)
+	}
+
+	// 第九步：等待关闭信号
 	app.waitForShutdown()
 
 	return nil
@@ -146,6 +158,32 @@ func (app *Application) startServer() error {
 	return nil
 }
 
+// startScheduler 启动任务调度器
+func (app *Application) startScheduler() error {
+	app.schedulerMgr = NewSchedulerManager()
+
+	if err := app.schedulerMgr.Initialize(app.db, app.rdb, app.config); err != nil {
+		return err
+	}
+
+	return app.schedulerMgr.Start()
+}
+
+// startMonitor 启动监控数据采集，由调度主节点负责采集，避免多个实例重复采集同一台服务器
+func (app *Application) startMonitor() error {
+	if app.schedulerMgr == nil || app.schedulerMgr.scheduler == nil {
+		return fmt.Errorf("采集依赖调度主节点选举，任务调度器未启动")
+	}
+
+	app.monitorMgr = NewMonitorManager()
+
+	if err := app.monitorMgr.Initialize(app.db, app.rdb, app.config.Monitor); err != nil {
+		return err
+	}
+
+	return app.monitorMgr.Start(app.schedulerMgr.IsLeader)
+}
+
 // waitForShutdown 等待关闭信号
 func (app *Application) waitForShutdown() {
 	quit := make(chan os.Signal, 1)
@@ -169,6 +207,18 @@ func (app *Application) gracefulShutdown() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// 停止监控数据采集
+	if app.monitorMgr != nil {
+		app.monitorMgr.Shutdown()
+	}
+
+	// 停止任务调度器
+	if app.schedulerMgr != nil {
+		if err := app.schedulerMgr.Shutdown(ctx); err != nil {
+			log.Printf("任务调度器关闭失败: %v", err)
+		}
+	}
+
 	// 关闭HTTP服务器
 	if app.serverMgr != nil {
 		if err := app.serverMgr.Shutdown(ctx); err != nil {
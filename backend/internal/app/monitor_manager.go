@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/monitor"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// MonitorManager 监控数据采集管理器
+type MonitorManager struct {
+	service  *monitor.Service
+	interval time.Duration
+	cancel   context.CancelFunc
+}
+
+// NewMonitorManager 创建监控数据采集管理器实例
+func NewMonitorManager() *MonitorManager {
+	return &MonitorManager{}
+}
+
+// Initialize 初始化监控服务
+func (mm *MonitorManager) Initialize(db *gorm.DB, rdb *redis.Client, cfg config.Monitor) error {
+	if rdb == nil {
+		return fmt.Errorf("监控数据缓存依赖Redis，Redis不可用")
+	}
+
+	mm.service = monitor.NewService(db, rdb, cfg)
+	mm.interval = time.Duration(cfg.Interval) * time.Second
+	if mm.interval <= 0 {
+		mm.interval = monitor.DefaultInterval
+	}
+	return nil
+}
+
+// Start 启动监控数据采集，只有 leader 返回 true 的实例采集
+func (mm *MonitorManager) Start(leader func() bool) error {
+	if mm.service == nil {
+		return fmt.Errorf("监控服务未初始化")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := mm.service.StartMonitoring(ctx, mm.interval, leader); err != nil {
+		cancel()
+		return err
+	}
+	mm.cancel = cancel
+
+	log.Printf("监控数据采集已启动，间隔 %s", mm.interval)
+	return nil
+}
+
+// Shutdown 停止监控数据采集并关闭SSH连接
+func (mm *MonitorManager) Shutdown() {
+	if mm.cancel == nil {
+		return
+	}
+
+	log.Println("正在停止监控数据采集...")
+	// 先取消进行中的采集，避免等待SSH超时
+	mm.cancel()
+	mm.service.StopMonitoring()
+	log.Println("监控数据采集已停止")
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"devops/internal/config"
+	"devops/internal/scheduler"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// SchedulerManager 任务调度管理器
+type SchedulerManager struct {
+	scheduler *scheduler.Scheduler
+}
+
+// NewSchedulerManager 创建任务调度管理器实例
+func NewSchedulerManager() *SchedulerManager {
+	return &SchedulerManager{}
+}
+
+// Initialize 初始化任务调度器
+func (sm *SchedulerManager) Initialize(db *gorm.DB, rdb *redis.Client, cfg *config.Config) error {
+	if rdb == nil {
+		return fmt.Errorf("调度队列依赖Redis，Redis不可用")
+	}
+
+	sm.scheduler = scheduler.New(db, rdb, cfg)
+	return nil
+}
+
+// Start 启动任务调度器
+func (sm *SchedulerManager) Start() error {
+	if sm.scheduler == nil {
+		return fmt.Errorf("调度器未初始化")
+	}
+
+	return sm.scheduler.Start()
+}
+
+// IsLeader 判断当前实例是否为调度主节点，调度器未初始化时返回false
+func (sm *SchedulerManager) IsLeader() bool {
+	return sm.scheduler != nil && sm.scheduler.IsLeader()
+}
+
+// Shutdown 停止任务调度器
+func (sm *SchedulerManager) Shutdown(ctx context.Context) error {
+	if sm.scheduler == nil {
+		return nil
+	}
+
+	log.Println("正在停止任务调度器...")
+
+	if err := sm.scheduler.Stop(ctx); err != nil {
+		return fmt.Errorf("任务调度器停止失败: %w", err)
+	}
+
+	log.Println("任务调度器已停止")
+	return nil
+}
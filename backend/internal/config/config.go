@@ -8,12 +8,15 @@ import (
 
 // Config 应用配置结构
 type Config struct {
-	Server   Server   `mapstructure:"server"`
-	Database Database `mapstructure:"database"`
-	Redis    Redis    `mapstructure:"redis"`
-	JWT      JWT      `mapstructure:"jwt"`
-	Log      Log      `mapstructure:"log"`
-	Monitor  Monitor  `mapstructure:"monitor"`
+	Server    Server    `mapstructure:"server"`
+	Database  Database  `mapstructure:"database"`
+	Redis     Redis     `mapstructure:"redis"`
+	JWT       JWT       `mapstructure:"jwt"`
+	Log       Log       `mapstructure:"log"`
+	Monitor   Monitor   `mapstructure:"monitor"`
+	Deploy    Deploy    `mapstructure:"deploy"`
+	Task      Task      `mapstructure:"task"`
+	Retention Retention `mapstructure:"retention"`
 }
 
 // Server 服务器配置
@@ -70,8 +73,42 @@ type Log struct {
 
 // Monitor 监控配置
 type Monitor struct {
-	Interval int `mapstructure:"interval"`
-	Timeout  int `mapstructure:"timeout"`
+	Interval int            `mapstructure:"interval"`
+	Timeout  int            `mapstructure:"timeout"`
+	History  MetricsHistory `mapstructure:"history"`
+}
+
+// MetricsHistory 监控历史数据各级精度的保留时长（小时），取值为0表示不限制
+type MetricsHistory struct {
+	Raw        int `mapstructure:"raw"`         // 原始样本
+	Minute     int `mapstructure:"minute"`      // 1分钟汇总
+	FiveMinute int `mapstructure:"five_minute"` // 5分钟汇总
+	Hour       int `mapstructure:"hour"`        // 1小时汇总
+}
+
+// Deploy 部署配置
+type Deploy struct {
+	MirrorDir string `mapstructure:"mirror_dir"` // 代码仓库镜像目录
+}
+
+// Task 定时任务配置
+type Task struct {
+	MaxOutputSize int64 `mapstructure:"max_output_size"` // 单次执行保留的最大输出字节数，超出时保留首尾、省略中间
+}
+
+// Retention 历史记录保留配置
+type Retention struct {
+	Interval       int             `mapstructure:"interval"`        // 清理间隔（分钟）
+	BatchSize      int             `mapstructure:"batch_size"`      // 每批删除的记录数
+	TaskExecutions RetentionPolicy `mapstructure:"task_executions"` // 任务执行记录，按任务计数
+	DeploymentLogs RetentionPolicy `mapstructure:"deployment_logs"` // 部署日志，按部署运行计数
+}
+
+// RetentionPolicy 保留策略，取值为0表示不限制
+type RetentionPolicy struct {
+	MaxAge     int `mapstructure:"max_age"`     // 保留天数
+	MaxCount   int `mapstructure:"max_count"`   // 每个任务或部署保留的最近记录数
+	KeepFailed int `mapstructure:"keep_failed"` // 不受上述限制、额外保留的最近失败记录数
 }
 
 // Load 加载配置
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+)
+
+// 监控代理状态
+const (
+	AgentStatusActive  = "active"
+	AgentStatusRevoked = "revoked" // 凭证已吊销，代理无法再上报数据
+)
+
+// Agent 部署在服务器上的监控代理
+// 每台服务器最多一个代理，代理通过凭证上报监控数据，存在有效代理的服务器不再通过SSH采集
+type Agent struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	ServerID          uint       `gorm:"uniqueIndex;not null" json:"server_id"`
+	CredentialHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // 凭证的SHA-256摘要
+	Status            string     `gorm:"size:20;index;not null" json:"status"`  // active, revoked
+	Hostname          string     `gorm:"size:255" json:"hostname"`
+	Version           string     `gorm:"size:50" json:"version"`
+	OS                string     `gorm:"size:50" json:"os"`
+	Arch              string     `gorm:"size:20" json:"arch"`
+	Capabilities      []string   `gorm:"type:text;serializer:json" json:"capabilities"` // 代理支持的功能，如 metrics
+	EnrollmentTokenID *uint      `gorm:"index" json:"enrollment_token_id"`              // 通过注册令牌注册时的令牌，管理员直接生成凭证时为空
+	LastHeartbeatAt   *time.Time `json:"last_heartbeat_at"`
+	LastIP            string     `gorm:"size:64" json:"last_ip"`
+	EnrolledAt        time.Time  `json:"enrolled_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// 关联
+	Server Server `gorm:"foreignKey:ServerID" json:"server,omitempty"`
+}
+
+// TableName 设置表名
+func (Agent) TableName() string {
+	return "agents"
+}
+
+// AgentEnrollmentToken 代理注册令牌
+// 代理用注册令牌换取自己的凭证，并自动创建或关联服务器，新建的服务器归入令牌的分组和环境
+type AgentEnrollmentToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	TokenHash   string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // 令牌的SHA-256摘要
+	Group       string     `gorm:"column:group_name;size:50" json:"group"`
+	Environment string     `gorm:"size:20" json:"environment"`
+	MaxUses     int        `gorm:"not null" json:"max_uses"` // 可使用次数，1 为一次性令牌，0 为不限次数
+	Uses        int        `gorm:"not null" json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   uint       `gorm:"index;not null" json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (AgentEnrollmentToken) TableName() string {
+	return "agent_enrollment_tokens"
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 告警规则作用范围
+const (
+	AlertScopeGlobal = "global" // 所有服务器
+	AlertScopeGroup  = "group"  // 指定分组的服务器
+	AlertScopeServer = "server" // 单台服务器
+)
+
+// AlertRule 监控告警规则
+// 指标连续满足条件达到 Duration 秒后触发告警，Duration 为0时满足条件立即触发
+type AlertRule struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"size:100;not null" json:"name"`
+	Scope            string         `gorm:"size:20;index;not null" json:"scope"`    // global, group, server
+	ServerID         uint           `gorm:"index" json:"server_id"`                 // 作用范围为 server 时的服务器
+	Group            string         `gorm:"column:group_name;size:50" json:"group"` // 作用范围为 group 时的服务器分组
+	MetricType       string         `gorm:"size:20;not null" json:"metric_type"`    // cpu, memory, disk, load, net_recv, net_sent
+	Mountpoint       string         `gorm:"size:255" json:"mountpoint"`             // 磁盘规则的挂载点，为空时每个分区分别评估
+	Condition        string         `gorm:"size:5;not null" json:"condition"`       // >, <, >=, <=, ==, !=
+	Threshold        float64        `gorm:"not null" json:"threshold"`
+	Duration         int            `gorm:"not null" json:"duration"`      // 持续时间（秒）
+	Level            string         `gorm:"size:20;not null" json:"level"` // info, warning, critical
+	Enabled          bool           `gorm:"not null" json:"enabled"`
+	ChannelIDs       []uint         `gorm:"type:text;serializer:json" json:"channel_ids"` // 发送告警的通知渠道，未指定时发送到默认渠道
+	NotifyEmail      bool           `json:"notify_email"`                                 // 同时发送到所有启用的邮件渠道
+	NotifyWebhook    bool           `json:"notify_webhook"`
+	WebhookChannelID uint           `json:"webhook_channel_id"`              // 勾选 NotifyWebhook 时额外推送的通用 Webhook 渠道，使用渠道的签名密钥和频率限制
+	TitleTemplate    string         `gorm:"type:text" json:"title_template"` // 通知标题模板，优先于通知渠道的模板
+	BodyTemplate     string         `gorm:"type:text" json:"body_template"`  // 通知正文模板，优先于通知渠道的模板
+	CreatedBy        uint           `gorm:"index;not null" json:"created_by"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 设置表名
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+// 告警状态
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// Alert 监控告警
+// 规则、服务器和标签相同（指纹相同）的告警同时最多有一条处于触发状态，
+// 条件解除后标记为已恢复，再次满足条件时产生新的告警
+type Alert struct {
+	ID              uint              `gorm:"primaryKey" json:"id"`
+	Fingerprint     string            `gorm:"size:64;index;not null" json:"fingerprint"` // 规则、服务器和标签的SHA-256摘要
+	OpenFingerprint *string           `gorm:"size:64;uniqueIndex" json:"-"`              // 触发中时等于 Fingerprint，恢复时清空，保证同一指纹只有一条触发中的告警
+	RuleID          uint              `gorm:"index;not null" json:"rule_id"`
+	ServerID        uint              `gorm:"index;not null" json:"server_id"`
+	Name            string            `gorm:"size:100;not null" json:"name"` // 触发时的规则名称
+	MetricType      string            `gorm:"size:20;not null" json:"metric_type"`
+	Labels          map[string]string `gorm:"type:text;serializer:json" json:"labels"` // 如磁盘告警的 mountpoint
+	Level           string            `gorm:"size:20;not null" json:"level"`
+	Status          string            `gorm:"size:20;index;not null" json:"status"` // firing, resolved
+	Condition       string            `gorm:"size:5;not null" json:"condition"`
+	Threshold       float64           `gorm:"not null" json:"threshold"`
+	Value           float64           `gorm:"not null" json:"value"` // 最近一次评估时的值
+	StartsAt        time.Time         `json:"starts_at"`             // 开始持续满足条件的时间
+	FiredAt         time.Time         `gorm:"index" json:"fired_at"`
+	LastSeenAt      time.Time         `json:"last_seen_at"` // 最近一次仍满足条件的样本时间
+	ResolvedAt      *time.Time        `json:"resolved_at"`
+	AcknowledgedBy  *uint             `json:"acknowledged_by"`
+	AcknowledgedAt  *time.Time        `json:"acknowledged_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+
+	// 关联
+	Server Server `gorm:"foreignKey:ServerID" json:"server,omitempty"`
+}
+
+// TableName 设置表名
+func (Alert) TableName() string {
+	return "alerts"
+}
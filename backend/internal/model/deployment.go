@@ -6,25 +6,60 @@ import (
 	"gorm.io/gorm"
 )
 
+// 部署状态
+const (
+	DeploymentStatusPending   = 0 // 待部署
+	DeploymentStatusRunning   = 1 // 部署中
+	DeploymentStatusSuccess   = 2 // 部署成功
+	DeploymentStatusFailed    = 3 // 部署失败
+	DeploymentStatusCancelled = 4 // 已取消
+)
+
+// 部署策略
+const (
+	DeploymentStrategyScript  = "script"  // 执行部署脚本
+	DeploymentStrategyDocker  = "docker"  // 拉取镜像并重建容器
+	DeploymentStrategyCompose = "compose" // 在部署目录执行 docker compose
+)
+
+// 部署动作
+const (
+	DeploymentActionDeploy   = "deploy"
+	DeploymentActionRollback = "rollback"
+)
+
 // Deployment 部署模型
 type Deployment struct {
-	ID         uint           `gorm:"primaryKey" json:"id"`
-	Name       string         `gorm:"size:100;not null" json:"name"`
-	ServerID   uint           `gorm:"index;not null" json:"server_id"`
-	Server     Server         `gorm:"foreignKey:ServerID" json:"server,omitempty"`
-	Repository string         `gorm:"size:200" json:"repository"`
-	Branch     string         `gorm:"size:50;default:main" json:"branch"`
-	Path       string         `gorm:"size:200" json:"path"`
-	Script     string         `gorm:"type:text" json:"script"`
-	Status     int            `gorm:"default:0" json:"status"` // 0:待部署 1:部署中 2:部署成功 3:部署失败
-	CreatedBy  uint           `gorm:"index;not null" json:"created_by"`
-	User       User           `gorm:"foreignKey:CreatedBy" json:"user,omitempty"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	Name           string         `gorm:"size:100;not null" json:"name"`
+	ServerID       uint           `gorm:"index;not null" json:"server_id"`
+	Server         Server         `gorm:"foreignKey:ServerID" json:"server,omitempty"`
+	Repository     string         `gorm:"size:200" json:"repository"`
+	Branch         string         `gorm:"size:50;default:main" json:"branch"`
+	Path           string         `gorm:"size:200" json:"path"`
+	Script         string         `gorm:"type:text" json:"script"`
+	ScriptID       *uint          `gorm:"index" json:"script_id"`                 // 引用脚本库中的脚本，设置后代替 Script 执行
+	ScriptVersion  int            `json:"script_version"`                         // 固定的脚本版本，0表示跟随最新版本
+	Strategy       string         `gorm:"size:20;default:script" json:"strategy"` // script, docker, compose
+	Image          string         `gorm:"size:200" json:"image"`                  // 镜像地址（不含标签）
+	ContainerName  string         `gorm:"size:100" json:"container_name"`
+	RunOptions     string         `gorm:"type:text" json:"run_options"`     // docker run 附加参数
+	HealthTimeout  int            `gorm:"default:60" json:"health_timeout"` // 容器健康检查超时（秒）
+	CurrentTag     string         `gorm:"size:100" json:"current_tag"`      // 当前运行的镜像标签
+	PreviousTag    string         `gorm:"size:100" json:"previous_tag"`     // 上一次的镜像标签，用于回滚
+	CurrentImage   string         `gorm:"size:300" json:"current_image"`    // 当前运行的镜像摘要（镜像@sha256:... 或镜像ID），仅Docker部署记录
+	PreviousImage  string         `gorm:"size:300" json:"previous_image"`   // 上一次运行的镜像摘要，Docker部署优先按摘要回滚
+	Status         int            `gorm:"default:0" json:"status"`          // 0:待部署 1:部署中 2:部署成功 3:部署失败 4:已取消
+	DeployedCommit string         `gorm:"size:40" json:"deployed_commit"`   // 目标服务器上当前部署的提交
+	CreatedBy      uint           `gorm:"index;not null" json:"created_by"`
+	User           User           `gorm:"foreignKey:CreatedBy" json:"user,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联
 	Logs []DeploymentLog `gorm:"foreignKey:DeploymentID" json:"-"`
+	Runs []DeploymentRun `gorm:"foreignKey:DeploymentID" json:"-"`
 }
 
 // TableName 设置表名
@@ -32,11 +67,46 @@ func (Deployment) TableName() string {
 	return "deployments"
 }
 
+// DeploymentRun 部署运行记录模型
+type DeploymentRun struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	DeploymentID   uint           `gorm:"index;not null" json:"deployment_id"`
+	Deployment     Deployment     `gorm:"foreignKey:DeploymentID" json:"-"`
+	Status         int            `gorm:"default:0;index" json:"status"`        // 0:待部署 1:部署中 2:部署成功 3:部署失败 4:已取消
+	Environment    string         `gorm:"size:20;index" json:"environment"`     // 触发时服务器所属环境
+	Instance       string         `gorm:"size:100" json:"instance"`             // 执行该运行的后端实例
+	LockToken      string         `gorm:"size:100" json:"-"`                    // 持有部署锁的令牌
+	PGID           int            `json:"pgid"`                                 // 远程脚本进程组ID
+	Action         string         `gorm:"size:20;default:deploy" json:"action"` // deploy, rollback
+	Ref            string         `gorm:"size:100" json:"ref"`                  // 触发时的分支或标签
+	ImageTag       string         `gorm:"size:100" json:"image_tag"`            // 部署的镜像标签
+	PreviousTag    string         `gorm:"size:100" json:"previous_tag"`         // 部署前运行的镜像标签
+	ImageDigest    string         `gorm:"size:300" json:"image_digest"`         // 实际运行的镜像摘要，回滚时为要恢复的镜像
+	Commit         string         `gorm:"size:40" json:"commit"`                // 实际部署的提交
+	CommitAt       *time.Time     `json:"commit_at"`                            // 部署提交的提交时间，用于计算变更前置时间
+	FreezeOverride string         `gorm:"type:text" json:"freeze_override"`     // 冻结期间强制部署的理由
+	Error          string         `gorm:"type:text" json:"error"`
+	TriggeredBy    uint           `gorm:"index;not null" json:"triggered_by"`
+	CancelledBy    *uint          `json:"cancelled_by"`
+	StartedAt      *time.Time     `json:"started_at"`
+	FinishedAt     *time.Time     `json:"finished_at"`
+	Duration       int            `json:"duration"` // 执行时长（秒）
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 设置表名
+func (DeploymentRun) TableName() string {
+	return "deployment_runs"
+}
+
 // DeploymentLog 部署日志模型
 type DeploymentLog struct {
 	ID           uint           `gorm:"primaryKey" json:"id"`
 	DeploymentID uint           `gorm:"index;not null" json:"deployment_id"`
 	Deployment   Deployment     `gorm:"foreignKey:DeploymentID" json:"-"`
+	RunID        uint           `gorm:"index" json:"run_id"`
 	Level        string         `gorm:"size:20" json:"level"` // info, warn, error
 	Message      string         `gorm:"type:text" json:"message"`
 	CreatedAt    time.Time      `json:"created_at"`
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 冻结窗口类型
+const (
+	FreezeTypeOnce      = "once"      // 一次性时间段
+	FreezeTypeRecurring = "recurring" // 每周或每天重复的时间段
+)
+
+// DeploymentFreeze 部署冻结窗口模型
+type DeploymentFreeze struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Environment string         `gorm:"size:20;index" json:"environment"` // dev, test, prod，为空表示所有环境
+	Type        string         `gorm:"size:20;not null" json:"type"`     // once, recurring
+	StartAt     *time.Time     `json:"start_at"`                         // 一次性窗口开始时间
+	EndAt       *time.Time     `json:"end_at"`                           // 一次性窗口结束时间
+	StartSpec   string         `gorm:"size:20" json:"start_spec"`        // 重复窗口开始，如 "Fri 18:00" 或每天的 "22:00"
+	EndSpec     string         `gorm:"size:20" json:"end_spec"`          // 重复窗口结束，如 "Mon 09:00" 或每天的 "06:00"
+	Timezone    string         `gorm:"size:50" json:"timezone"`          // 重复窗口的IANA时区，为空使用服务器本地时区
+	Reason      string         `gorm:"type:text" json:"reason"`
+	Enabled     bool           `gorm:"not null" json:"enabled"`
+	CreatedBy   uint           `gorm:"index;not null" json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 设置表名
+func (DeploymentFreeze) TableName() string {
+	return "deployment_freezes"
+}
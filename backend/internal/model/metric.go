@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+)
+
+// 监控历史数据的精度（秒）
+const (
+	MetricResolutionRaw    = 0    // 原始样本
+	MetricResolutionMinute = 60   // 1分钟汇总
+	MetricResolution5m     = 300  // 5分钟汇总
+	MetricResolutionHour   = 3600 // 1小时汇总
+)
+
+// 监控历史数据的指标
+const (
+	MetricCPU     = "cpu"      // CPU使用率(%)
+	MetricMemory  = "memory"   // 内存使用率(%)
+	MetricDisk    = "disk"     // 使用率最高的分区的使用率(%)
+	MetricLoad    = "load"     // 1分钟平均负载
+	MetricNetRecv = "net_recv" // 网络接收速率(bytes/s)
+	MetricNetSent = "net_sent" // 网络发送速率(bytes/s)
+)
+
+// MetricPoint 监控指标历史数据点
+// 原始样本的 Avg、Min、Max 相同，Count 为1；汇总数据的 Time 为时间段起点
+type MetricPoint struct {
+	ID         uint64    `gorm:"primaryKey" json:"-"`
+	ServerID   uint      `gorm:"uniqueIndex:idx_metric_point,priority:1;not null" json:"server_id"`
+	Metric     string    `gorm:"size:20;uniqueIndex:idx_metric_point,priority:2;not null" json:"metric"`
+	Resolution int       `gorm:"uniqueIndex:idx_metric_point,priority:3;index:idx_metric_expire,priority:1;not null" json:"resolution"`
+	Time       time.Time `gorm:"uniqueIndex:idx_metric_point,priority:4;index:idx_metric_expire,priority:2;not null" json:"timestamp"`
+	Avg        float64   `gorm:"not null" json:"value"`
+	Min        float64   `gorm:"not null" json:"min"`
+	Max        float64   `gorm:"not null" json:"max"`
+	Count      int       `gorm:"not null" json:"-"` // 汇总的原始样本数，用于逐级汇总时加权
+}
+
+// TableName 设置表名
+func (MetricPoint) TableName() string {
+	return "metric_points"
+}
+
+// MetricRollupMark 需要重新汇总的服务器
+// 代理补报的样本晚于常规汇总的回溯范围时，记录其中最早的样本时间，下次汇总时从该时间重新汇总
+type MetricRollupMark struct {
+	ServerID uint      `gorm:"primaryKey;autoIncrement:false" json:"server_id"`
+	Since    time.Time `gorm:"not null" json:"since"`
+}
+
+// TableName 设置表名
+func (MetricRollupMark) TableName() string {
+	return "metric_rollup_marks"
+}
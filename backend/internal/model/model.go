@@ -9,9 +9,27 @@ func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&User{},
 		&Server{},
+		&Agent{},
+		&AgentEnrollmentToken{},
+		&MetricPoint{},
+		&MetricRollupMark{},
+		&AlertRule{},
+		&Alert{},
+		&NotifyChannel{},
+		&NotifyDelivery{},
 		&Deployment{},
+		&DeploymentRun{},
 		&DeploymentLog{},
+		&DeploymentFreeze{},
+		&Script{},
+		&ScriptVersion{},
 		&Task{},
 		&TaskExecution{},
+		&TaskOutputChunk{},
+		&Workflow{},
+		&WorkflowNode{},
+		&WorkflowEdge{},
+		&WorkflowRun{},
+		&WorkflowRunNode{},
 	)
 }
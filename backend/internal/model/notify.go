@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通知渠道类型
+const (
+	NotifyChannelEmail    = "email"    // SMTP 邮件
+	NotifyChannelWebhook  = "webhook"  // 通用 Webhook，JSON 格式，可用 HMAC 签名
+	NotifyChannelDingTalk = "dingtalk" // 钉钉群机器人
+	NotifyChannelWeCom    = "wecom"    // 企业微信群机器人
+	NotifyChannelFeishu   = "feishu"   // 飞书群机器人
+	NotifyChannelSlack    = "slack"    // Slack Incoming Webhook
+)
+
+// 通知发送状态
+const (
+	NotifyDeliverySent        = "sent"
+	NotifyDeliveryFailed      = "failed"       // 重试后仍然失败
+	NotifyDeliveryRateLimited = "rate_limited" // 超出渠道的发送频率限制，未发送
+)
+
+// NotifyChannelConfig 通知渠道配置，各类型只使用其中部分字段
+type NotifyChannelConfig struct {
+	URL      string   `json:"url,omitempty"`      // Webhook 地址
+	Secret   string   `json:"secret,omitempty"`   // 通用 Webhook 的 HMAC 密钥，钉钉、飞书的加签密钥
+	Host     string   `json:"host,omitempty"`     // SMTP 服务器
+	Port     int      `json:"port,omitempty"`     // SMTP 端口，465 使用 TLS 连接，其他端口在服务器支持时使用 STARTTLS
+	Username string   `json:"username,omitempty"` // SMTP 用户名
+	Password string   `json:"password,omitempty"` // SMTP 密码
+	From     string   `json:"from,omitempty"`     // 发件人
+	To       []string `json:"to,omitempty"`       // 收件人
+}
+
+// NotifyChannel 通知渠道
+// 告警规则可以指定发送的渠道，未指定渠道的通知（如任务通知）发送到所有默认渠道
+type NotifyChannel struct {
+	ID            uint                `gorm:"primaryKey" json:"id"`
+	Name          string              `gorm:"size:100;not null" json:"name"`
+	Type          string              `gorm:"size:20;index;not null" json:"type"` // email, webhook, dingtalk, wecom, feishu, slack
+	Config        NotifyChannelConfig `gorm:"type:text;serializer:json" json:"config"`
+	RateLimit     int                 `gorm:"not null" json:"rate_limit"`                // 每分钟最多发送的通知数，0 表示不限制
+	Default       bool                `gorm:"column:is_default;not null" json:"default"` // 接收未指定渠道的通知
+	TitleTemplate string              `gorm:"type:text" json:"title_template"`           // 通知标题的 text/template 模板，为空时使用默认格式
+	BodyTemplate  string              `gorm:"type:text" json:"body_template"`            // 通知正文的 text/template 模板，为空时使用默认格式
+	Enabled       bool                `gorm:"not null" json:"enabled"`
+	CreatedBy     uint                `gorm:"index;not null" json:"created_by"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt      `gorm:"index" json:"-"`
+}
+
+// TableName 设置表名
+func (NotifyChannel) TableName() string {
+	return "notify_channels"
+}
+
+// NotifyDelivery 通知发送记录
+type NotifyDelivery struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ChannelID   uint      `gorm:"index;not null" json:"channel_id"` // 告警规则直接配置的 Webhook 为0
+	ChannelType string    `gorm:"size:20;not null" json:"channel_type"`
+	Source      string    `gorm:"size:20" json:"source"` // monitor, task
+	Key         string    `gorm:"column:notify_key;size:255;index" json:"key"`
+	Level       string    `gorm:"size:20" json:"level"`
+	Title       string    `gorm:"size:255" json:"title"`
+	Status      string    `gorm:"size:20;index;not null" json:"status"` // sent, failed, rate_limited
+	Attempts    int       `gorm:"not null" json:"attempts"`
+	Error       string    `gorm:"type:text" json:"error"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (NotifyDelivery) TableName() string {
+	return "notify_deliveries"
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 脚本解释器
+const (
+	ScriptInterpreterBash   = "bash"
+	ScriptInterpreterSh     = "sh"
+	ScriptInterpreterPython = "python"
+)
+
+// Script 脚本库中的脚本，内容和参数定义为最新版本
+// 任务和部署引用脚本时可以固定版本，也可以跟随最新版本
+type Script struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	Name          string          `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description   string          `gorm:"type:text" json:"description"`
+	Interpreter   string          `gorm:"size:20;not null" json:"interpreter"` // bash, sh, python
+	Content       string          `gorm:"type:mediumtext;not null" json:"content"`
+	Parameters    []TaskParameter `gorm:"type:text;serializer:json" json:"parameters"` // 脚本中 {{name}} 占位符的参数定义
+	LatestVersion int             `gorm:"not null" json:"latest_version"`
+	CreatedBy     uint            `gorm:"index;not null" json:"created_by"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt  `gorm:"index" json:"-"`
+}
+
+// TableName 设置表名
+func (Script) TableName() string {
+	return "scripts"
+}
+
+// ScriptVersion 脚本的历史版本，创建后不再修改
+type ScriptVersion struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	ScriptID    uint            `gorm:"uniqueIndex:idx_script_version;not null" json:"script_id"`
+	Version     int             `gorm:"uniqueIndex:idx_script_version;not null" json:"version"`
+	Interpreter string          `gorm:"size:20;not null" json:"interpreter"`
+	Content     string          `gorm:"type:mediumtext;not null" json:"content"`
+	Parameters  []TaskParameter `gorm:"type:text;serializer:json" json:"parameters"`
+	Comment     string          `gorm:"size:255" json:"comment"` // 版本变更说明
+	CreatedBy   uint            `gorm:"index;not null" json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// TableName 设置表名
+func (ScriptVersion) TableName() string {
+	return "script_versions"
+}
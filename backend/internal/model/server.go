@@ -18,6 +18,7 @@ type Server struct {
 	Status      int            `gorm:"default:1" json:"status"`
 	Environment string         `gorm:"size:20" json:"environment"`
 	Description string         `gorm:"type:text" json:"description"`
+	Group       string         `gorm:"column:group_name;size:50;index" json:"group"` // 服务器分组，用于代理注册和告警规则的范围
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
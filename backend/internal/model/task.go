@@ -6,22 +6,111 @@ import (
 	"gorm.io/gorm"
 )
 
+// 任务状态
+const (
+	TaskStatusDisabled = 0
+	TaskStatusEnabled  = 1
+)
+
+// 任务执行状态
+const (
+	TaskExecutionRunning = 0
+	TaskExecutionSuccess = 1
+	TaskExecutionFailed  = 2
+	TaskExecutionTimeout = 3
+	TaskExecutionSkipped = 4
+)
+
+// 任务并发策略，与 Kubernetes CronJob 的 concurrencyPolicy 一致
+const (
+	TaskConcurrencyAllow   = "allow"   // 允许与上一次执行并行
+	TaskConcurrencyForbid  = "forbid"  // 上一次执行未结束时跳过本次
+	TaskConcurrencyReplace = "replace" // 终止上一次执行后开始本次
+)
+
+// 错过触发时间后的处理策略
+const (
+	TaskMissedSkip    = "skip"     // 跳过所有错过的触发
+	TaskMissedRunOnce = "run_once" // 只补执行一次
+	TaskMissedCatchUp = "catch_up" // 逐个补执行所有错过的触发
+)
+
+// 任务重试退避方式
+const (
+	TaskBackoffFixed       = "fixed"
+	TaskBackoffExponential = "exponential"
+)
+
+// 任务参数类型
+const (
+	TaskParamString = "string"
+	TaskParamInt    = "int"
+	TaskParamEnum   = "enum"
+	TaskParamSecret = "secret" // 不写入执行记录，输出中出现的取值会被遮盖
+)
+
+// TaskParameter 任务命令中 {{name}} 占位符的参数定义
+type TaskParameter struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Default     string   `json:"default"`
+	Options     []string `json:"options,omitempty"` // 枚举类型的可选值
+	Description string   `json:"description,omitempty"`
+}
+
+// 任务通知规则类型
+const (
+	TaskNotifyFailure     = "failure"      // 执行失败（重试用尽后）
+	TaskNotifyConsecutive = "consecutive"  // 连续 Threshold 次触发失败
+	TaskNotifyRecovery    = "recovery"     // 失败后恢复成功
+	TaskNotifyLongRunning = "long_running" // 执行超过 Threshold 秒仍未结束
+	TaskNotifyNoSuccess   = "no_success"   // 超过 Threshold 秒没有成功的执行
+)
+
+// TaskNotifyRule 任务通知规则
+type TaskNotifyRule struct {
+	Type      string `json:"type"`
+	Threshold int    `json:"threshold,omitempty"` // 连续失败次数，或以秒计的时长
+}
+
+// 任务执行触发方式
+const (
+	TaskTriggerSchedule = "schedule"
+	TaskTriggerManual   = "manual"
+	TaskTriggerWorkflow = "workflow"
+)
+
 // Task 任务模型
 type Task struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"size:100;not null" json:"name"`
-	Command   string         `gorm:"type:text;not null" json:"command"`
-	CronExpr  string         `gorm:"size:50" json:"cron_expr"`
-	ServerID  uint           `gorm:"index;not null" json:"server_id"`
-	Server    Server         `gorm:"foreignKey:ServerID" json:"server,omitempty"`
-	Status    int            `gorm:"default:1" json:"status"` // 0:禁用 1:启用
-	LastRun   *time.Time     `json:"last_run"`
-	NextRun   *time.Time     `json:"next_run"`
-	CreatedBy uint           `gorm:"index;not null" json:"created_by"`
-	User      User           `gorm:"foreignKey:CreatedBy" json:"user,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint              `gorm:"primaryKey" json:"id"`
+	Name              string            `gorm:"size:100;not null" json:"name"`
+	Command           string            `gorm:"type:text;not null" json:"command"`
+	Parameters        []TaskParameter   `gorm:"type:text;serializer:json" json:"parameters"`    // 命令占位符的参数定义
+	ScriptID          *uint             `gorm:"index" json:"script_id"`                         // 引用脚本库中的脚本，设置后代替 Command 执行
+	ScriptVersion     int               `json:"script_version"`                                 // 固定的脚本版本，0表示跟随最新版本
+	ScriptParams      map[string]string `gorm:"type:text;serializer:json" json:"script_params"` // 本任务覆盖的脚本参数默认值
+	CronExpr          string            `gorm:"size:50" json:"cron_expr"`
+	Timezone          string            `gorm:"size:64" json:"timezone"` // cron 表达式使用的 IANA 时区，为空使用服务器本地时区
+	ServerID          uint              `gorm:"index;not null" json:"server_id"`
+	Server            Server            `gorm:"foreignKey:ServerID" json:"server,omitempty"`
+	Status            int               `gorm:"default:1" json:"status"`                         // 0:禁用 1:启用
+	Timeout           int               `gorm:"default:0" json:"timeout"`                        // 单次执行超时（秒），0表示不限制
+	MaxRetries        int               `gorm:"default:0" json:"max_retries"`                    // 失败后最多重试次数
+	RetryBackoff      string            `gorm:"size:20;default:fixed" json:"retry_backoff"`      // fixed, exponential
+	RetryDelay        int               `json:"retry_delay"`                                     // 重试间隔（秒），指数退避时为初始间隔
+	RetryExitCodes    string            `gorm:"size:100" json:"retry_exit_codes"`                // 需要重试的退出码，逗号分隔，为空表示任何失败都重试
+	ConcurrencyPolicy string            `gorm:"size:20;default:allow" json:"concurrency_policy"` // allow, forbid, replace
+	MissedRunPolicy   string            `gorm:"size:20;default:skip" json:"missed_run_policy"`   // skip, run_once, catch_up
+	StartingDeadline  int               `gorm:"default:0" json:"starting_deadline"`              // 晚于计划时间超过该秒数视为错过，0使用默认值
+	NotifyRules       []TaskNotifyRule  `gorm:"type:text;serializer:json" json:"notify_rules"`   // 通知规则
+	LastRun           *time.Time        `json:"last_run"`
+	NextRun           *time.Time        `json:"next_run"`
+	CreatedBy         uint              `gorm:"index;not null" json:"created_by"`
+	User              User              `gorm:"foreignKey:CreatedBy" json:"user,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt    `gorm:"index" json:"-"`
 
 	// 关联
 	Executions []TaskExecution `gorm:"foreignKey:TaskID" json:"-"`
@@ -34,20 +123,48 @@ func (Task) TableName() string {
 
 // TaskExecution 任务执行记录模型
 type TaskExecution struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	TaskID    uint           `gorm:"index;not null" json:"task_id"`
-	Task      Task           `gorm:"foreignKey:TaskID" json:"-"`
-	Status    int            `gorm:"default:0" json:"status"` // 0:运行中 1:成功 2:失败
-	Output    string         `gorm:"type:text" json:"output"`
-	Error     string         `gorm:"type:text" json:"error"`
-	StartTime time.Time      `json:"start_time"`
-	EndTime   *time.Time     `json:"end_time"`
-	Duration  int            `json:"duration"` // 执行时长（秒）
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint              `gorm:"primaryKey" json:"id"`
+	TaskID          uint              `gorm:"index;not null" json:"task_id"`
+	Task            Task              `gorm:"foreignKey:TaskID" json:"-"`
+	Status          int               `gorm:"default:0;index" json:"status"`               // 0:运行中 1:成功 2:失败 3:超时 4:已跳过
+	Reason          string            `gorm:"size:255" json:"reason"`                      // 跳过或被终止的原因
+	Attempt         int               `gorm:"default:1" json:"attempt"`                    // 第几次尝试，从1开始
+	RetryOf         *uint             `gorm:"index" json:"retry_of"`                       // 重试时指向同一次触发的首次执行
+	Trigger         string            `gorm:"size:20;default:schedule" json:"trigger"`     // schedule, manual, workflow
+	ScheduledAt     *time.Time        `json:"scheduled_at"`                                // 计划触发时间，手动执行为空
+	TriggeredBy     uint              `gorm:"index" json:"triggered_by"`                   // 手动执行的用户
+	Parameters      map[string]string `gorm:"type:text;serializer:json" json:"parameters"` // 本次执行的参数取值，密文参数已遮盖
+	ScriptVersion   int               `json:"script_version"`                              // 引用脚本的任务执行时使用的脚本版本
+	FencingToken    int64             `json:"fencing_token"`                               // 触发时调度主节点的任期令牌
+	Output          string            `gorm:"type:mediumtext" json:"output,omitempty"`     // 分块存储之前的执行输出，新执行写入 TaskOutputChunk
+	OutputSize      int64             `json:"output_size"`                                 // 执行产生的输出总字节数
+	OutputTruncated bool              `json:"output_truncated"`                            // 超出保留上限，中间部分已省略
+	Error           string            `gorm:"type:text" json:"error"`
+	ExitCode        *int              `json:"exit_code"`
+	PGID            int               `gorm:"column:pgid" json:"pgid"` // 远程进程组ID
+	HeartbeatAt     *time.Time        `json:"heartbeat_at"`            // 执行实例最近一次心跳，用于识别已失联的执行
+	StartTime       time.Time         `json:"start_time"`
+	EndTime         *time.Time        `json:"end_time"`
+	Duration        int               `json:"duration"` // 执行时长（秒）
+	CreatedAt       time.Time         `json:"created_at"`
+	DeletedAt       gorm.DeletedAt    `gorm:"index" json:"-"`
 }
 
 // TableName 设置表名
 func (TaskExecution) TableName() string {
 	return "task_executions"
 }
+
+// TaskOutputChunk 任务执行输出分块，按序号拼接得到保留的输出
+type TaskOutputChunk struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ExecutionID uint      `gorm:"uniqueIndex:idx_execution_seq;not null" json:"execution_id"`
+	Seq         int       `gorm:"uniqueIndex:idx_execution_seq;not null" json:"seq"`
+	Content     string    `gorm:"type:mediumtext" json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (TaskOutputChunk) TableName() string {
+	return "task_output_chunks"
+}
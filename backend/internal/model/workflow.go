@@ -0,0 +1,131 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 工作流边的触发条件
+const (
+	WorkflowOnSuccess = "success" // 上游成功时执行
+	WorkflowOnFailure = "failure" // 上游失败时执行
+	WorkflowAlways    = "always"  // 上游结束（含被跳过）后总是执行
+)
+
+// 工作流运行状态
+const (
+	WorkflowRunRunning = 0
+	WorkflowRunSuccess = 1
+	WorkflowRunFailed  = 2
+	WorkflowRunSkipped = 3
+)
+
+// 工作流节点运行状态
+const (
+	WorkflowNodePending = 0
+	WorkflowNodeRunning = 1
+	WorkflowNodeSuccess = 2
+	WorkflowNodeFailed  = 3
+	WorkflowNodeSkipped = 4
+)
+
+// WorkflowTriggerRerun 从失败节点重新运行
+const WorkflowTriggerRerun = "rerun"
+
+// Workflow 工作流模型，由任务节点和带条件的边组成有向无环图
+type Workflow struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	CronExpr    string         `gorm:"size:50" json:"cron_expr"` // 为空表示只能手动运行
+	Timezone    string         `gorm:"size:64" json:"timezone"`
+	Status      int            `gorm:"default:1" json:"status"` // 0:禁用 1:启用
+	LastRun     *time.Time     `json:"last_run"`
+	NextRun     *time.Time     `json:"next_run"`
+	CreatedBy   uint           `gorm:"index;not null" json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	Nodes []WorkflowNode `gorm:"foreignKey:WorkflowID" json:"nodes"`
+	Edges []WorkflowEdge `gorm:"foreignKey:WorkflowID" json:"edges"`
+}
+
+// TableName 设置表名
+func (Workflow) TableName() string {
+	return "workflows"
+}
+
+// WorkflowNode 工作流节点，引用一个任务
+type WorkflowNode struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	WorkflowID uint   `gorm:"index;not null" json:"workflow_id"`
+	Key        string `gorm:"size:50;not null" json:"key"` // 节点标识，在工作流内唯一
+	TaskID     uint   `gorm:"index;not null" json:"task_id"`
+	Task       Task   `gorm:"foreignKey:TaskID" json:"task,omitempty"`
+}
+
+// TableName 设置表名
+func (WorkflowNode) TableName() string {
+	return "workflow_nodes"
+}
+
+// WorkflowEdge 工作流的边，上游节点结束且满足条件后执行下游节点
+type WorkflowEdge struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	WorkflowID uint   `gorm:"index;not null" json:"workflow_id"`
+	From       string `gorm:"column:from_node;size:50;not null" json:"from"`
+	To         string `gorm:"column:to_node;size:50;not null" json:"to"`
+	Condition  string `gorm:"size:20;not null" json:"condition"` // success, failure, always
+}
+
+// TableName 设置表名
+func (WorkflowEdge) TableName() string {
+	return "workflow_edges"
+}
+
+// WorkflowRun 工作流运行记录
+type WorkflowRun struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	WorkflowID   uint           `gorm:"index;not null" json:"workflow_id"`
+	Status       int            `gorm:"default:0;index" json:"status"` // 0:运行中 1:成功 2:失败 3:已跳过
+	Reason       string         `gorm:"size:255" json:"reason"`        // 跳过的原因
+	Trigger      string         `gorm:"size:20" json:"trigger"`        // schedule, manual, rerun
+	RerunOf      *uint          `gorm:"index" json:"rerun_of"`         // 重新运行时指向原运行记录
+	ScheduledAt  *time.Time     `json:"scheduled_at"`
+	FencingToken int64          `json:"fencing_token"`
+	TriggeredBy  uint           `json:"triggered_by"`
+	StartTime    time.Time      `json:"start_time"`
+	EndTime      *time.Time     `json:"end_time"`
+	Duration     int            `json:"duration"` // 执行时长（秒）
+	CreatedAt    time.Time      `json:"created_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	Nodes []WorkflowRunNode `gorm:"foreignKey:RunID" json:"nodes,omitempty"`
+}
+
+// TableName 设置表名
+func (WorkflowRun) TableName() string {
+	return "workflow_runs"
+}
+
+// WorkflowRunNode 工作流运行中单个节点的状态
+type WorkflowRunNode struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	RunID       uint       `gorm:"index;not null" json:"run_id"`
+	NodeKey     string     `gorm:"size:50;not null" json:"node_key"`
+	TaskID      uint       `gorm:"not null" json:"task_id"`
+	Status      int        `gorm:"default:0" json:"status"` // 0:等待 1:运行中 2:成功 3:失败 4:已跳过
+	ExecutionID *uint      `json:"execution_id"`            // 对应的任务执行记录，重试时为最后一次尝试
+	Reused      bool       `json:"reused"`                  // 重新运行时沿用原运行的结果
+	StartTime   *time.Time `json:"start_time"`
+	EndTime     *time.Time `json:"end_time"`
+}
+
+// TableName 设置表名
+func (WorkflowRunNode) TableName() string {
+	return "workflow_run_nodes"
+}
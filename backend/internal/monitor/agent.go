@@ -0,0 +1,390 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"devops/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxIngestBatch 代理单次上报的最大样本数
+const MaxIngestBatch = 1000
+
+// maxClockSkew 允许代理时钟超前的最大时长，超出时使用服务端时间
+const maxClockSkew = time.Minute
+
+// AgentOfflineAfter 超过该时长没有心跳或上报的代理视为离线
+const AgentOfflineAfter = 5 * time.Minute
+
+// ErrInvalidAgentCredential 代理凭证无效或已吊销
+var ErrInvalidAgentCredential = errors.New("代理凭证无效或已吊销")
+
+// ErrInvalidEnrollmentToken 注册令牌无效
+var ErrInvalidEnrollmentToken = errors.New("注册令牌无效")
+
+// ErrEnrollmentDenied 注册令牌无权关联已有服务器
+var ErrEnrollmentDenied = errors.New("注册令牌无权关联该服务器")
+
+// AgentInfo 代理注册和心跳时上报的自身信息
+type AgentInfo struct {
+	Hostname     string   `json:"hostname"`
+	Version      string   `json:"version"`
+	OS           string   `json:"os"`
+	Arch         string   `json:"arch"`
+	Capabilities []string `json:"capabilities"`
+	IP           string   `json:"-"` // 请求来源地址，由后端填写
+}
+
+// Enrollment 代理注册结果，凭证明文只在此时返回
+type Enrollment struct {
+	AgentID    uint   `json:"agent_id"`
+	ServerID   uint   `json:"server_id"`
+	Credential string `json:"credential"`
+}
+
+// hashSecret 计算令牌或凭证的摘要，数据库中只保存摘要
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecret 生成随机令牌或凭证
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// AgentOnline 代理是否在线
+func AgentOnline(agent *model.Agent, now time.Time) bool {
+	return agent.Status == model.AgentStatusActive &&
+		agent.LastHeartbeatAt != nil && now.Sub(*agent.LastHeartbeatAt) < AgentOfflineAfter
+}
+
+// enrollmentUsable 检查注册令牌当前是否可用
+func enrollmentUsable(token *model.AgentEnrollmentToken, now time.Time) error {
+	if token.RevokedAt != nil {
+		return errors.New("注册令牌已吊销")
+	}
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return errors.New("注册令牌已过期")
+	}
+	if token.MaxUses > 0 && token.Uses >= token.MaxUses {
+		return errors.New("注册令牌已用完")
+	}
+	return nil
+}
+
+// enrollmentAllowed 检查注册令牌能否关联已有服务器：服务器需属于令牌的分组，
+// 令牌指定了环境时环境也需一致；服务器已有有效代理时不能通过注册替换其凭证
+func enrollmentAllowed(token *model.AgentEnrollmentToken, server *model.Server, agent *model.Agent) error {
+	if server.Group != token.Group {
+		return fmt.Errorf("%w: 服务器不属于令牌的分组", ErrEnrollmentDenied)
+	}
+	if token.Environment != "" && server.Environment != token.Environment {
+		return fmt.Errorf("%w: 服务器不属于令牌的环境", ErrEnrollmentDenied)
+	}
+	if agent != nil && agent.Status == model.AgentStatusActive {
+		return fmt.Errorf("%w: 服务器已有有效的代理，需由管理员吊销后重新注册或重新生成凭证", ErrEnrollmentDenied)
+	}
+	return nil
+}
+
+// CreateEnrollmentToken 创建注册令牌，返回只出现这一次的令牌明文
+func (s *Service) CreateEnrollmentToken(token *model.AgentEnrollmentToken) (string, error) {
+	if token.MaxUses < 0 {
+		return "", errors.New("可使用次数不能为负数")
+	}
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+		return "", errors.New("过期时间必须晚于当前时间")
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", fmt.Errorf("生成注册令牌失败: %w", err)
+	}
+	token.TokenHash = hashSecret(secret)
+	token.Uses = 0
+	token.RevokedAt = nil
+	if err := s.db.Create(token).Error; err != nil {
+		return "", fmt.Errorf("创建注册令牌失败: %w", err)
+	}
+	return secret, nil
+}
+
+// ListEnrollmentTokens 获取注册令牌列表
+func (s *Service) ListEnrollmentTokens() ([]model.AgentEnrollmentToken, error) {
+	var tokens []model.AgentEnrollmentToken
+	if err := s.db.Order("id DESC").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("获取注册令牌列表失败: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeEnrollmentToken 吊销注册令牌，已注册的代理不受影响
+func (s *Service) RevokeEnrollmentToken(id uint) error {
+	result := s.db.Model(&model.AgentEnrollmentToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("吊销注册令牌失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("注册令牌不存在或已吊销")
+	}
+	return nil
+}
+
+// Enroll 代理用注册令牌换取凭证
+// 按 host 关联已有服务器，不存在时以令牌的分组和环境新建服务器；
+// 已有服务器必须属于令牌的分组和环境，其代理已吊销时（如重装）替换凭证，代理仍有效时拒绝注册
+func (s *Service) Enroll(secret, host string, info AgentInfo) (*Enrollment, error) {
+	if secret == "" {
+		return nil, ErrInvalidEnrollmentToken
+	}
+	if host == "" {
+		return nil, errors.New("缺少服务器地址")
+	}
+	credential, err := newSecret()
+	if err != nil {
+		return nil, fmt.Errorf("生成代理凭证失败: %w", err)
+	}
+
+	var result Enrollment
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var token model.AgentEnrollmentToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashSecret(secret)).First(&token).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidEnrollmentToken
+			}
+			return fmt.Errorf("获取注册令牌失败: %w", err)
+		}
+		if err := enrollmentUsable(&token, time.Now()); err != nil {
+			return err
+		}
+
+		var server model.Server
+		err = tx.Where("host = ?", host).Order("id").First(&server).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			server = model.Server{
+				Name:        info.Hostname,
+				Host:        host,
+				Status:      1,
+				Group:       token.Group,
+				Environment: token.Environment,
+			}
+			if server.Name == "" {
+				server.Name = host
+			}
+			err = tx.Create(&server).Error
+		} else if err == nil {
+			if err := checkEnrollment(tx, &token, &server); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("关联服务器失败: %w", err)
+		}
+
+		agent, err := saveAgent(tx, server.ID, hashSecret(credential), &token.ID, info)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&token).Update("uses", gorm.Expr("uses + 1")).Error; err != nil {
+			return fmt.Errorf("更新注册令牌失败: %w", err)
+		}
+
+		result = Enrollment{AgentID: agent.ID, ServerID: server.ID, Credential: credential}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeCollector(result.ServerID)
+	return &result, nil
+}
+
+// checkEnrollment 检查注册令牌能否关联已有服务器及其代理
+func checkEnrollment(tx *gorm.DB, token *model.AgentEnrollmentToken, server *model.Server) error {
+	var agent model.Agent
+	err := tx.Where("server_id = ?", server.ID).First(&agent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return enrollmentAllowed(token, server, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("获取服务器代理失败: %w", err)
+	}
+	return enrollmentAllowed(token, server, &agent)
+}
+
+// IssueAgentCredential 管理员直接为服务器生成代理凭证，服务器原有代理的凭证随即失效
+// 凭证明文只在生成时返回一次；生成后该服务器改由代理推送数据，不再通过SSH采集
+func (s *Service) IssueAgentCredential(serverID uint) (*Enrollment, error) {
+	var server model.Server
+	if err := s.db.First(&server, serverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("服务器 %d 不存在", serverID)
+		}
+		return nil, fmt.Errorf("获取服务器失败: %w", err)
+	}
+
+	credential, err := newSecret()
+	if err != nil {
+		return nil, fmt.Errorf("生成代理凭证失败: %w", err)
+	}
+	agent, err := saveAgent(s.db, serverID, hashSecret(credential), nil, AgentInfo{})
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeCollector(serverID)
+	return &Enrollment{AgentID: agent.ID, ServerID: serverID, Credential: credential}, nil
+}
+
+// saveAgent 创建服务器的代理，或替换已有代理的凭证并重新启用
+func saveAgent(tx *gorm.DB, serverID uint, credentialHash string, tokenID *uint, info AgentInfo) (*model.Agent, error) {
+	now := time.Now()
+	agent := model.Agent{
+		ServerID:          serverID,
+		CredentialHash:    credentialHash,
+		Status:            model.AgentStatusActive,
+		Hostname:          info.Hostname,
+		Version:           info.Version,
+		OS:                info.OS,
+		Arch:              info.Arch,
+		Capabilities:      info.Capabilities,
+		EnrollmentTokenID: tokenID,
+		LastIP:            info.IP,
+		EnrolledAt:        now,
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "server_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"credential_hash", "status", "hostname", "version", "os", "arch", "capabilities",
+			"enrollment_token_id", "last_ip", "enrolled_at", "revoked_at", "updated_at",
+		}),
+	}).Create(&agent).Error
+	if err != nil {
+		return nil, fmt.Errorf("保存代理失败: %w", err)
+	}
+
+	// 冲突更新时 MySQL 不返回已有记录的ID，重新查询
+	var saved model.Agent
+	if err := tx.Where("server_id = ?", serverID).First(&saved).Error; err != nil {
+		return nil, fmt.Errorf("获取代理失败: %w", err)
+	}
+	return &saved, nil
+}
+
+// ListAgents 获取代理列表
+func (s *Service) ListAgents() ([]model.Agent, error) {
+	var agents []model.Agent
+	if err := s.db.Preload("Server").Order("id DESC").Find(&agents).Error; err != nil {
+		return nil, fmt.Errorf("获取代理列表失败: %w", err)
+	}
+	return agents, nil
+}
+
+// RevokeAgent 吊销代理凭证，之后该代理无法上报数据，服务器需重新注册或生成凭证
+func (s *Service) RevokeAgent(id uint) error {
+	now := time.Now()
+	result := s.db.Model(&model.Agent{}).
+		Where("id = ? AND status = ?", id, model.AgentStatusActive).
+		Updates(map[string]interface{}{"status": model.AgentStatusRevoked, "revoked_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("吊销代理失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("代理不存在或已吊销")
+	}
+
+	// 代理停止上报后其服务器的告警不会自行恢复
+	var agent model.Agent
+	if err := s.db.Select("server_id").First(&agent, id).Error; err != nil {
+		fmt.Printf("获取代理%d 失败，未恢复其服务器的告警: %v\n", id, err)
+		return nil
+	}
+	if err := s.resolveServerAlerts(context.Background(), agent.ServerID, now); err != nil {
+		fmt.Printf("恢复服务器%d 的告警失败: %v\n", agent.ServerID, err)
+	}
+	return nil
+}
+
+// AuthenticateAgent 校验代理凭证，返回凭证所属的代理
+func (s *Service) AuthenticateAgent(credential string) (*model.Agent, error) {
+	if credential == "" {
+		return nil, ErrInvalidAgentCredential
+	}
+
+	var agent model.Agent
+	err := s.db.Where("credential_hash = ? AND status = ?", hashSecret(credential), model.AgentStatusActive).
+		First(&agent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAgentCredential
+		}
+		return nil, fmt.Errorf("校验代理凭证失败: %w", err)
+	}
+	return &agent, nil
+}
+
+// Heartbeat 记录代理心跳，同时更新代理上报的版本和功能
+func (s *Service) Heartbeat(agent *model.Agent, info AgentInfo) error {
+	capabilities, err := json.Marshal(info.Capabilities)
+	if err != nil {
+		return fmt.Errorf("序列化代理功能失败: %w", err)
+	}
+
+	err = s.db.Model(agent).Updates(map[string]interface{}{
+		"hostname":          info.Hostname,
+		"version":           info.Version,
+		"os":                info.OS,
+		"arch":              info.Arch,
+		"capabilities":      string(capabilities),
+		"last_heartbeat_at": time.Now(),
+		"last_ip":           info.IP,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("记录代理心跳失败: %w", err)
+	}
+	return nil
+}
+
+// Ingest 接收代理上报的监控数据，上报同时视为一次心跳
+// 代理在后端不可达时会缓存样本，恢复后一次上报多个，这里按采集时间顺序处理
+func (s *Service) Ingest(ctx context.Context, agent *model.Agent, ip string, samples []SystemMetrics) error {
+	if len(samples) > MaxIngestBatch {
+		return fmt.Errorf("单次最多上报 %d 个样本", MaxIngestBatch)
+	}
+
+	now := time.Now()
+	s.db.Model(agent).Updates(map[string]interface{}{"last_heartbeat_at": now, "last_ip": ip})
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp.Before(samples[j].Timestamp)
+	})
+	for i := range samples {
+		metrics := &samples[i]
+		metrics.ServerID = agent.ServerID
+		if metrics.Timestamp.IsZero() || metrics.Timestamp.After(now.Add(maxClockSkew)) {
+			metrics.Timestamp = now
+		}
+		s.processMetrics(ctx, agent.ServerID, metrics)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrollmentUsable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.NoError(t, enrollmentUsable(&model.AgentEnrollmentToken{}, now))
+	assert.NoError(t, enrollmentUsable(&model.AgentEnrollmentToken{MaxUses: 1, ExpiresAt: &future}, now))
+	assert.NoError(t, enrollmentUsable(&model.AgentEnrollmentToken{MaxUses: 0, Uses: 100}, now))
+
+	// 一次性令牌用过后失效
+	assert.Error(t, enrollmentUsable(&model.AgentEnrollmentToken{MaxUses: 1, Uses: 1}, now))
+	assert.Error(t, enrollmentUsable(&model.AgentEnrollmentToken{ExpiresAt: &past}, now))
+	assert.Error(t, enrollmentUsable(&model.AgentEnrollmentToken{RevokedAt: &past}, now))
+}
+
+func TestEnrollmentAllowed(t *testing.T) {
+	token := &model.AgentEnrollmentToken{Group: "staging"}
+	server := &model.Server{Group: "staging", Environment: "test"}
+	assert.NoError(t, enrollmentAllowed(token, server, nil))
+	// 代理已吊销时可以重新注册
+	assert.NoError(t, enrollmentAllowed(token, server, &model.Agent{Status: model.AgentStatusRevoked}))
+
+	assert.ErrorIs(t, enrollmentAllowed(token, &model.Server{Group: "prod"}, nil), ErrEnrollmentDenied)
+	assert.ErrorIs(t, enrollmentAllowed(&model.AgentEnrollmentToken{}, server, nil), ErrEnrollmentDenied)
+	assert.ErrorIs(t, enrollmentAllowed(token, server, &model.Agent{Status: model.AgentStatusActive}), ErrEnrollmentDenied)
+
+	token.Environment = "prod"
+	assert.ErrorIs(t, enrollmentAllowed(token, server, nil), ErrEnrollmentDenied)
+	token.Environment = "test"
+	assert.NoError(t, enrollmentAllowed(token, server, nil))
+}
+
+func TestAgentOnline(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Minute)
+	stale := now.Add(-AgentOfflineAfter - time.Second)
+
+	assert.True(t, AgentOnline(&model.Agent{Status: model.AgentStatusActive, LastHeartbeatAt: &recent}, now))
+	assert.False(t, AgentOnline(&model.Agent{Status: model.AgentStatusActive, LastHeartbeatAt: &stale}, now))
+	assert.False(t, AgentOnline(&model.Agent{Status: model.AgentStatusActive}, now))
+	assert.False(t, AgentOnline(&model.Agent{Status: model.AgentStatusRevoked, LastHeartbeatAt: &recent}, now))
+}
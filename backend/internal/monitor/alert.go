@@ -0,0 +1,433 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"devops/internal/model"
+	"devops/internal/notify"
+
+	"gorm.io/gorm"
+)
+
+// alertSampleGap 相邻两个样本间隔超过该时长时不视为连续，持续时间重新计算
+const alertSampleGap = 5 * time.Minute
+
+// alertRuleTTL 告警规则在内存中的缓存时间，其他实例修改规则后最迟在该时长后生效
+const alertRuleTTL = 30 * time.Second
+
+// ErrAlertRuleNotFound 告警规则不存在
+var ErrAlertRuleNotFound = errors.New("告警规则不存在")
+
+// alertConditions 告警规则支持的比较条件
+var alertConditions = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	"<":  func(v, t float64) bool { return v < t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+// alertMetrics 告警规则支持的指标
+var alertMetrics = map[string]bool{
+	model.MetricCPU:     true,
+	model.MetricMemory:  true,
+	model.MetricDisk:    true,
+	model.MetricLoad:    true,
+	model.MetricNetRecv: true,
+	model.MetricNetSent: true,
+}
+
+// validateAlertRule 校验告警规则，并清理与作用范围无关的字段
+func validateAlertRule(rule *model.AlertRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return errors.New("告警规则名称不能为空")
+	}
+
+	switch rule.Scope {
+	case model.AlertScopeGlobal:
+		rule.ServerID, rule.Group = 0, ""
+	case model.AlertScopeGroup:
+		if rule.Group == "" {
+			return errors.New("分组告警规则需要指定服务器分组")
+		}
+		rule.ServerID = 0
+	case model.AlertScopeServer:
+		if rule.ServerID == 0 {
+			return errors.New("服务器告警规则需要指定服务器")
+		}
+		rule.Group = ""
+	default:
+		return fmt.Errorf("不支持的作用范围: %s", rule.Scope)
+	}
+
+	if !alertMetrics[rule.MetricType] {
+		return fmt.Errorf("不支持的告警指标: %s", rule.MetricType)
+	}
+	if rule.Mountpoint != "" && rule.MetricType != model.MetricDisk {
+		return errors.New("只有磁盘告警规则可以指定挂载点")
+	}
+	if _, ok := alertConditions[rule.Condition]; !ok {
+		return fmt.Errorf("不支持的比较条件: %s", rule.Condition)
+	}
+	if rule.Duration < 0 {
+		return errors.New("持续时间不能为负数")
+	}
+
+	if rule.NotifyWebhook && rule.WebhookChannelID == 0 {
+		return errors.New("勾选Webhook通知时需要选择Webhook渠道")
+	}
+	if err := validateTemplates(rule.TitleTemplate, rule.BodyTemplate); err != nil {
+		return err
+	}
+
+	switch rule.Level {
+	case "":
+		rule.Level = notify.LevelWarning
+	case notify.LevelInfo, notify.LevelWarning, notify.LevelCritical:
+	default:
+		return fmt.Errorf("不支持的告警级别: %s", rule.Level)
+	}
+	return nil
+}
+
+// validateTemplates 校验通知标题和正文模板
+func validateTemplates(title, body string) error {
+	if err := notify.ValidateTemplate(title); err != nil {
+		return fmt.Errorf("通知标题%w", err)
+	}
+	if err := notify.ValidateTemplate(body); err != nil {
+		return fmt.Errorf("通知正文%w", err)
+	}
+	return nil
+}
+
+// ruleApplies 判断告警规则是否适用于服务器
+func ruleApplies(rule *model.AlertRule, serverID uint, group string) bool {
+	switch rule.Scope {
+	case model.AlertScopeGlobal:
+		return true
+	case model.AlertScopeGroup:
+		return group != "" && rule.Group == group
+	case model.AlertScopeServer:
+		return rule.ServerID == serverID
+	}
+	return false
+}
+
+// alertSample 告警评估使用的一个指标值
+type alertSample struct {
+	Metric string
+	Labels map[string]string // 磁盘指标的 mountpoint
+	Value  float64
+}
+
+// alertSamples 从一份监控数据提取告警评估使用的指标值
+// 磁盘按分区分别评估，网络速率的计算与历史数据相同
+func alertSamples(cur, prev *SystemMetrics) []alertSample {
+	var samples []alertSample
+	for metric, value := range metricValues(cur, prev) {
+		if metric == model.MetricDisk {
+			continue
+		}
+		samples = append(samples, alertSample{Metric: metric, Value: value})
+	}
+	for _, p := range cur.Disk.Partitions {
+		samples = append(samples, alertSample{
+			Metric: model.MetricDisk,
+			Labels: map[string]string{"mountpoint": p.Mountpoint},
+			Value:  p.Usage,
+		})
+	}
+	return samples
+}
+
+// matches 判断指标值是否属于告警规则
+func (a alertSample) matches(rule *model.AlertRule) bool {
+	if a.Metric != rule.MetricType {
+		return false
+	}
+	return rule.Mountpoint == "" || a.Labels["mountpoint"] == rule.Mountpoint
+}
+
+// labelsKey 将标签按键排序后拼接，用于区分同一规则的不同指标值
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// alertPending 告警规则持续满足条件的状态
+type alertPending struct {
+	Since time.Time `json:"since"` // 连续满足条件的第一个样本时间
+	Last  time.Time `json:"last"`  // 最近一个满足条件的样本时间
+}
+
+// evaluateRule 用一个样本推进告警规则的持续状态，返回是否触发告警和新的状态
+// 不满足条件时状态为nil；与上一个满足条件的样本间隔超过 alertSampleGap 时重新计时
+func evaluateRule(rule *model.AlertRule, value float64, at time.Time, pending *alertPending) (bool, *alertPending) {
+	compare, ok := alertConditions[rule.Condition]
+	if !ok || !compare(value, rule.Threshold) {
+		return false, nil
+	}
+
+	next := &alertPending{Since: at, Last: at}
+	if pending != nil && !at.Before(pending.Since) && at.Sub(pending.Last) <= alertSampleGap {
+		next.Since = pending.Since
+	}
+	return at.Sub(next.Since) >= time.Duration(rule.Duration)*time.Second, next
+}
+
+// AlertEvaluation 一条告警规则对一个指标值的评估结果
+type AlertEvaluation struct {
+	Rule     *model.AlertRule
+	ServerID uint
+	Labels   map[string]string
+	Value    float64
+	Firing   bool      // 持续满足条件已达到规则的持续时间
+	Since    time.Time // 开始持续满足条件的时间，不满足条件时为零值
+}
+
+// alertRuleCache 启用的告警规则和服务器分组的内存缓存
+type alertRuleCache struct {
+	mu       sync.Mutex
+	rules    []model.AlertRule
+	groups   map[uint]string
+	loadedAt time.Time
+}
+
+// alertRules 返回启用的告警规则和服务器分组，缓存 alertRuleTTL
+func (s *Service) alertRules() ([]model.AlertRule, map[uint]string, error) {
+	c := &s.ruleCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < alertRuleTTL {
+		return c.rules, c.groups, nil
+	}
+
+	var rules []model.AlertRule
+	if err := s.db.Where("enabled = ?", true).Order("id").Find(&rules).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询告警规则失败: %w", err)
+	}
+
+	// 只有存在分组规则时才需要服务器分组
+	groups := make(map[uint]string)
+	for _, rule := range rules {
+		if rule.Scope != model.AlertScopeGroup {
+			continue
+		}
+		var servers []model.Server
+		if err := s.db.Select("id", "group_name").Where("group_name <> ''").Find(&servers).Error; err != nil {
+			return nil, nil, fmt.Errorf("查询服务器分组失败: %w", err)
+		}
+		for _, server := range servers {
+			groups[server.ID] = server.Group
+		}
+		break
+	}
+
+	c.rules, c.groups, c.loadedAt = rules, groups, time.Now()
+	return rules, groups, nil
+}
+
+// invalidateAlertRules 告警规则变更后清除本实例的缓存
+func (s *Service) invalidateAlertRules() {
+	s.ruleCache.mu.Lock()
+	s.ruleCache.loadedAt = time.Time{}
+	s.ruleCache.mu.Unlock()
+}
+
+// evaluateAlerts 用服务器的最新监控数据评估适用的告警规则，同时返回适用于该服务器的规则
+// 各规则的持续状态保存在缓存中，多个实例处理同一服务器的数据时共享
+func (s *Service) evaluateAlerts(ctx context.Context, serverID uint, cur, prev *SystemMetrics) ([]AlertEvaluation, map[uint]bool, error) {
+	rules, groups, err := s.alertRules()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pendingKey := s.keys.AlertPending(serverID)
+	var pending map[string]*alertPending
+	s.cache.Get(ctx, pendingKey, &pending)
+
+	samples := alertSamples(cur, prev)
+	next := make(map[string]*alertPending)
+	var results []AlertEvaluation
+	applied := make(map[uint]bool)
+	for i := range rules {
+		rule := &rules[i]
+		if !ruleApplies(rule, serverID, groups[serverID]) {
+			continue
+		}
+		applied[rule.ID] = true
+		for _, sample := range samples {
+			if !sample.matches(rule) {
+				continue
+			}
+			id := fmt.Sprintf("%d|%s", rule.ID, labelsKey(sample.Labels))
+			firing, state := evaluateRule(rule, sample.Value, cur.Timestamp, pending[id])
+			result := AlertEvaluation{
+				Rule:     rule,
+				ServerID: serverID,
+				Labels:   sample.Labels,
+				Value:    sample.Value,
+				Firing:   firing,
+			}
+			if state != nil {
+				next[id] = state
+				result.Since = state.Since
+			}
+			results = append(results, result)
+		}
+	}
+
+	// 状态在样本中断超过 alertSampleGap 后自然失效
+	if len(next) > 0 {
+		if err := s.cache.Set(ctx, pendingKey, next, alertSampleGap); err != nil {
+			return results, applied, fmt.Errorf("保存告警状态失败: %w", err)
+		}
+	} else if len(pending) > 0 {
+		s.cache.Delete(ctx, pendingKey)
+	}
+	return results, applied, nil
+}
+
+// ListAlertRules 获取告警规则列表
+func (s *Service) ListAlertRules() ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	if err := s.db.Order("id DESC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("查询告警规则失败: %w", err)
+	}
+	return rules, nil
+}
+
+// GetAlertRule 获取告警规则
+func (s *Service) GetAlertRule(id uint) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAlertRuleNotFound
+		}
+		return nil, fmt.Errorf("查询告警规则失败: %w", err)
+	}
+	return &rule, nil
+}
+
+// checkAlertRule 校验告警规则，服务器规则需要服务器存在
+func (s *Service) checkAlertRule(rule *model.AlertRule) error {
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+	if rule.Scope == model.AlertScopeServer {
+		var count int64
+		if err := s.db.Model(&model.Server{}).Where("id = ?", rule.ServerID).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询服务器失败: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("服务器 %d 不存在", rule.ServerID)
+		}
+	}
+
+	for _, id := range rule.ChannelIDs {
+		var count int64
+		if err := s.db.Model(&model.NotifyChannel{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询通知渠道失败: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("通知渠道 %d 不存在", id)
+		}
+	}
+
+	if rule.NotifyWebhook {
+		var channel model.NotifyChannel
+		if err := s.db.Select("type").First(&channel, rule.WebhookChannelID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("通知渠道 %d 不存在", rule.WebhookChannelID)
+			}
+			return fmt.Errorf("查询通知渠道失败: %w", err)
+		}
+		if channel.Type != model.NotifyChannelWebhook {
+			return fmt.Errorf("通知渠道 %d 不是通用Webhook渠道", rule.WebhookChannelID)
+		}
+	}
+	return nil
+}
+
+// CreateAlertRule 创建告警规则
+func (s *Service) CreateAlertRule(rule *model.AlertRule) error {
+	if err := s.checkAlertRule(rule); err != nil {
+		return err
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return fmt.Errorf("创建告警规则失败: %w", err)
+	}
+	s.invalidateAlertRules()
+	return nil
+}
+
+// UpdateAlertRule 更新告警规则
+func (s *Service) UpdateAlertRule(id uint, rule *model.AlertRule) error {
+	if err := s.checkAlertRule(rule); err != nil {
+		return err
+	}
+
+	channelIDs, err := json.Marshal(rule.ChannelIDs)
+	if err != nil {
+		return fmt.Errorf("序列化通知渠道失败: %w", err)
+	}
+	result := s.db.Model(&model.AlertRule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":               rule.Name,
+		"scope":              rule.Scope,
+		"server_id":          rule.ServerID,
+		"group_name":         rule.Group,
+		"metric_type":        rule.MetricType,
+		"mountpoint":         rule.Mountpoint,
+		"condition":          rule.Condition,
+		"threshold":          rule.Threshold,
+		"duration":           rule.Duration,
+		"level":              rule.Level,
+		"enabled":            rule.Enabled,
+		"channel_ids":        string(channelIDs),
+		"notify_email":       rule.NotifyEmail,
+		"notify_webhook":     rule.NotifyWebhook,
+		"webhook_channel_id": rule.WebhookChannelID,
+		"title_template":     rule.TitleTemplate,
+		"body_template":      rule.BodyTemplate,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("更新告警规则失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlertRuleNotFound
+	}
+	s.invalidateAlertRules()
+	return nil
+}
+
+// DeleteAlertRule 删除告警规则
+func (s *Service) DeleteAlertRule(id uint) error {
+	result := s.db.Delete(&model.AlertRule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除告警规则失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlertRuleNotFound
+	}
+	s.invalidateAlertRules()
+	return nil
+}
@@ -0,0 +1,372 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"devops/internal/model"
+	"devops/internal/notify"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlertNotFound 告警不存在
+var ErrAlertNotFound = errors.New("告警不存在")
+
+// AlertFilter 告警历史查询条件，零值表示不限
+type AlertFilter struct {
+	Status   string
+	ServerID uint
+	RuleID   uint
+	Level    string
+	Start    *time.Time // 触发时间范围
+	End      *time.Time
+}
+
+// alertKey 告警标识，由规则、服务器和标签组成
+func alertKey(ruleID, serverID uint, labels map[string]string) string {
+	return fmt.Sprintf("alert:%d:%d:%s", ruleID, serverID, labelsKey(labels))
+}
+
+// alertChanges 一次评估后需要写入的告警变化
+type alertChanges struct {
+	fire    []model.Alert // 新触发的告警
+	update  []model.Alert // 仍在触发的告警，只更新最新值
+	resolve []model.Alert // 条件已解除的告警
+}
+
+// reconcileAlerts 将服务器的评估结果与其触发中的告警对比，决定告警的变化
+// applied 为适用于该服务器的启用规则，规则已删除、停用或不再适用时其告警同样视为解除；
+// 规则仍适用但本次没有对应指标值（如缺少上一份数据无法计算网络速率）时保持原状，
+// 超过 alertSampleGap 仍没有指标值（如分区已卸载）时视为解除
+func reconcileAlerts(results []AlertEvaluation, open []model.Alert, applied map[uint]bool, at time.Time) alertChanges {
+	openByFingerprint := make(map[string]model.Alert, len(open))
+	for _, alert := range open {
+		openByFingerprint[alert.Fingerprint] = alert
+	}
+
+	var changes alertChanges
+	seen := make(map[string]bool)
+	for _, r := range results {
+		fingerprint := hashSecret(alertKey(r.Rule.ID, r.ServerID, r.Labels))
+		seen[fingerprint] = true
+		alert, firing := openByFingerprint[fingerprint]
+
+		switch {
+		case r.Firing && !firing:
+			changes.fire = append(changes.fire, model.Alert{
+				Fingerprint:     fingerprint,
+				OpenFingerprint: &fingerprint,
+				RuleID:          r.Rule.ID,
+				ServerID:        r.ServerID,
+				Name:            r.Rule.Name,
+				MetricType:      r.Rule.MetricType,
+				Labels:          r.Labels,
+				Level:           r.Rule.Level,
+				Status:          model.AlertStatusFiring,
+				Condition:       r.Rule.Condition,
+				Threshold:       r.Rule.Threshold,
+				Value:           r.Value,
+				StartsAt:        r.Since,
+				FiredAt:         at,
+				LastSeenAt:      at,
+			})
+		case r.Firing && firing:
+			alert.Value = r.Value
+			alert.LastSeenAt = at
+			changes.update = append(changes.update, alert)
+		case !r.Firing && firing:
+			alert.Value = r.Value
+			changes.resolve = append(changes.resolve, resolvedAlert(alert, at))
+		}
+	}
+
+	for _, alert := range open {
+		if !seen[alert.Fingerprint] && (!applied[alert.RuleID] || staleAlert(&alert, at)) {
+			changes.resolve = append(changes.resolve, resolvedAlert(alert, at))
+		}
+	}
+	return changes
+}
+
+// resolvedAlert 将告警标记为在 at 时恢复
+func resolvedAlert(alert model.Alert, at time.Time) model.Alert {
+	alert.Status = model.AlertStatusResolved
+	alert.ResolvedAt = &at
+	alert.OpenFingerprint = nil
+	return alert
+}
+
+// staleAlert 判断触发中的告警是否已超过 alertSampleGap 没有评估结果
+func staleAlert(alert *model.Alert, at time.Time) bool {
+	return at.Sub(alert.LastSeenAt) > alertSampleGap
+}
+
+// updateAlerts 根据评估结果更新服务器的告警，只在告警触发和恢复时发送通知
+func (s *Service) updateAlerts(ctx context.Context, serverID uint, results []AlertEvaluation, applied map[uint]bool, at time.Time) error {
+	var open []model.Alert
+	if err := s.db.Where("server_id = ? AND status = ?", serverID, model.AlertStatusFiring).Find(&open).Error; err != nil {
+		return fmt.Errorf("查询触发中的告警失败: %w", err)
+	}
+
+	changes := reconcileAlerts(results, open, applied, at)
+
+	// 同一指纹只能有一条触发中的告警，其他实例或重叠的评估已触发时忽略，不重复通知
+	for i := range changes.fire {
+		alert := &changes.fire[i]
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+		if result.Error != nil {
+			return fmt.Errorf("保存告警失败: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			s.notifyAlert(ctx, alert)
+		}
+	}
+
+	for _, alert := range changes.update {
+		err := s.db.Model(&model.Alert{}).Where("id = ?", alert.ID).Updates(map[string]interface{}{
+			"value":        alert.Value,
+			"last_seen_at": alert.LastSeenAt,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("更新告警失败: %w", err)
+		}
+	}
+
+	return s.resolveAlerts(ctx, changes.resolve)
+}
+
+// resolveAlerts 保存已恢复的告警并发送恢复通知
+func (s *Service) resolveAlerts(ctx context.Context, alerts []model.Alert) error {
+	for i := range alerts {
+		alert := &alerts[i]
+		// 只恢复仍在触发的告警，避免多个实例重复发送恢复通知
+		result := s.db.Model(&model.Alert{}).Where("id = ? AND status = ?", alert.ID, model.AlertStatusFiring).
+			Updates(map[string]interface{}{
+				"status":           alert.Status,
+				"value":            alert.Value,
+				"resolved_at":      alert.ResolvedAt,
+				"open_fingerprint": nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("恢复告警失败: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			s.notifyAlert(ctx, alert)
+		}
+	}
+	return nil
+}
+
+// ResolveStaleAlerts 恢复超过 alertSampleGap 没有评估结果的告警，如服务器已停止上报数据
+// 由采集主节点定期执行，覆盖不再产生评估的服务器
+func (s *Service) ResolveStaleAlerts(ctx context.Context, now time.Time) error {
+	var stale []model.Alert
+	err := s.db.Where("status = ? AND last_seen_at < ?", model.AlertStatusFiring, now.Add(-alertSampleGap)).
+		Find(&stale).Error
+	if err != nil {
+		return fmt.Errorf("查询过期告警失败: %w", err)
+	}
+	for i := range stale {
+		stale[i] = resolvedAlert(stale[i], now)
+	}
+	return s.resolveAlerts(ctx, stale)
+}
+
+// resolveServerAlerts 恢复服务器所有触发中的告警，用于服务器移出监控或代理被吊销
+func (s *Service) resolveServerAlerts(ctx context.Context, serverID uint, at time.Time) error {
+	var open []model.Alert
+	if err := s.db.Where("server_id = ? AND status = ?", serverID, model.AlertStatusFiring).Find(&open).Error; err != nil {
+		return fmt.Errorf("查询触发中的告警失败: %w", err)
+	}
+	for i := range open {
+		open[i] = resolvedAlert(open[i], at)
+	}
+	return s.resolveAlerts(ctx, open)
+}
+
+// notifyAlert 发送告警触发或恢复通知，服务器和规则已删除时仍使用其最后的信息
+func (s *Service) notifyAlert(ctx context.Context, alert *model.Alert) {
+	var server model.Server
+	if err := s.db.Unscoped().First(&server, alert.ServerID).Error; err != nil {
+		server = model.Server{ID: alert.ServerID}
+	}
+	var rule model.AlertRule
+	if err := s.db.Unscoped().First(&rule, alert.RuleID).Error; err != nil {
+		s.notifier.Notify(ctx, alertMessage(alert, &server, nil))
+		return
+	}
+	s.notifier.Notify(ctx, alertMessage(alert, &server, &rule))
+}
+
+// alertMessage 生成告警通知，rule 为空时使用默认发送目标和默认格式
+func alertMessage(alert *model.Alert, server *model.Server, rule *model.AlertRule) notify.Message {
+	serverName := server.Name
+	if serverName == "" {
+		serverName = fmt.Sprintf("服务器%d", alert.ServerID)
+	}
+	labels := map[string]string{
+		"alert_id":  fmt.Sprint(alert.ID),
+		"server_id": fmt.Sprint(alert.ServerID),
+		"server":    serverName,
+		"rule_id":   fmt.Sprint(alert.RuleID),
+		"metric":    alert.MetricType,
+	}
+	if server.Host != "" {
+		labels["host"] = server.Host
+	}
+	target := alert.MetricType
+	for k, v := range alert.Labels {
+		labels[k] = v
+		target += " " + v
+	}
+	where := serverName
+	if server.Host != "" {
+		where = fmt.Sprintf("%s（%s）", serverName, server.Host)
+	}
+
+	data := &notify.Alert{
+		ID:         alert.ID,
+		Status:     alert.Status,
+		RuleID:     alert.RuleID,
+		RuleName:   alert.Name,
+		ServerID:   alert.ServerID,
+		ServerName: serverName,
+		ServerHost: server.Host,
+		Metric:     alert.MetricType,
+		Labels:     alert.Labels,
+		Condition:  alert.Condition,
+		Value:      alert.Value,
+		Threshold:  alert.Threshold,
+		StartsAt:   alert.StartsAt,
+		FiredAt:    alert.FiredAt,
+		ResolvedAt: alert.ResolvedAt,
+		Duration:   alert.FiredAt.Sub(alert.StartsAt).Round(time.Second),
+	}
+
+	msg := notify.Message{
+		Source: notify.SourceMonitor,
+		Key:    alertKey(alert.RuleID, alert.ServerID, alert.Labels),
+		Level:  alert.Level,
+		Title:  serverName + " " + alert.Name,
+		Content: fmt.Sprintf("%s %s 当前值 %.2f，条件 %s %.2f，已持续 %s", where, target,
+			alert.Value, alert.Condition, alert.Threshold, data.Duration),
+		Labels: labels,
+		Time:   alert.FiredAt,
+		Alert:  data,
+	}
+	if alert.Status == model.AlertStatusResolved && alert.ResolvedAt != nil {
+		data.Duration = alert.ResolvedAt.Sub(alert.StartsAt).Round(time.Second)
+		msg.Level = notify.LevelInfo
+		msg.Title += " 已恢复"
+		msg.Content = fmt.Sprintf("%s %s 当前值 %.2f，告警持续 %s", where, target, alert.Value, data.Duration)
+		msg.Time = *alert.ResolvedAt
+	}
+	if rule != nil {
+		msg.Route = alertRoute(rule)
+		msg.Template = notify.Template{Title: rule.TitleTemplate, Body: rule.BodyTemplate}
+	}
+	return msg
+}
+
+// alertRoute 按告警规则的通知设置确定发送目标
+func alertRoute(rule *model.AlertRule) notify.Route {
+	route := notify.Route{Channels: rule.ChannelIDs}
+	if rule.NotifyEmail {
+		route.Types = []string{model.NotifyChannelEmail}
+	}
+	if rule.NotifyWebhook && rule.WebhookChannelID > 0 {
+		route.Channels = append(append([]uint(nil), rule.ChannelIDs...), rule.WebhookChannelID)
+	}
+	return route
+}
+
+// ListFiringAlerts 获取触发中的告警，serverID 为0时返回所有服务器的告警
+func (s *Service) ListFiringAlerts(serverID uint) ([]model.Alert, error) {
+	var alerts []model.Alert
+	query := s.db.Preload("Server").Where("status = ?", model.AlertStatusFiring)
+	if serverID > 0 {
+		query = query.Where("server_id = ?", serverID)
+	}
+	if err := query.Order("fired_at DESC").Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("查询告警失败: %w", err)
+	}
+	return alerts, nil
+}
+
+// ListAlerts 分页查询告警历史
+func (s *Service) ListAlerts(filter AlertFilter, page, pageSize int) ([]model.Alert, int64, error) {
+	var alerts []model.Alert
+	var total int64
+
+	query := s.db.Model(&model.Alert{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ServerID > 0 {
+		query = query.Where("server_id = ?", filter.ServerID)
+	}
+	if filter.RuleID > 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.Level != "" {
+		query = query.Where("level = ?", filter.Level)
+	}
+	if filter.Start != nil {
+		query = query.Where("fired_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("fired_at < ?", *filter.End)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询告警总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Server").Order("id DESC").Offset(offset).Limit(pageSize).Find(&alerts).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询告警失败: %w", err)
+	}
+	return alerts, total, nil
+}
+
+// GetAlert 获取告警详情
+func (s *Service) GetAlert(id uint) (*model.Alert, error) {
+	var alert model.Alert
+	if err := s.db.Preload("Server").First(&alert, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAlertNotFound
+		}
+		return nil, fmt.Errorf("查询告警失败: %w", err)
+	}
+	return &alert, nil
+}
+
+// AcknowledgeAlert 确认触发中的告警，表示已有人处理
+func (s *Service) AcknowledgeAlert(id, userID uint) error {
+	now := time.Now()
+	result := s.db.Model(&model.Alert{}).
+		Where("id = ? AND status = ? AND acknowledged_at IS NULL", id, model.AlertStatusFiring).
+		Updates(map[string]interface{}{
+			"acknowledged_by": userID,
+			"acknowledged_at": now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("确认告警失败: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	alert, err := s.GetAlert(id)
+	if err != nil {
+		return err
+	}
+	if alert.Status != model.AlertStatusFiring {
+		return errors.New("告警已恢复，无需确认")
+	}
+	return errors.New("告警已被确认")
+}
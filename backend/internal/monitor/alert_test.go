@@ -0,0 +1,264 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlertRule(t *testing.T) {
+	rule := &model.AlertRule{
+		Name:       "磁盘使用率过高",
+		Scope:      model.AlertScopeGroup,
+		ServerID:   3,
+		Group:      "web",
+		MetricType: model.MetricDisk,
+		Mountpoint: "/data",
+		Condition:  ">=",
+		Threshold:  90,
+	}
+	require.NoError(t, validateAlertRule(rule))
+	assert.Zero(t, rule.ServerID)
+	assert.Equal(t, "warning", rule.Level)
+
+	invalid := []model.AlertRule{
+		{Name: "a", Scope: "all", MetricType: model.MetricCPU, Condition: ">"},
+		{Name: "a", Scope: model.AlertScopeServer, MetricType: model.MetricCPU, Condition: ">"},
+		{Name: "a", Scope: model.AlertScopeGroup, MetricType: model.MetricCPU, Condition: ">"},
+		{Name: "a", Scope: model.AlertScopeGlobal, MetricType: "network", Condition: ">"},
+		{Name: "a", Scope: model.AlertScopeGlobal, MetricType: model.MetricCPU, Condition: "=>"},
+		{Name: "a", Scope: model.AlertScopeGlobal, MetricType: model.MetricCPU, Mountpoint: "/", Condition: ">"},
+		{Name: "a", Scope: model.AlertScopeGlobal, MetricType: model.MetricCPU, Condition: ">", Duration: -1},
+		{Name: "a", Scope: model.AlertScopeGlobal, MetricType: model.MetricCPU, Condition: ">", Level: "fatal"},
+		{Name: "a", Scope: model.AlertScopeGlobal, MetricType: model.MetricCPU, Condition: ">", NotifyWebhook: true},
+	}
+	for i := range invalid {
+		assert.Error(t, validateAlertRule(&invalid[i]), "rule %d", i)
+	}
+}
+
+func TestRuleApplies(t *testing.T) {
+	global := &model.AlertRule{Scope: model.AlertScopeGlobal}
+	group := &model.AlertRule{Scope: model.AlertScopeGroup, Group: "web"}
+	server := &model.AlertRule{Scope: model.AlertScopeServer, ServerID: 2}
+
+	assert.True(t, ruleApplies(global, 1, ""))
+	assert.True(t, ruleApplies(group, 1, "web"))
+	assert.False(t, ruleApplies(group, 1, "db"))
+	assert.False(t, ruleApplies(group, 1, ""))
+	assert.True(t, ruleApplies(server, 2, "web"))
+	assert.False(t, ruleApplies(server, 1, "web"))
+}
+
+func TestAlertConditions(t *testing.T) {
+	cases := []struct {
+		cond string
+		want []bool // 值依次为 4、5、6，阈值为 5
+	}{
+		{">", []bool{false, false, true}},
+		{"<", []bool{true, false, false}},
+		{">=", []bool{false, true, true}},
+		{"<=", []bool{true, true, false}},
+		{"==", []bool{false, true, false}},
+		{"!=", []bool{true, false, true}},
+	}
+	for _, c := range cases {
+		for i, value := range []float64{4, 5, 6} {
+			assert.Equal(t, c.want[i], alertConditions[c.cond](value, 5), "%v %s 5", value, c.cond)
+		}
+	}
+}
+
+func TestAlertSamples(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := &SystemMetrics{
+		Timestamp: start,
+		Network:   NetworkMetrics{Interfaces: []NetworkInterface{{BytesRecv: 1000}}},
+	}
+	cur := &SystemMetrics{
+		Timestamp: start.Add(10 * time.Second),
+		CPU:       CPUMetrics{Usage: 50},
+		Disk: DiskMetrics{Partitions: []PartitionMetrics{
+			{Mountpoint: "/", Usage: 40},
+			{Mountpoint: "/data", Usage: 95},
+		}},
+		Network: NetworkMetrics{Interfaces: []NetworkInterface{{BytesRecv: 3000}}},
+	}
+
+	disk := &model.AlertRule{MetricType: model.MetricDisk}
+	data := &model.AlertRule{MetricType: model.MetricDisk, Mountpoint: "/data"}
+	recv := &model.AlertRule{MetricType: model.MetricNetRecv}
+
+	var diskValues, dataValues, recvValues []float64
+	for _, s := range alertSamples(cur, prev) {
+		if s.matches(disk) {
+			diskValues = append(diskValues, s.Value)
+		}
+		if s.matches(data) {
+			dataValues = append(dataValues, s.Value)
+		}
+		if s.matches(recv) {
+			recvValues = append(recvValues, s.Value)
+		}
+	}
+	assert.ElementsMatch(t, []float64{40, 95}, diskValues)
+	assert.Equal(t, []float64{95}, dataValues)
+	assert.Equal(t, []float64{200}, recvValues)
+
+	// 没有上一份数据时不评估网络速率
+	for _, s := range alertSamples(cur, nil) {
+		assert.False(t, s.matches(recv))
+	}
+}
+
+func TestEvaluateRuleDuration(t *testing.T) {
+	rule := &model.AlertRule{Condition: ">", Threshold: 80, Duration: 300}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 每30秒一个样本，持续满足条件满5分钟后触发
+	var pending *alertPending
+	var firing bool
+	for i := 0; i <= 10; i++ {
+		firing, pending = evaluateRule(rule, 90, start.Add(time.Duration(i)*30*time.Second), pending)
+		require.NotNil(t, pending)
+		assert.Equal(t, i == 10, firing, "sample %d", i)
+	}
+	assert.Equal(t, start, pending.Since)
+
+	// 恢复正常后重新计时
+	firing, state := evaluateRule(rule, 50, start.Add(330*time.Second), pending)
+	assert.False(t, firing)
+	assert.Nil(t, state)
+
+	// 样本中断超过 alertSampleGap 时不视为连续
+	firing, state = evaluateRule(rule, 90, start.Add(11*time.Minute), pending)
+	assert.False(t, firing)
+	assert.Equal(t, start.Add(11*time.Minute), state.Since)
+
+	// 持续时间为0时满足条件立即触发
+	firing, _ = evaluateRule(&model.AlertRule{Condition: ">=", Threshold: 80}, 80, start, nil)
+	assert.True(t, firing)
+}
+
+func TestLabelsKey(t *testing.T) {
+	assert.Equal(t, "", labelsKey(nil))
+	assert.Equal(t, "a=1,mountpoint=/", labelsKey(map[string]string{"mountpoint": "/", "a": "1"}))
+}
+
+func TestReconcileAlerts(t *testing.T) {
+	cpu := &model.AlertRule{ID: 1, Name: "CPU使用率过高", MetricType: model.MetricCPU, Condition: ">", Threshold: 80, Level: "critical"}
+	disk := &model.AlertRule{ID: 2, Name: "磁盘使用率过高", MetricType: model.MetricDisk, Condition: ">", Threshold: 90}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	applied := map[uint]bool{1: true, 2: true}
+	root := map[string]string{"mountpoint": "/"}
+	data := map[string]string{"mountpoint": "/data"}
+
+	// 首次满足条件时触发，指纹区分同一规则的不同分区
+	changes := reconcileAlerts([]AlertEvaluation{
+		{Rule: cpu, ServerID: 1, Value: 95, Firing: true, Since: start.Add(-5 * time.Minute)},
+		{Rule: disk, ServerID: 1, Labels: root, Value: 50},
+		{Rule: disk, ServerID: 1, Labels: data, Value: 95, Firing: true, Since: start},
+	}, nil, applied, start)
+	require.Len(t, changes.fire, 2)
+	assert.Empty(t, changes.update)
+	assert.Empty(t, changes.resolve)
+	fired := changes.fire[0]
+	assert.Equal(t, model.AlertStatusFiring, fired.Status)
+	assert.Equal(t, "critical", fired.Level)
+	assert.Equal(t, start.Add(-5*time.Minute), fired.StartsAt)
+	assert.Equal(t, start, fired.FiredAt)
+	require.NotNil(t, fired.OpenFingerprint)
+	assert.Equal(t, fired.Fingerprint, *fired.OpenFingerprint)
+	assert.Equal(t, data, changes.fire[1].Labels)
+	assert.NotEqual(t, fired.Fingerprint, changes.fire[1].Fingerprint)
+	assert.NotEqual(t, hashSecret(alertKey(2, 1, root)), changes.fire[1].Fingerprint)
+
+	open := []model.Alert{changes.fire[0], changes.fire[1]}
+	open[0].ID, open[1].ID = 10, 11
+
+	// 持续触发时只更新，不重复触发；条件解除时恢复
+	later := start.Add(30 * time.Second)
+	changes = reconcileAlerts([]AlertEvaluation{
+		{Rule: cpu, ServerID: 1, Value: 97, Firing: true, Since: start.Add(-5 * time.Minute)},
+		{Rule: disk, ServerID: 1, Labels: data, Value: 70},
+	}, open, applied, later)
+	assert.Empty(t, changes.fire)
+	require.Len(t, changes.update, 1)
+	assert.Equal(t, uint(10), changes.update[0].ID)
+	assert.Equal(t, 97.0, changes.update[0].Value)
+	assert.Equal(t, later, changes.update[0].LastSeenAt)
+	require.Len(t, changes.resolve, 1)
+	assert.Equal(t, uint(11), changes.resolve[0].ID)
+	assert.Equal(t, model.AlertStatusResolved, changes.resolve[0].Status)
+	assert.Equal(t, later, *changes.resolve[0].ResolvedAt)
+	assert.Nil(t, changes.resolve[0].OpenFingerprint)
+
+	// 规则仍适用但没有指标值时保持原状，规则被删除或停用时恢复
+	changes = reconcileAlerts(nil, open[:1], applied, later)
+	assert.Empty(t, changes.resolve)
+	changes = reconcileAlerts(nil, open[:1], map[uint]bool{2: true}, later)
+	require.Len(t, changes.resolve, 1)
+	assert.Equal(t, uint(10), changes.resolve[0].ID)
+
+	// 超过 alertSampleGap 仍没有指标值（如分区已卸载）时恢复
+	gone := start.Add(alertSampleGap + time.Second)
+	changes = reconcileAlerts([]AlertEvaluation{
+		{Rule: cpu, ServerID: 1, Value: 97, Firing: true, Since: start.Add(-5 * time.Minute)},
+	}, open, applied, gone)
+	require.Len(t, changes.update, 1)
+	require.Len(t, changes.resolve, 1)
+	assert.Equal(t, uint(11), changes.resolve[0].ID)
+	assert.Equal(t, gone, *changes.resolve[0].ResolvedAt)
+	assert.Nil(t, changes.resolve[0].OpenFingerprint)
+}
+
+func TestAlertMessage(t *testing.T) {
+	startsAt := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	alert := &model.Alert{
+		ID:         7,
+		RuleID:     1,
+		ServerID:   2,
+		Name:       "磁盘使用率过高",
+		MetricType: model.MetricDisk,
+		Labels:     map[string]string{"mountpoint": "/data"},
+		Level:      "critical",
+		Status:     model.AlertStatusFiring,
+		Condition:  ">",
+		Threshold:  90,
+		Value:      95,
+		StartsAt:   startsAt,
+		FiredAt:    startsAt.Add(5 * time.Minute),
+	}
+	server := &model.Server{ID: 2, Name: "db-01", Host: "10.0.0.8"}
+	rule := &model.AlertRule{ChannelIDs: []uint{3}, NotifyEmail: true, TitleTemplate: "{{.Alert.ServerName}}"}
+
+	msg := alertMessage(alert, server, rule)
+	assert.Equal(t, "db-01 磁盘使用率过高", msg.Title)
+	assert.Equal(t, "db-01（10.0.0.8） disk /data 当前值 95.00，条件 > 90.00，已持续 5m0s", msg.Content)
+	assert.Equal(t, "10.0.0.8", msg.Labels["host"])
+	assert.Equal(t, []uint{3}, msg.Route.Channels)
+	assert.Equal(t, []string{model.NotifyChannelEmail}, msg.Route.Types)
+	assert.Equal(t, "{{.Alert.ServerName}}", msg.Template.Title)
+	require.NotNil(t, msg.Alert)
+	assert.Equal(t, "db-01", msg.Alert.ServerName)
+	assert.Equal(t, 5*time.Minute, msg.Alert.Duration)
+
+	// 恢复时为告警总时长，服务器已不存在时使用编号
+	resolvedAt := startsAt.Add(20 * time.Minute)
+	alert.Status, alert.ResolvedAt, alert.Value = model.AlertStatusResolved, &resolvedAt, 40
+	msg = alertMessage(alert, &model.Server{}, nil)
+	assert.Equal(t, "服务器2 磁盘使用率过高 已恢复", msg.Title)
+	assert.Equal(t, "服务器2 disk /data 当前值 40.00，告警持续 20m0s", msg.Content)
+	assert.Equal(t, 20*time.Minute, msg.Alert.Duration)
+	assert.True(t, msg.Route.Channels == nil && msg.Template.Title == "")
+
+	// 勾选Webhook通知时推送到选定的Webhook渠道，不修改规则的渠道列表
+	rule.NotifyWebhook, rule.WebhookChannelID = true, 5
+	msg = alertMessage(alert, server, rule)
+	assert.Equal(t, []uint{3, 5}, msg.Route.Channels)
+	assert.Equal(t, []uint{3}, rule.ChannelIDs)
+}
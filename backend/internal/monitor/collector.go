@@ -18,61 +18,103 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 )
 
+// MetricsSource 监控数据来源
+// 本机通过 gopsutil 采集，远程服务器通过 SSH 读取 /proc 等采集，两者可以互换
+type MetricsSource interface {
+	// Collect 采集一次系统指标，ServerID 和 Timestamp 由 Collector 填写
+	Collect(ctx context.Context) (*SystemMetrics, error)
+	// Close 释放数据来源持有的连接等资源
+	Close() error
+}
+
 // Collector 监控数据收集器
 type Collector struct {
 	serverID uint
+	source   MetricsSource
 }
 
-// NewCollector 创建监控数据收集器
+// NewCollector 创建采集本机数据的监控数据收集器
 func NewCollector(serverID uint) *Collector {
+	return NewCollectorWithSource(serverID, NewLocalSource())
+}
+
+// NewCollectorWithSource 创建使用指定数据来源的监控数据收集器
+func NewCollectorWithSource(serverID uint, source MetricsSource) *Collector {
 	return &Collector{
 		serverID: serverID,
+		source:   source,
 	}
 }
 
 // CollectSystemMetrics 收集系统监控指标
 func (c *Collector) CollectSystemMetrics(ctx context.Context) (*SystemMetrics, error) {
-	metrics := &SystemMetrics{
-		ServerID:  c.serverID,
-		Timestamp: time.Now(),
+	metrics, err := c.source.Collect(ctx)
+	if err != nil {
+		return nil, err
 	}
+	metrics.ServerID = c.serverID
+	metrics.Timestamp = time.Now()
+	return metrics, nil
+}
+
+// Close 关闭收集器的数据来源
+func (c *Collector) Close() error {
+	return c.source.Close()
+}
+
+// LocalSource 通过 gopsutil 采集后端所在主机的监控数据
+type LocalSource struct{}
+
+// NewLocalSource 创建本机监控数据来源
+func NewLocalSource() *LocalSource {
+	return &LocalSource{}
+}
+
+// Close 本机数据来源没有需要释放的资源
+func (l *LocalSource) Close() error {
+	return nil
+}
+
+// Collect 收集本机系统监控指标
+func (l *LocalSource) Collect(ctx context.Context) (*SystemMetrics, error) {
+	metrics := &SystemMetrics{}
 
 	// 并发收集各项指标
 	errChan := make(chan error, 6)
 
 	go func() {
 		var err error
-		metrics.CPU, err = c.collectCPUMetrics(ctx)
+		metrics.CPU, err = l.collectCPUMetrics(ctx)
 		errChan <- err
 	}()
 
 	go func() {
 		var err error
-		metrics.Memory, err = c.collectMemoryMetrics(ctx)
+		metrics.Memory, err = l.collectMemoryMetrics(ctx)
 		errChan <- err
 	}()
 
 	go func() {
 		var err error
-		metrics.Disk, err = c.collectDiskMetrics(ctx)
+		metrics.Disk, err = l.collectDiskMetrics(ctx)
 		errChan <- err
 	}()
 
 	go func() {
 		var err error
-		metrics.Network, err = c.collectNetworkMetrics(ctx)
+		metrics.Network, err = l.collectNetworkMetrics(ctx)
 		errChan <- err
 	}()
 
 	go func() {
 		var err error
-		metrics.Load, err = c.collectLoadMetrics(ctx)
+		metrics.Load, err = l.collectLoadMetrics(ctx)
 		errChan <- err
 	}()
 
 	go func() {
 		var err error
-		metrics.Processes, metrics.Uptime, err = c.collectSystemInfo(ctx)
+		metrics.Processes, metrics.Uptime, err = l.collectSystemInfo(ctx)
 		errChan <- err
 	}()
 
@@ -87,7 +129,7 @@ func (c *Collector) CollectSystemMetrics(ctx context.Context) (*SystemMetrics, e
 }
 
 // collectCPUMetrics 收集CPU指标
-func (c *Collector) collectCPUMetrics(ctx context.Context) (CPUMetrics, error) {
+func (l *LocalSource) collectCPUMetrics(ctx context.Context) (CPUMetrics, error) {
 	// 获取CPU使用率
 	cpuPercent, err := cpu.PercentWithContext(ctx, time.Second, false)
 	if err != nil {
@@ -129,7 +171,7 @@ func (c *Collector) collectCPUMetrics(ctx context.Context) (CPUMetrics, error) {
 }
 
 // collectMemoryMetrics 收集内存指标
-func (c *Collector) collectMemoryMetrics(ctx context.Context) (MemoryMetrics, error) {
+func (l *LocalSource) collectMemoryMetrics(ctx context.Context) (MemoryMetrics, error) {
 	vmStat, err := mem.VirtualMemoryWithContext(ctx)
 	if err != nil {
 		return MemoryMetrics{}, err
@@ -155,7 +197,7 @@ func (c *Collector) collectMemoryMetrics(ctx context.Context) (MemoryMetrics, er
 }
 
 // collectDiskMetrics 收集磁盘指标
-func (c *Collector) collectDiskMetrics(ctx context.Context) (DiskMetrics, error) {
+func (l *LocalSource) collectDiskMetrics(ctx context.Context) (DiskMetrics, error) {
 	// 获取磁盘分区信息
 	partitions, err := disk.PartitionsWithContext(ctx, false)
 	if err != nil {
@@ -210,7 +252,7 @@ func (c *Collector) collectDiskMetrics(ctx context.Context) (DiskMetrics, error)
 }
 
 // collectNetworkMetrics 收集网络指标
-func (c *Collector) collectNetworkMetrics(ctx context.Context) (NetworkMetrics, error) {
+func (l *LocalSource) collectNetworkMetrics(ctx context.Context) (NetworkMetrics, error) {
 	interfaces, err := net.IOCountersWithContext(ctx, true)
 	if err != nil {
 		return NetworkMetrics{}, err
@@ -242,7 +284,7 @@ func (c *Collector) collectNetworkMetrics(ctx context.Context) (NetworkMetrics,
 }
 
 // collectLoadMetrics 收集负载指标
-func (c *Collector) collectLoadMetrics(ctx context.Context) (LoadMetrics, error) {
+func (l *LocalSource) collectLoadMetrics(ctx context.Context) (LoadMetrics, error) {
 	loadStat, err := load.AvgWithContext(ctx)
 	if err != nil {
 		return LoadMetrics{}, err
@@ -256,7 +298,7 @@ func (c *Collector) collectLoadMetrics(ctx context.Context) (LoadMetrics, error)
 }
 
 // collectSystemInfo 收集系统信息
-func (c *Collector) collectSystemInfo(ctx context.Context) (int, int64, error) {
+func (l *LocalSource) collectSystemInfo(ctx context.Context) (int, int64, error) {
 	// 获取运行时间
 	hostInfo, err := host.InfoWithContext(ctx)
 	if err != nil {
@@ -272,7 +314,7 @@ func (c *Collector) collectSystemInfo(ctx context.Context) (int, int64, error) {
 	return len(processes), int64(hostInfo.Uptime), nil
 }
 
-// CollectProcessMetrics 收集进程指标
+// CollectProcessMetrics 收集本机进程指标
 func (c *Collector) CollectProcessMetrics(ctx context.Context, limit int) ([]ProcessMetrics, error) {
 	pids, err := process.PidsWithContext(ctx)
 	if err != nil {
@@ -0,0 +1,445 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CompactInterval 历史数据汇总和清理的间隔
+const CompactInterval = time.Minute
+
+const (
+	// rollupLateness 代理补报的样本可能晚到，汇总时重新计算最近这段时间内的时间段
+	rollupLateness = 10 * time.Minute
+	// compactWindow 每次从数据库读取的源数据时间跨度，避免首次汇总时一次读入过多数据
+	compactWindow = 6 * time.Hour
+	// compactBatch 每批写入或删除的数据点数
+	compactBatch = 1000
+)
+
+// rollupTiers 逐级汇总：原始样本汇总为1分钟，1分钟汇总为5分钟，5分钟汇总为1小时
+var rollupTiers = []struct{ resolution, source int }{
+	{model.MetricResolutionMinute, model.MetricResolutionRaw},
+	{model.MetricResolution5m, model.MetricResolutionMinute},
+	{model.MetricResolutionHour, model.MetricResolution5m},
+}
+
+// historyRanges 历史查询支持的时间范围及默认精度，数据点数控制在几百以内
+var historyRanges = map[string]struct {
+	span       time.Duration
+	resolution int
+}{
+	"1h":  {time.Hour, model.MetricResolutionRaw},
+	"6h":  {6 * time.Hour, model.MetricResolutionMinute},
+	"24h": {24 * time.Hour, model.MetricResolution5m},
+	"7d":  {7 * 24 * time.Hour, model.MetricResolutionHour},
+}
+
+// historyMetrics 历史查询支持的指标，network 同时返回接收和发送速率
+var historyMetrics = map[string][]string{
+	model.MetricCPU:     {model.MetricCPU},
+	model.MetricMemory:  {model.MetricMemory},
+	model.MetricDisk:    {model.MetricDisk},
+	model.MetricLoad:    {model.MetricLoad},
+	model.MetricNetRecv: {model.MetricNetRecv},
+	model.MetricNetSent: {model.MetricNetSent},
+	"network":           {model.MetricNetRecv, model.MetricNetSent},
+}
+
+// MetricSeries 一个指标的历史数据
+type MetricSeries struct {
+	Metric string              `json:"metric"`
+	Data   []model.MetricPoint `json:"data"`
+}
+
+// History 服务器历史监控数据
+type History struct {
+	ServerID   uint           `json:"server_id"`
+	Metric     string         `json:"metric"`
+	TimeRange  string         `json:"time_range"`
+	Resolution int            `json:"resolution"` // 数据点精度（秒），0 为原始样本
+	Series     []MetricSeries `json:"series"`
+}
+
+// HistoryStore 监控历史数据存储
+// 原始样本逐级汇总为1分钟、5分钟、1小时的平均值、最小值和最大值，各级按配置的时长保留
+type HistoryStore struct {
+	db     *gorm.DB
+	config config.MetricsHistory
+}
+
+// NewHistoryStore 创建监控历史数据存储
+func NewHistoryStore(db *gorm.DB, cfg config.MetricsHistory) *HistoryStore {
+	return &HistoryStore{db: db, config: cfg}
+}
+
+// retention 返回指定精度数据的保留时长，0 表示不限制
+func (h *HistoryStore) retention(resolution int) time.Duration {
+	var hours int
+	switch resolution {
+	case model.MetricResolutionRaw:
+		hours = h.config.Raw
+	case model.MetricResolutionMinute:
+		hours = h.config.Minute
+	case model.MetricResolution5m:
+		hours = h.config.FiveMinute
+	case model.MetricResolutionHour:
+		hours = h.config.Hour
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// metricValues 从一份监控数据提取需要保存历史的指标
+// 网络速率由与上一份数据的计数差计算，没有上一份数据或计数器回绕时不记录
+func metricValues(cur, prev *SystemMetrics) map[string]float64 {
+	values := map[string]float64{
+		model.MetricCPU:    cur.CPU.Usage,
+		model.MetricMemory: cur.Memory.Usage,
+		model.MetricLoad:   cur.Load.Load1,
+	}
+
+	var disk float64
+	for _, p := range cur.Disk.Partitions {
+		disk = max(disk, p.Usage)
+	}
+	values[model.MetricDisk] = disk
+
+	if prev == nil {
+		return values
+	}
+	seconds := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+	if seconds <= 0 {
+		return values
+	}
+	recv, sent := networkTotals(cur)
+	prevRecv, prevSent := networkTotals(prev)
+	if recv >= prevRecv && sent >= prevSent {
+		values[model.MetricNetRecv] = float64(recv-prevRecv) / seconds
+		values[model.MetricNetSent] = float64(sent-prevSent) / seconds
+	}
+	return values
+}
+
+// networkTotals 汇总所有网络接口的收发字节数
+func networkTotals(m *SystemMetrics) (recv, sent uint64) {
+	for _, iface := range m.Network.Interfaces {
+		recv += iface.BytesRecv
+		sent += iface.BytesSent
+	}
+	return recv, sent
+}
+
+// Record 保存一份监控数据的原始样本，prev 为该服务器的上一份数据，用于计算速率
+func (h *HistoryStore) Record(ctx context.Context, cur, prev *SystemMetrics) error {
+	values := metricValues(cur, prev)
+	points := make([]model.MetricPoint, 0, len(values))
+	for metric, value := range values {
+		points = append(points, model.MetricPoint{
+			ServerID:   cur.ServerID,
+			Metric:     metric,
+			Resolution: model.MetricResolutionRaw,
+			Time:       cur.Timestamp,
+			Avg:        value,
+			Min:        value,
+			Max:        value,
+			Count:      1,
+		})
+	}
+
+	// 代理重复上报同一样本时忽略
+	err := h.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&points).Error
+	if err != nil {
+		return fmt.Errorf("保存监控历史数据失败: %w", err)
+	}
+	if lateSample(cur.Timestamp, time.Now()) {
+		return h.markRollup(ctx, cur.ServerID, cur.Timestamp)
+	}
+	return nil
+}
+
+// lateSample 样本是否可能早于常规汇总的回溯范围，留出一半的余量
+func lateSample(at, now time.Time) bool {
+	return at.Before(now.Add(-rollupLateness / 2))
+}
+
+// markRollup 记录服务器需要从 since 开始重新汇总，已有记录时保留较早的时间
+func (h *HistoryStore) markRollup(ctx context.Context, serverID uint, since time.Time) error {
+	err := h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "server_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"since": gorm.Expr("LEAST(since, VALUES(since))")}),
+	}).Create(&model.MetricRollupMark{ServerID: serverID, Since: since}).Error
+	if err != nil {
+		return fmt.Errorf("记录监控数据重新汇总失败: %w", err)
+	}
+	return nil
+}
+
+// historyResolution 选择查询时间范围使用的精度
+// 默认精度的数据保留时长不足以覆盖整个范围时改用更粗的精度
+func (h *HistoryStore) historyResolution(timeRange string) (time.Duration, int, error) {
+	r, ok := historyRanges[timeRange]
+	if !ok {
+		return 0, 0, fmt.Errorf("不支持的时间范围: %s", timeRange)
+	}
+
+	resolution := r.resolution
+	for _, tier := range rollupTiers {
+		if tier.source != resolution {
+			continue
+		}
+		if keep := h.retention(resolution); keep == 0 || keep >= r.span {
+			break
+		}
+		resolution = tier.resolution
+	}
+	return r.span, resolution, nil
+}
+
+// Query 查询服务器在时间范围内的历史数据
+func (h *HistoryStore) Query(serverID uint, metric, timeRange string, now time.Time) (*History, error) {
+	metrics, ok := historyMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指标: %s", metric)
+	}
+	span, resolution, err := h.historyResolution(timeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []model.MetricPoint
+	err = h.db.Where("server_id = ? AND metric IN ? AND resolution = ? AND time >= ?",
+		serverID, metrics, resolution, now.Add(-span)).
+		Order("time").Find(&points).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询监控历史数据失败: %w", err)
+	}
+
+	history := &History{
+		ServerID:   serverID,
+		Metric:     metric,
+		TimeRange:  timeRange,
+		Resolution: resolution,
+		Series:     make([]MetricSeries, len(metrics)),
+	}
+	index := make(map[string]int, len(metrics))
+	for i, m := range metrics {
+		history.Series[i] = MetricSeries{Metric: m, Data: []model.MetricPoint{}}
+		index[m] = i
+	}
+	for _, p := range points {
+		series := &history.Series[index[p.Metric]]
+		series.Data = append(series.Data, p)
+	}
+	return history, nil
+}
+
+// rollup 将数据点按服务器、指标和时间段汇总，平均值按各点的样本数加权
+func rollup(points []model.MetricPoint, resolution int) []model.MetricPoint {
+	type key struct {
+		serverID uint
+		metric   string
+		bucket   int64
+	}
+	type sum struct {
+		point model.MetricPoint
+		total float64
+	}
+
+	size := int64(resolution)
+	sums := make(map[key]*sum)
+	for _, p := range points {
+		bucket := p.Time.Unix() / size * size
+		k := key{p.ServerID, p.Metric, bucket}
+		s, ok := sums[k]
+		if !ok {
+			s = &sum{point: model.MetricPoint{
+				ServerID:   p.ServerID,
+				Metric:     p.Metric,
+				Resolution: resolution,
+				Time:       time.Unix(bucket, 0),
+				Min:        p.Min,
+				Max:        p.Max,
+			}}
+			sums[k] = s
+		}
+		s.point.Min = min(s.point.Min, p.Min)
+		s.point.Max = max(s.point.Max, p.Max)
+		s.point.Count += p.Count
+		s.total += p.Avg * float64(p.Count)
+	}
+
+	result := make([]model.MetricPoint, 0, len(sums))
+	for _, s := range sums {
+		if s.point.Count > 0 {
+			s.point.Avg = s.total / float64(s.point.Count)
+		}
+		result = append(result, s.point)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.ServerID != b.ServerID {
+			return a.ServerID < b.ServerID
+		}
+		return a.Metric < b.Metric
+	})
+	return result
+}
+
+// Compact 汇总已结束的时间段并清理超过保留时长的数据，由调度主节点定期调用
+func (h *HistoryStore) Compact(ctx context.Context, now time.Time) error {
+	for _, tier := range rollupTiers {
+		if err := h.rollupTier(ctx, tier.resolution, tier.source, now); err != nil {
+			return err
+		}
+	}
+	if err := h.rollupMarked(ctx, now); err != nil {
+		return err
+	}
+
+	for _, resolution := range []int{
+		model.MetricResolutionRaw, model.MetricResolutionMinute, model.MetricResolution5m, model.MetricResolutionHour,
+	} {
+		keep := h.retention(resolution)
+		if keep == 0 {
+			continue
+		}
+		if err := h.purge(ctx, resolution, now.Add(-keep)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupTier 将源精度的数据汇总到目标精度
+// 从目标精度最新的时间段往前回溯 rollupLateness 开始重新汇总，只汇总已经结束的时间段；
+// 更早的晚到样本（如代理在后端不可用期间缓存的样本）由 rollupMarked 按服务器重新汇总
+func (h *HistoryStore) rollupTier(ctx context.Context, resolution, source int, now time.Time) error {
+	size := time.Duration(resolution) * time.Second
+	end := now.Truncate(size)
+
+	var last sql.NullTime
+	err := h.db.WithContext(ctx).Model(&model.MetricPoint{}).
+		Where("resolution = ?", resolution).Select("MAX(time)").Row().Scan(&last)
+	if err != nil {
+		return fmt.Errorf("查询汇总进度失败: %w", err)
+	}
+
+	var start time.Time
+	if last.Valid {
+		start = last.Time.Add(-max(rollupLateness, size))
+	} else {
+		var first sql.NullTime
+		err := h.db.WithContext(ctx).Model(&model.MetricPoint{}).
+			Where("resolution = ?", source).Select("MIN(time)").Row().Scan(&first)
+		if err != nil {
+			return fmt.Errorf("查询汇总进度失败: %w", err)
+		}
+		if !first.Valid {
+			return nil
+		}
+		start = first.Time
+	}
+	return h.rollupRange(ctx, resolution, source, 0, start.Truncate(size), end)
+}
+
+// rollupMarked 重新汇总有晚到样本的服务器，从最早的晚到样本所在的时间段开始逐级汇总
+// 先删除标记再汇总，汇总期间新的晚到样本会重新标记；汇总失败时恢复标记
+func (h *HistoryStore) rollupMarked(ctx context.Context, now time.Time) error {
+	var marks []model.MetricRollupMark
+	if err := h.db.WithContext(ctx).Find(&marks).Error; err != nil {
+		return fmt.Errorf("查询监控数据重新汇总标记失败: %w", err)
+	}
+
+	for _, mark := range marks {
+		result := h.db.WithContext(ctx).Where("server_id = ? AND since = ?", mark.ServerID, mark.Since).
+			Delete(&model.MetricRollupMark{})
+		if result.Error != nil {
+			return fmt.Errorf("删除监控数据重新汇总标记失败: %w", result.Error)
+		}
+		// 标记已被其他汇总处理，或有更早的样本，留到下次处理
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		for _, tier := range rollupTiers {
+			size := time.Duration(tier.resolution) * time.Second
+			err := h.rollupRange(ctx, tier.resolution, tier.source, mark.ServerID, mark.Since.Truncate(size), now.Truncate(size))
+			if err != nil {
+				h.markRollup(context.Background(), mark.ServerID, mark.Since)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollupRange 将 [start, end) 内源精度的数据汇总到目标精度，serverID 为0时汇总所有服务器
+func (h *HistoryStore) rollupRange(ctx context.Context, resolution, source int, serverID uint, start, end time.Time) error {
+	for from := start; from.Before(end); from = from.Add(compactWindow) {
+		to := from.Add(compactWindow)
+		if to.After(end) {
+			to = end
+		}
+
+		var points []model.MetricPoint
+		query := h.db.WithContext(ctx).Where("resolution = ? AND time >= ? AND time < ?", source, from, to)
+		if serverID > 0 {
+			query = query.Where("server_id = ?", serverID)
+		}
+		err := query.Find(&points).Error
+		if err != nil {
+			return fmt.Errorf("读取监控历史数据失败: %w", err)
+		}
+
+		rolled := rollup(points, resolution)
+		if len(rolled) == 0 {
+			continue
+		}
+		err = h.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "server_id"}, {Name: "metric"}, {Name: "resolution"}, {Name: "time"}},
+			DoUpdates: clause.AssignmentColumns([]string{"avg", "min", "max", "count"}),
+		}).CreateInBatches(rolled, compactBatch).Error
+		if err != nil {
+			return fmt.Errorf("保存监控汇总数据失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// purge 分批删除指定精度早于 before 的数据
+func (h *HistoryStore) purge(ctx context.Context, resolution int, before time.Time) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ids []uint64
+		err := h.db.WithContext(ctx).Model(&model.MetricPoint{}).
+			Where("resolution = ? AND time < ?", resolution, before).
+			Limit(compactBatch).Pluck("id", &ids).Error
+		if err != nil {
+			return fmt.Errorf("清理监控历史数据失败: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := h.db.WithContext(ctx).Where("id IN ?", ids).Delete(&model.MetricPoint{}).Error; err != nil {
+			return fmt.Errorf("清理监控历史数据失败: %w", err)
+		}
+		if len(ids) < compactBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricValues(t *testing.T) {
+	now := time.Now()
+	prev := &SystemMetrics{
+		Timestamp: now.Add(-10 * time.Second),
+		Network: NetworkMetrics{Interfaces: []NetworkInterface{
+			{Name: "eth0", BytesRecv: 1000, BytesSent: 500},
+			{Name: "eth1", BytesRecv: 0, BytesSent: 0},
+		}},
+	}
+	cur := &SystemMetrics{
+		Timestamp: now,
+		CPU:       CPUMetrics{Usage: 42},
+		Memory:    MemoryMetrics{Usage: 60},
+		Load:      LoadMetrics{Load1: 1.5},
+		Disk: DiskMetrics{Partitions: []PartitionMetrics{
+			{Mountpoint: "/", Usage: 30},
+			{Mountpoint: "/data", Usage: 85},
+		}},
+		Network: NetworkMetrics{Interfaces: []NetworkInterface{
+			{Name: "eth0", BytesRecv: 6000, BytesSent: 1500},
+			{Name: "eth1", BytesRecv: 1000, BytesSent: 0},
+		}},
+	}
+
+	values := metricValues(cur, prev)
+	assert.Equal(t, 42.0, values[model.MetricCPU])
+	assert.Equal(t, 60.0, values[model.MetricMemory])
+	assert.Equal(t, 85.0, values[model.MetricDisk])
+	assert.Equal(t, 1.5, values[model.MetricLoad])
+	assert.Equal(t, 600.0, values[model.MetricNetRecv])
+	assert.Equal(t, 100.0, values[model.MetricNetSent])
+
+	// 没有上一份数据或计数器回绕时不记录速率
+	values = metricValues(cur, nil)
+	assert.NotContains(t, values, model.MetricNetRecv)
+	values = metricValues(prev, cur)
+	assert.NotContains(t, values, model.MetricNetRecv)
+}
+
+func TestRollup(t *testing.T) {
+	base := time.Unix(1700000040, 0) // 整分钟
+	raw := func(offset time.Duration, value float64) model.MetricPoint {
+		return model.MetricPoint{ServerID: 1, Metric: model.MetricCPU, Time: base.Add(offset), Avg: value, Min: value, Max: value, Count: 1}
+	}
+
+	minutes := rollup([]model.MetricPoint{
+		raw(0, 10), raw(30*time.Second, 30),
+		raw(60*time.Second, 50),
+	}, model.MetricResolutionMinute)
+	require.Len(t, minutes, 2)
+	assert.Equal(t, base.Unix(), minutes[0].Time.Unix())
+	assert.Equal(t, model.MetricResolutionMinute, minutes[0].Resolution)
+	assert.Equal(t, 20.0, minutes[0].Avg)
+	assert.Equal(t, 10.0, minutes[0].Min)
+	assert.Equal(t, 30.0, minutes[0].Max)
+	assert.Equal(t, 2, minutes[0].Count)
+	assert.Equal(t, 50.0, minutes[1].Avg)
+
+	// 逐级汇总时平均值按样本数加权
+	hour := rollup(minutes, model.MetricResolutionHour)
+	require.Len(t, hour, 1)
+	assert.InDelta(t, 30.0, hour[0].Avg, 0.001)
+	assert.Equal(t, 10.0, hour[0].Min)
+	assert.Equal(t, 50.0, hour[0].Max)
+	assert.Equal(t, 3, hour[0].Count)
+}
+
+func TestHistoryResolution(t *testing.T) {
+	h := NewHistoryStore(nil, config.MetricsHistory{Raw: 6, Minute: 48, FiveMinute: 336, Hour: 2160})
+
+	_, resolution, err := h.historyResolution("1h")
+	require.NoError(t, err)
+	assert.Equal(t, model.MetricResolutionRaw, resolution)
+	_, resolution, _ = h.historyResolution("6h")
+	assert.Equal(t, model.MetricResolutionMinute, resolution)
+	_, resolution, _ = h.historyResolution("24h")
+	assert.Equal(t, model.MetricResolution5m, resolution)
+	span, resolution, _ := h.historyResolution("7d")
+	assert.Equal(t, 7*24*time.Hour, span)
+	assert.Equal(t, model.MetricResolutionHour, resolution)
+
+	// 默认精度保留时长不够时改用更粗的精度
+	short := NewHistoryStore(nil, config.MetricsHistory{Raw: 0, Minute: 2, FiveMinute: 12})
+	_, resolution, _ = short.historyResolution("6h")
+	assert.Equal(t, model.MetricResolution5m, resolution)
+	_, resolution, _ = short.historyResolution("24h")
+	assert.Equal(t, model.MetricResolutionHour, resolution)
+
+	_, _, err = h.historyResolution("30d")
+	assert.Error(t, err)
+}
+
+func TestLateSample(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.False(t, lateSample(now.Add(-time.Minute), now))
+	assert.False(t, lateSample(now.Add(-rollupLateness/2), now))
+	// 代理在后端不可用期间缓存的样本
+	assert.True(t, lateSample(now.Add(-rollupLateness), now))
+	assert.True(t, lateSample(now.Add(-3*time.Hour), now))
+}
@@ -103,24 +103,19 @@ func TestMonitorTypes(t *testing.T) {
 		assert.Equal(t, uint64(8589934592), metrics.Memory.Total)
 		assert.Equal(t, 50.0, metrics.Memory.Usage)
 	})
+}
 
-	t.Run("Alert", func(t *testing.T) {
-		alert := &Alert{
-			ID:           1,
-			ServerID:     1,
-			MetricType:   "cpu",
-			CurrentValue: 85.5,
-			Threshold:    80.0,
-			Status:       "firing",
-			Message:      "CPU使用率过高",
-			FiredAt:      time.Now(),
-		}
+func TestDiffServers(t *testing.T) {
+	added, removed := diffServers([]uint{1, 2, 3}, []uint{2, 3, 4, 4})
+	assert.Equal(t, []uint{4}, added)
+	assert.Equal(t, []uint{1}, removed)
 
-		assert.Equal(t, uint(1), alert.ID)
-		assert.Equal(t, "cpu", alert.MetricType)
-		assert.Equal(t, 85.5, alert.CurrentValue)
-		assert.Equal(t, 80.0, alert.Threshold)
-		assert.Equal(t, "firing", alert.Status)
-		assert.Contains(t, alert.Message, "CPU")
-	})
+	// 非主节点释放全部收集器
+	added, removed = diffServers([]uint{1}, nil)
+	assert.Empty(t, added)
+	assert.Equal(t, []uint{1}, removed)
+
+	added, removed = diffServers(nil, []uint{5})
+	assert.Equal(t, []uint{5}, added)
+	assert.Empty(t, removed)
 }
@@ -0,0 +1,381 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"devops/pkg/ssh"
+)
+
+// remoteSectionPrefix 远程采集脚本输出中各段的标记前缀
+const remoteSectionPrefix = "@@"
+
+// remoteScript 远程采集脚本，只依赖 /proc 和 df，一次会话输出所有数据
+// CPU使用率由间隔1秒的两次 /proc/stat 采样计算
+var remoteScript = strings.Join([]string{
+	"echo @@stat1; head -n 1 /proc/stat",
+	"sleep 1",
+	"echo @@stat2; head -n 1 /proc/stat",
+	"echo @@cores; grep -c ^processor /proc/cpuinfo",
+	"echo @@meminfo; cat /proc/meminfo",
+	"echo @@df; df -P -T -B1 2>/dev/null",
+	"echo @@dfi; df -P -i 2>/dev/null",
+	"echo @@diskstats; cat /proc/diskstats",
+	"echo @@netdev; cat /proc/net/dev",
+	"echo @@loadavg; cat /proc/loadavg",
+	"echo @@uptime; cat /proc/uptime",
+	"echo @@procs; ls -d /proc/[0-9]* | wc -l",
+}, "\n")
+
+// RemoteSource 通过 SSH 采集远程服务器的监控数据
+// 连接在多次采集间复用，采集失败时断开，下次采集重新连接
+type RemoteSource struct {
+	config ssh.Config
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewRemoteSource 创建远程监控数据来源
+func NewRemoteSource(config ssh.Config) *RemoteSource {
+	return &RemoteSource{config: config}
+}
+
+// Collect 收集远程服务器系统监控指标
+func (r *RemoteSource) Collect(ctx context.Context) (*SystemMetrics, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil {
+		client, err := ssh.Dial(ctx, r.config)
+		if err != nil {
+			return nil, fmt.Errorf("收集监控数据失败: %w", err)
+		}
+		r.client = client
+	}
+
+	output, err := r.client.Output(ctx, remoteScript)
+	if err != nil {
+		r.client.Close()
+		r.client = nil
+		return nil, fmt.Errorf("收集监控数据失败: %w", err)
+	}
+
+	return parseRemoteMetrics(output)
+}
+
+// Close 断开SSH连接
+func (r *RemoteSource) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Close()
+	r.client = nil
+	return err
+}
+
+// parseRemoteMetrics 解析远程采集脚本的输出
+func parseRemoteMetrics(output string) (*SystemMetrics, error) {
+	sections := splitSections(output)
+	metrics := &SystemMetrics{}
+
+	cpu, err := parseCPUStat(sections["stat1"], sections["stat2"])
+	if err != nil {
+		return nil, err
+	}
+	metrics.CPU = cpu
+	if len(sections["cores"]) > 0 {
+		metrics.CPU.Cores, _ = strconv.Atoi(strings.TrimSpace(sections["cores"][0]))
+	}
+
+	if metrics.Memory, err = parseMeminfo(sections["meminfo"]); err != nil {
+		return nil, err
+	}
+	metrics.Disk = DiskMetrics{
+		Partitions: parseDF(sections["df"], sections["dfi"]),
+		IOStats:    parseDiskstats(sections["diskstats"]),
+	}
+	metrics.Network = NetworkMetrics{Interfaces: parseNetDev(sections["netdev"])}
+	if metrics.Load, err = parseLoadavg(sections["loadavg"]); err != nil {
+		return nil, err
+	}
+	if len(sections["uptime"]) > 0 {
+		if fields := strings.Fields(sections["uptime"][0]); len(fields) > 0 {
+			uptime, _ := strconv.ParseFloat(fields[0], 64)
+			metrics.Uptime = int64(uptime)
+		}
+	}
+	if len(sections["procs"]) > 0 {
+		metrics.Processes, _ = strconv.Atoi(strings.TrimSpace(sections["procs"][0]))
+	}
+
+	return metrics, nil
+}
+
+// splitSections 按 @@name 标记将输出拆分为各段的行
+func splitSections(output string) map[string][]string {
+	sections := make(map[string][]string)
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, remoteSectionPrefix) {
+			current = strings.TrimPrefix(line, remoteSectionPrefix)
+			sections[current] = nil
+			continue
+		}
+		if current != "" && strings.TrimSpace(line) != "" {
+			sections[current] = append(sections[current], line)
+		}
+	}
+	return sections
+}
+
+// cpuTimes /proc/stat 中 cpu 行的各项累计时间
+type cpuTimes struct {
+	user, nice, system, idle, iowait, irq, softirq, steal float64
+}
+
+func (t cpuTimes) total() float64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// parseCPULine 解析 /proc/stat 的 cpu 汇总行
+func parseCPULine(lines []string) (cpuTimes, error) {
+	if len(lines) == 0 {
+		return cpuTimes{}, errors.New("缺少 /proc/stat 数据")
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) < 8 || fields[0] != "cpu" {
+		return cpuTimes{}, fmt.Errorf("无法解析 /proc/stat: %s", lines[0])
+	}
+	values := make([]float64, 8)
+	for i := range values {
+		if i+1 < len(fields) {
+			values[i], _ = strconv.ParseFloat(fields[i+1], 64)
+		}
+	}
+	return cpuTimes{
+		user: values[0], nice: values[1], system: values[2], idle: values[3],
+		iowait: values[4], irq: values[5], softirq: values[6], steal: values[7],
+	}, nil
+}
+
+// parseCPUStat 根据两次 /proc/stat 采样计算CPU使用率
+func parseCPUStat(first, second []string) (CPUMetrics, error) {
+	before, err := parseCPULine(first)
+	if err != nil {
+		return CPUMetrics{}, err
+	}
+	after, err := parseCPULine(second)
+	if err != nil {
+		return CPUMetrics{}, err
+	}
+
+	total := after.total() - before.total()
+	if total <= 0 {
+		return CPUMetrics{}, nil
+	}
+	percent := func(v float64) float64 { return v / total * 100 }
+	idle := after.idle - before.idle
+	iowait := after.iowait - before.iowait
+
+	return CPUMetrics{
+		Usage:      percent(total - idle - iowait),
+		UserMode:   percent(after.user - before.user + after.nice - before.nice),
+		SystemMode: percent(after.system - before.system),
+		Idle:       percent(idle),
+		IOWait:     percent(iowait),
+	}, nil
+}
+
+// parseMeminfo 解析 /proc/meminfo，计算方式与 gopsutil 一致
+func parseMeminfo(lines []string) (MemoryMetrics, error) {
+	values := make(map[string]uint64)
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		// 单位为 kB
+		values[key] = n * 1024
+	}
+
+	total := values["MemTotal"]
+	if total == 0 {
+		return MemoryMetrics{}, errors.New("缺少 /proc/meminfo 数据")
+	}
+
+	m := MemoryMetrics{
+		Total:     total,
+		Free:      values["MemFree"],
+		Available: values["MemAvailable"],
+		Buffers:   values["Buffers"],
+		Cached:    values["Cached"] + values["SReclaimable"],
+		SwapTotal: values["SwapTotal"],
+		SwapFree:  values["SwapFree"],
+	}
+	if m.Available == 0 {
+		// 旧内核没有 MemAvailable
+		m.Available = m.Free + m.Buffers + m.Cached
+	}
+	if used := m.Free + m.Buffers + m.Cached; used < total {
+		m.Used = total - used
+	}
+	m.Usage = float64(m.Used) / float64(total) * 100
+	if m.SwapFree < m.SwapTotal {
+		m.SwapUsed = m.SwapTotal - m.SwapFree
+	}
+	return m, nil
+}
+
+// parseDF 解析 df -P -T -B1 和 df -P -i 的输出，只保留 /dev 下的块设备分区
+func parseDF(lines, inodeLines []string) []PartitionMetrics {
+	type inodes struct{ total, used, free uint64 }
+	inodeByMount := make(map[string]inodes)
+	for _, line := range skipHeader(inodeLines) {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		var n inodes
+		n.total, _ = strconv.ParseUint(fields[1], 10, 64)
+		n.used, _ = strconv.ParseUint(fields[2], 10, 64)
+		n.free, _ = strconv.ParseUint(fields[3], 10, 64)
+		inodeByMount[strings.Join(fields[5:], " ")] = n
+	}
+
+	var partitions []PartitionMetrics
+	for _, line := range skipHeader(lines) {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		total, _ := strconv.ParseUint(fields[2], 10, 64)
+		used, _ := strconv.ParseUint(fields[3], 10, 64)
+		available, _ := strconv.ParseUint(fields[4], 10, 64)
+		mountpoint := strings.Join(fields[6:], " ")
+
+		var usage float64
+		if used+available > 0 {
+			usage = float64(used) / float64(used+available) * 100
+		}
+		n := inodeByMount[mountpoint]
+		partitions = append(partitions, PartitionMetrics{
+			Device:     fields[0],
+			Mountpoint: mountpoint,
+			Filesystem: fields[1],
+			Total:      total,
+			Used:       used,
+			Available:  available,
+			Usage:      usage,
+			Inodes:     n.total,
+			InodesUsed: n.used,
+			InodesFree: n.free,
+		})
+	}
+	return partitions
+}
+
+// parseDiskstats 解析 /proc/diskstats，汇总所有设备的IO统计
+func parseDiskstats(lines []string) DiskIOStats {
+	const sectorSize = 512
+
+	var stats DiskIOStats
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 13 {
+			continue
+		}
+		name := fields[2]
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+			continue
+		}
+		values := make([]uint64, 14)
+		for i := 3; i < len(fields) && i < len(values); i++ {
+			values[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		stats.ReadOps += values[3]
+		stats.ReadBytes += values[5] * sectorSize
+		stats.ReadTime += values[6]
+		stats.WriteOps += values[7]
+		stats.WriteBytes += values[9] * sectorSize
+		stats.WriteTime += values[10]
+		stats.IOTime += values[12]
+	}
+	return stats
+}
+
+// parseNetDev 解析 /proc/net/dev，跳过回环接口
+func parseNetDev(lines []string) []NetworkInterface {
+	var interfaces []NetworkInterface
+	for _, line := range lines {
+		name, data, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "lo") {
+			continue
+		}
+		fields := strings.Fields(data)
+		if len(fields) < 12 {
+			continue
+		}
+		values := make([]uint64, 12)
+		for i := range values {
+			values[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		interfaces = append(interfaces, NetworkInterface{
+			Name:        name,
+			BytesRecv:   values[0],
+			PacketsRecv: values[1],
+			ErrorsRecv:  values[2],
+			DroppedRecv: values[3],
+			BytesSent:   values[8],
+			PacketsSent: values[9],
+			ErrorsSent:  values[10],
+			DroppedSent: values[11],
+		})
+	}
+	return interfaces
+}
+
+// parseLoadavg 解析 /proc/loadavg
+func parseLoadavg(lines []string) (LoadMetrics, error) {
+	if len(lines) == 0 {
+		return LoadMetrics{}, errors.New("缺少 /proc/loadavg 数据")
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) < 3 {
+		return LoadMetrics{}, fmt.Errorf("无法解析 /proc/loadavg: %s", lines[0])
+	}
+	var load LoadMetrics
+	load.Load1, _ = strconv.ParseFloat(fields[0], 64)
+	load.Load5, _ = strconv.ParseFloat(fields[1], 64)
+	load.Load15, _ = strconv.ParseFloat(fields[2], 64)
+	return load, nil
+}
+
+// skipHeader 去掉 df 输出的表头
+func skipHeader(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	return lines[1:]
+}
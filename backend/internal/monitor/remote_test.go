@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteOutput = `@@stat1
+cpu  1000 0 500 8000 500 0 0 0 0 0
+@@stat2
+cpu  1300 0 600 8500 600 0 0 0 0 0
+@@cores
+4
+@@meminfo
+MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    5000000 kB
+Buffers:          500000 kB
+Cached:          2000000 kB
+SwapTotal:       2000000 kB
+SwapFree:        1500000 kB
+SReclaimable:     500000 kB
+@@df
+Filesystem     Type     1-blocks        Used   Available Capacity Mounted on
+udev           devtmpfs 4096000000 0 4096000000 0% /dev
+/dev/sda1      ext4     100000000000 60000000000 40000000000 60% /
+/dev/sdb1      xfs      200000000000 50000000000 150000000000 25% /data dir
+@@dfi
+Filesystem      Inodes  IUsed   IFree IUse% Mounted on
+/dev/sda1      6000000 300000 5700000    5% /
+/dev/sdb1      1000000 100000  900000   10% /data dir
+@@diskstats
+   8       0 sda 100 0 2000 30 200 0 4000 60 0 90 90 0 0 0 0
+   7       0 loop0 999 0 999 999 999 0 999 999 0 999 999 0 0 0 0
+@@netdev
+Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  5000      50    0    0    0     0          0         0     5000      50    0    0    0     0       0          0
+  eth0: 123456    1000    1    2    0     0          0         0   654321     900    3    4    0     0       0          0
+@@loadavg
+0.50 0.40 0.30 2/300 12345
+@@uptime
+86400.55 300000.00
+@@procs
+212
+`
+
+func TestParseRemoteMetrics(t *testing.T) {
+	metrics, err := parseRemoteMetrics(remoteOutput)
+	require.NoError(t, err)
+
+	// CPU 两次采样的差值：user 300, system 100, idle 500, iowait 100
+	assert.InDelta(t, 40.0, metrics.CPU.Usage, 0.01)
+	assert.InDelta(t, 30.0, metrics.CPU.UserMode, 0.01)
+	assert.InDelta(t, 10.0, metrics.CPU.SystemMode, 0.01)
+	assert.InDelta(t, 50.0, metrics.CPU.Idle, 0.01)
+	assert.InDelta(t, 10.0, metrics.CPU.IOWait, 0.01)
+	assert.Equal(t, 4, metrics.CPU.Cores)
+
+	assert.Equal(t, uint64(8000000*1024), metrics.Memory.Total)
+	assert.Equal(t, uint64(4000000*1024), metrics.Memory.Used)
+	assert.Equal(t, uint64(5000000*1024), metrics.Memory.Available)
+	assert.InDelta(t, 50.0, metrics.Memory.Usage, 0.01)
+	assert.Equal(t, uint64(500000*1024), metrics.Memory.SwapUsed)
+
+	require.Len(t, metrics.Disk.Partitions, 2)
+	root := metrics.Disk.Partitions[0]
+	assert.Equal(t, "/dev/sda1", root.Device)
+	assert.Equal(t, "/", root.Mountpoint)
+	assert.Equal(t, "ext4", root.Filesystem)
+	assert.InDelta(t, 60.0, root.Usage, 0.01)
+	assert.Equal(t, uint64(300000), root.InodesUsed)
+	assert.Equal(t, "/data dir", metrics.Disk.Partitions[1].Mountpoint)
+	assert.Equal(t, uint64(100000), metrics.Disk.Partitions[1].InodesUsed)
+
+	// loop 设备不计入
+	assert.Equal(t, uint64(100), metrics.Disk.IOStats.ReadOps)
+	assert.Equal(t, uint64(2000*512), metrics.Disk.IOStats.ReadBytes)
+	assert.Equal(t, uint64(4000*512), metrics.Disk.IOStats.WriteBytes)
+	assert.Equal(t, uint64(90), metrics.Disk.IOStats.IOTime)
+
+	require.Len(t, metrics.Network.Interfaces, 1)
+	eth0 := metrics.Network.Interfaces[0]
+	assert.Equal(t, "eth0", eth0.Name)
+	assert.Equal(t, uint64(123456), eth0.BytesRecv)
+	assert.Equal(t, uint64(654321), eth0.BytesSent)
+	assert.Equal(t, uint64(4), eth0.DroppedSent)
+
+	assert.Equal(t, 0.5, metrics.Load.Load1)
+	assert.Equal(t, 0.3, metrics.Load.Load15)
+	assert.Equal(t, int64(86400), metrics.Uptime)
+	assert.Equal(t, 212, metrics.Processes)
+}
+
+func TestParseRemoteMetricsMissingData(t *testing.T) {
+	_, err := parseRemoteMetrics("@@stat1\n@@stat2\n")
+	assert.Error(t, err)
+
+	_, err = parseRemoteMetrics("@@stat1\ncpu 1 0 1 1 0 0 0 0\n@@stat2\ncpu 2 0 2 2 0 0 0 0\n")
+	assert.Error(t, err)
+}
@@ -2,10 +2,15 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/internal/notify"
+	"devops/internal/service"
 	"devops/pkg/cache"
 
 	"github.com/redis/go-redis/v9"
@@ -18,6 +23,10 @@ type Service struct {
 	rdb        *redis.Client
 	cache      *cache.CacheService
 	keys       *cache.CacheKeys
+	config     config.Monitor
+	history    *HistoryStore
+	notifier   notify.Notifier
+	ruleCache  alertRuleCache
 	collectors map[uint]*Collector
 	mu         sync.RWMutex
 	stopChan   chan struct{}
@@ -25,38 +34,34 @@ type Service struct {
 }
 
 // NewService 创建监控服务
-func NewService(db *gorm.DB, rdb *redis.Client) *Service {
+func NewService(db *gorm.DB, rdb *redis.Client, cfg config.Monitor) *Service {
 	cacheService := cache.NewCacheService(rdb, "devops")
 	return &Service{
 		db:         db,
 		rdb:        rdb,
 		cache:      cacheService,
 		keys:       cache.NewCacheKeys(),
+		config:     cfg,
+		history:    NewHistoryStore(db, cfg.History),
+		notifier:   notify.NewService(db, rdb),
 		collectors: make(map[uint]*Collector),
 		stopChan:   make(chan struct{}),
 	}
 }
 
-// StartMonitoring 启动监控
-func (s *Service) StartMonitoring(ctx context.Context, interval time.Duration) error {
-	// 获取所有需要监控的服务器
-	servers, err := s.getActiveServers()
-	if err != nil {
-		return fmt.Errorf("获取服务器列表失败: %w", err)
-	}
+// DefaultInterval 未配置采集间隔时通过SSH采集监控数据的间隔
+const DefaultInterval = 30 * time.Second
 
-	// 为每台服务器创建收集器
-	s.mu.Lock()
-	for _, serverID := range servers {
-		if _, exists := s.collectors[serverID]; !exists {
-			s.collectors[serverID] = NewCollector(serverID)
-		}
+// StartMonitoring 启动监控，定期通过SSH采集未安装代理的服务器的数据
+// 多个后端实例同时运行时只有 leader 返回 true 的实例采集，其他实例释放已建立的SSH连接
+func (s *Service) StartMonitoring(ctx context.Context, interval time.Duration, leader func() bool) error {
+	if interval <= 0 {
+		return fmt.Errorf("无效的监控采集间隔: %s", interval)
 	}
-	s.mu.Unlock()
 
 	// 启动定时收集任务
 	s.wg.Add(1)
-	go s.collectMetricsLoop(ctx, interval)
+	go s.collectMetricsLoop(ctx, interval, leader)
 
 	return nil
 }
@@ -65,10 +70,17 @@ func (s *Service) StartMonitoring(ctx context.Context, interval time.Duration) e
 func (s *Service) StopMonitoring() {
 	close(s.stopChan)
 	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, collector := range s.collectors {
+		collector.Close()
+		delete(s.collectors, id)
+	}
 }
 
 // collectMetricsLoop 监控数据收集循环
-func (s *Service) collectMetricsLoop(ctx context.Context, interval time.Duration) {
+func (s *Service) collectMetricsLoop(ctx context.Context, interval time.Duration, leader func() bool) {
 	defer s.wg.Done()
 
 	ticker := time.NewTicker(interval)
@@ -81,11 +93,66 @@ func (s *Service) collectMetricsLoop(ctx context.Context, interval time.Duration
 		case <-s.stopChan:
 			return
 		case <-ticker.C:
+			if leader != nil && !leader() {
+				s.syncCollectors(nil)
+				continue
+			}
+			servers, err := s.getActiveServers()
+			if err != nil {
+				fmt.Printf("获取服务器列表失败: %v\n", err)
+				continue
+			}
+			s.syncCollectors(servers)
 			s.collectAllMetrics(ctx)
+			if err := s.ResolveStaleAlerts(ctx, time.Now()); err != nil {
+				fmt.Printf("恢复过期告警失败: %v\n", err)
+			}
 		}
 	}
 }
 
+// syncCollectors 按需要SSH采集的服务器列表增删收集器，其他实例上添加或移除的服务器在下一轮采集时生效
+func (s *Service) syncCollectors(servers []uint) {
+	s.mu.RLock()
+	current := make([]uint, 0, len(s.collectors))
+	for id := range s.collectors {
+		current = append(current, id)
+	}
+	s.mu.RUnlock()
+
+	added, removed := diffServers(current, servers)
+	for _, id := range removed {
+		s.removeCollector(id)
+	}
+	for _, id := range added {
+		if err := s.AddServer(id); err != nil {
+			fmt.Printf("添加服务器%d 到监控失败: %v\n", id, err)
+		}
+	}
+}
+
+// diffServers 比较当前采集的服务器和目标列表，返回需要添加和移除的服务器
+func diffServers(current, target []uint) (added, removed []uint) {
+	want := make(map[uint]bool, len(target))
+	for _, id := range target {
+		want[id] = true
+	}
+	have := make(map[uint]bool, len(current))
+	for _, id := range current {
+		have[id] = true
+		if !want[id] {
+			removed = append(removed, id)
+		}
+	}
+	for _, id := range target {
+		if !have[id] {
+			added = append(added, id)
+			have[id] = true
+		}
+	}
+	return added, removed
+}
+
 // collectAllMetrics 收集所有服务器的监控数据
 func (s *Service) collectAllMetrics(ctx context.Context) {
 	s.mu.RLock()
@@ -117,17 +184,38 @@ func (s *Service) collectServerMetrics(ctx context.Context, serverID uint, colle
 		return
 	}
 
-	// 存储到缓存
+	s.processMetrics(ctx, serverID, metrics)
+}
+
+// processMetrics 处理一份监控数据，SSH采集和代理上报的数据都经过这里
+func (s *Service) processMetrics(ctx context.Context, serverID uint, metrics *SystemMetrics) {
+	// 缓存中的上一份数据用于计算网络速率
 	metricsKey := s.keys.ServerMetrics(serverID)
-	if err := s.cache.Set(ctx, metricsKey, metrics, cache.TTLServerMetrics); err != nil {
-		fmt.Printf("存储服务器%d 监控数据到缓存失败: %v\n", serverID, err)
+	var prev *SystemMetrics
+	var cached SystemMetrics
+	if err := s.cache.Get(ctx, metricsKey, &cached); err == nil {
+		prev = &cached
 	}
 
-	// 检查告警规则
-	s.checkAlerts(ctx, serverID, metrics)
+	// 存储到缓存，代理补报的较早样本不覆盖最新数据，也不参与告警评估
+	if prev == nil || metrics.Timestamp.After(prev.Timestamp) {
+		if err := s.cache.Set(ctx, metricsKey, metrics, cache.TTLServerMetrics); err != nil {
+			fmt.Printf("存储服务器%d 监控数据到缓存失败: %v\n", serverID, err)
+		}
+		s.checkAlerts(ctx, serverID, metrics, prev)
+	} else {
+		prev = nil
+	}
 
-	// 可选：持久化到数据库（用于历史数据分析）
-	// s.persistMetrics(ctx, metrics)
+	// 持久化到数据库（用于历史数据分析）
+	if err := s.history.Record(ctx, metrics, prev); err != nil {
+		fmt.Printf("存储服务器%d 监控历史数据失败: %v\n", serverID, err)
+	}
+}
+
+// GetServerHistory 获取服务器历史监控数据
+func (s *Service) GetServerHistory(serverID uint, metric, timeRange string) (*History, error) {
+	return s.history.Query(serverID, metric, timeRange, time.Now())
 }
 
 // GetServerMetrics 获取服务器监控数据
@@ -158,27 +246,61 @@ func (s *Service) GetServerStatus(ctx context.Context, serverID uint) (string, e
 	return "online", nil
 }
 
-// AddServer 添加服务器到监控
-func (s *Service) AddServer(serverID uint) {
+// AddServer 添加服务器到监控，通过SSH采集该服务器的数据
+func (s *Service) AddServer(serverID uint) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.collectors[serverID]; !exists {
-		s.collectors[serverID] = NewCollector(serverID)
+	if _, exists := s.collectors[serverID]; exists {
+		return nil
+	}
+
+	var server model.Server
+	if err := s.db.First(&server, serverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("服务器 %d 不存在", serverID)
+		}
+		return fmt.Errorf("获取服务器失败: %w", err)
 	}
+	var agents int64
+	if err := s.db.Model(&model.Agent{}).Where("server_id = ? AND status = ?", serverID, model.AgentStatusActive).
+		Count(&agents).Error; err != nil {
+		return fmt.Errorf("获取服务器代理失败: %w", err)
+	}
+	if agents > 0 {
+		return fmt.Errorf("服务器 %d 已由监控代理上报数据", serverID)
+	}
+
+	sshConfig := service.SSHConfig(&server)
+	sshConfig.Timeout = time.Duration(s.config.Timeout) * time.Second
+	s.collectors[serverID] = NewCollectorWithSource(serverID, NewRemoteSource(sshConfig))
+	return nil
 }
 
 // RemoveServer 从监控中移除服务器
 func (s *Service) RemoveServer(serverID uint) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	delete(s.collectors, serverID)
+	s.removeCollector(serverID)
 
 	// 清除缓存
 	ctx := context.Background()
 	metricsKey := s.keys.ServerMetrics(serverID)
 	s.cache.Delete(ctx, metricsKey)
+
+	// 不再评估的服务器的告警不会自行恢复
+	if err := s.resolveServerAlerts(ctx, serverID, time.Now()); err != nil {
+		fmt.Printf("恢复服务器%d 的告警失败: %v\n", serverID, err)
+	}
+}
+
+// removeCollector 停止通过SSH采集服务器数据
+func (s *Service) removeCollector(serverID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if collector, exists := s.collectors[serverID]; exists {
+		collector.Close()
+		delete(s.collectors, serverID)
+	}
 }
 
 // GetSystemStats 获取系统统计信息
@@ -221,56 +343,26 @@ func (s *Service) GetSystemStats(ctx context.Context) (map[string]interface{}, e
 	return stats, nil
 }
 
-// checkAlerts 检查告警规则
-func (s *Service) checkAlerts(ctx context.Context, serverID uint, metrics *SystemMetrics) {
-	// 这里实现告警规则检查逻辑
-	// 简化实现，实际项目中需要从数据库获取告警规则
-	// CPU使用率告警
-	if metrics.CPU.Usage > 80 {
-		s.triggerAlert(ctx, serverID, "cpu", metrics.CPU.Usage, 80, "CPU使用率过高")
-	}
-
-	// 内存使用率告警
-	if metrics.Memory.Usage > 85 {
-		s.triggerAlert(ctx, serverID, "memory", metrics.Memory.Usage, 85, "内存使用率过高")
-	}
-
-	// 磁盘使用率告警
-	for _, partition := range metrics.Disk.Partitions {
-		if partition.Usage > 90 {
-			s.triggerAlert(ctx, serverID, "disk", partition.Usage, 90,
-				fmt.Sprintf("磁盘 %s 使用率过高", partition.Mountpoint))
+// checkAlerts 检查告警规则，触发新的告警并恢复条件已解除的告警
+func (s *Service) checkAlerts(ctx context.Context, serverID uint, cur, prev *SystemMetrics) {
+	results, applied, err := s.evaluateAlerts(ctx, serverID, cur, prev)
+	if err != nil {
+		fmt.Printf("检查服务器%d 告警规则失败: %v\n", serverID, err)
+		if applied == nil {
+			return
 		}
 	}
-}
-
-// triggerAlert 触发告警
-func (s *Service) triggerAlert(ctx context.Context, serverID uint, metricType string, currentValue, threshold float64, message string) {
-	alert := Alert{
-		ServerID:     serverID,
-		MetricType:   metricType,
-		CurrentValue: currentValue,
-		Threshold:    threshold,
-		Status:       "firing",
-		Message:      message,
-		FiredAt:      time.Now(),
+	if err := s.updateAlerts(ctx, serverID, results, applied, cur.Timestamp); err != nil {
+		fmt.Printf("更新服务器%d 告警失败: %v\n", serverID, err)
 	}
-
-	// 存储告警到缓存
-	alertKey := fmt.Sprintf("alert:%d:%s", serverID, metricType)
-	s.cache.Set(ctx, alertKey, alert, 24*time.Hour)
-
-	// 这里可以实现告警通知逻辑
-	fmt.Printf("告警: 服务器%d %s\n", serverID, message)
 }
 
-// getActiveServers 获取需要监控的活跃服务器列表
+// getActiveServers 获取需要通过SSH采集的活跃服务器列表，已部署监控代理的服务器除外
 func (s *Service) getActiveServers() ([]uint, error) {
-	// 简化实现，实际应该从数据库查询
-	// var servers []uint
-	// err := s.db.Model(&model.Server{}).Where("status = ?", 1).Pluck("id", &servers).Error
-	// return servers, err
-
-	// 暂时返回示例数据
-	return []uint{1}, nil
+	var servers []uint
+	err := s.db.Model(&model.Server{}).
+		Where("status = ? AND id NOT IN (?)", 1,
+			s.db.Model(&model.Agent{}).Select("server_id").Where("status = ?", model.AgentStatusActive)).
+		Pluck("id", &servers).Error
+	return servers, err
 }
@@ -120,36 +120,3 @@ type ServiceStatus struct {
 	Uptime      int64     `json:"uptime"`
 	PID         int       `json:"pid"`
 }
-
-// AlertRule 告警规则
-type AlertRule struct {
-	ID            uint      `json:"id"`
-	Name          string    `json:"name"`
-	ServerID      uint      `json:"server_id"`
-	MetricType    string    `json:"metric_type"` // cpu, memory, disk, network
-	Condition     string    `json:"condition"`   // >, <, >=, <=, ==
-	Threshold     float64   `json:"threshold"`
-	Duration      int       `json:"duration"` // 持续时间（秒）
-	Enabled       bool      `json:"enabled"`
-	NotifyEmail   bool      `json:"notify_email"`
-	NotifyWebhook bool      `json:"notify_webhook"`
-	WebhookURL    string    `json:"webhook_url"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-}
-
-// Alert 告警信息
-type Alert struct {
-	ID           uint       `json:"id"`
-	RuleID       uint       `json:"rule_id"`
-	ServerID     uint       `json:"server_id"`
-	ServerName   string     `json:"server_name"`
-	MetricType   string     `json:"metric_type"`
-	CurrentValue float64    `json:"current_value"`
-	Threshold    float64    `json:"threshold"`
-	Status       string     `json:"status"` // firing, resolved
-	Message      string     `json:"message"`
-	FiredAt      time.Time  `json:"fired_at"`
-	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
-	NotifiedAt   *time.Time `json:"notified_at,omitempty"`
-}
@@ -0,0 +1,360 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops/internal/model"
+)
+
+// 通用 Webhook 的签名请求头
+// 签名为 HMAC-SHA256(secret, timestamp + "." + body) 的十六进制，接收方应校验时间戳防止重放
+const (
+	HeaderTimestamp = "X-Devops-Timestamp"
+	HeaderSignature = "X-Devops-Signature"
+)
+
+// errPermanent 重试也不会成功的发送错误，如渠道配置错误或接收方拒绝
+var errPermanent = errors.New("发送被拒绝")
+
+// levelNames 通知级别的显示名称
+var levelNames = map[string]string{
+	LevelInfo:     "提示",
+	LevelWarning:  "警告",
+	LevelCritical: "严重",
+}
+
+// render 生成通知的标题和正文
+// 告警规则或渠道配置了模板时使用模板，模板执行失败（如渠道模板引用了任务通知没有的告警字段）时使用默认格式
+func render(channel *model.NotifyChannel, msg Message) (string, string) {
+	title, body := defaultRender(msg)
+
+	titleTemplate, bodyTemplate := msg.Template.Title, msg.Template.Body
+	if channel != nil {
+		if titleTemplate == "" {
+			titleTemplate = channel.TitleTemplate
+		}
+		if bodyTemplate == "" {
+			bodyTemplate = channel.BodyTemplate
+		}
+	}
+	if titleTemplate != "" {
+		if text, err := executeTemplate(titleTemplate, msg); err == nil {
+			title = text
+		} else {
+			log.Printf("渲染通知标题模板失败，使用默认格式: %v", err)
+		}
+	}
+	if bodyTemplate != "" {
+		if text, err := executeTemplate(bodyTemplate, msg); err == nil {
+			body = text
+		} else {
+			log.Printf("渲染通知正文模板失败，使用默认格式: %v", err)
+		}
+	}
+	return title, body
+}
+
+// defaultRender 默认格式的标题和正文，正文包含详细信息和按键排序的附加信息
+func defaultRender(msg Message) (string, string) {
+	title := msg.Title
+	if name, ok := levelNames[msg.Level]; ok {
+		title = fmt.Sprintf("[%s] %s", name, msg.Title)
+	}
+
+	var b strings.Builder
+	b.WriteString(msg.Content)
+	keys := make([]string, 0, len(msg.Labels))
+	for k := range msg.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		b.WriteString("\n")
+	}
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", k, msg.Labels[k])
+	}
+	if !msg.Time.IsZero() {
+		fmt.Fprintf(&b, "\n时间: %s", msg.Time.Format("2006-01-02 15:04:05"))
+	}
+	return title, b.String()
+}
+
+// send 通过渠道发送一条通知
+func send(ctx context.Context, client *http.Client, channel *model.NotifyChannel, msg Message) error {
+	title, body := render(channel, msg)
+	cfg := channel.Config
+
+	switch channel.Type {
+	case model.NotifyChannelEmail:
+		return sendEmail(ctx, cfg, title, body)
+	case model.NotifyChannelWebhook:
+		return sendWebhook(ctx, client, cfg, webhookPayload{Message: msg, RenderedTitle: title, RenderedBody: body})
+	case model.NotifyChannelDingTalk:
+		return sendDingTalk(ctx, client, cfg, title, body)
+	case model.NotifyChannelWeCom:
+		return postJSON(ctx, client, cfg.URL, map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": markdown(title, body)},
+		}, nil, checkErrcode)
+	case model.NotifyChannelFeishu:
+		return sendFeishu(ctx, client, cfg, title, body)
+	case model.NotifyChannelSlack:
+		return postJSON(ctx, client, cfg.URL, map[string]string{
+			"text": "*" + title + "*\n" + body,
+		}, nil, nil)
+	}
+	return fmt.Errorf("%w: 不支持的渠道类型 %s", errPermanent, channel.Type)
+}
+
+// markdown 生成钉钉、企业微信的 Markdown 正文
+func markdown(title, body string) string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = line + "  " // Markdown 中的行尾两个空格表示换行
+		}
+	}
+	return "### " + title + "\n\n" + strings.Join(lines, "\n")
+}
+
+// webhookPayload 通用 Webhook 的推送内容，在原始消息之外附带按模板渲染的标题和正文
+type webhookPayload struct {
+	Message
+	RenderedTitle string `json:"rendered_title"`
+	RenderedBody  string `json:"rendered_body"`
+}
+
+// sendWebhook 以 JSON 推送通知消息，配置了密钥时附带 HMAC 签名
+func sendWebhook(ctx context.Context, client *http.Client, cfg model.NotifyChannelConfig, in webhookPayload) error {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("%w: 序列化消息失败: %v", errPermanent, err)
+	}
+	var header http.Header
+	if cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		header = http.Header{}
+		header.Set(HeaderTimestamp, timestamp)
+		header.Set(HeaderSignature, "sha256="+Sign(cfg.Secret, timestamp, payload))
+	}
+	return postJSON(ctx, client, cfg.URL, json.RawMessage(payload), header, nil)
+}
+
+// Sign 计算通用 Webhook 的签名
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendDingTalk 通过钉钉群机器人发送 Markdown 消息，配置了加签密钥时在地址中附带签名
+func sendDingTalk(ctx context.Context, client *http.Client, cfg model.NotifyChannelConfig, title, body string) error {
+	target := cfg.URL
+	if cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.Secret))
+		mac.Write([]byte(timestamp + "\n" + cfg.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return fmt.Errorf("%w: Webhook 地址无效: %v", errPermanent, err)
+		}
+		q := u.Query()
+		q.Set("timestamp", timestamp)
+		q.Set("sign", sign)
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+	return postJSON(ctx, client, target, map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": title, "text": markdown(title, body)},
+	}, nil, checkErrcode)
+}
+
+// sendFeishu 通过飞书群机器人发送文本消息，配置了加签密钥时在请求中附带签名
+func sendFeishu(ctx context.Context, client *http.Client, cfg model.NotifyChannelConfig, title, body string) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": title + "\n" + body},
+	}
+	if cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+cfg.Secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return postJSON(ctx, client, cfg.URL, payload, nil, checkFeishuCode)
+}
+
+// checkErrcode 检查钉钉、企业微信返回的错误码
+func checkErrcode(body []byte) error {
+	var resp struct {
+		Errcode int    `json:"errcode"`
+		Errmsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if resp.Errcode != 0 {
+		return fmt.Errorf("%w: 错误码 %d: %s", errPermanent, resp.Errcode, resp.Errmsg)
+	}
+	return nil
+}
+
+// checkFeishuCode 检查飞书返回的错误码
+func checkFeishuCode(body []byte) error {
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("%w: 错误码 %d: %s", errPermanent, resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// postJSON 发送 JSON 请求，check 不为空时用于检查响应内容
+// 4xx（429 除外）视为不可重试的错误
+func postJSON(ctx context.Context, client *http.Client, target string, in interface{}, header http.Header, check func([]byte) error) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("%w: 序列化消息失败: %v", errPermanent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("接收方返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return fmt.Errorf("%w: %v", errPermanent, err)
+		}
+		return err
+	}
+	if check != nil {
+		return check(respBody)
+	}
+	return nil
+}
+
+// smtpTimeout SMTP 会话的超时时间
+const smtpTimeout = 30 * time.Second
+
+// sendEmail 通过 SMTP 发送纯文本邮件
+func sendEmail(ctx context.Context, cfg model.NotifyChannelConfig, title, body string) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	defer c.Close()
+
+	if port != 465 {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("SMTP STARTTLS 失败: %w", err)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("%w: SMTP 认证失败: %v", errPermanent, err)
+		}
+	}
+
+	if err := c.Mail(cfg.From); err != nil {
+		return fmt.Errorf("SMTP MAIL 失败: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT %s 失败: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA 失败: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("写入邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return c.Quit()
+}
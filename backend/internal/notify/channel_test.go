@@ -0,0 +1,289 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMessage() Message {
+	return Message{
+		Source:  SourceMonitor,
+		Key:     "alert:1:2:",
+		Level:   LevelCritical,
+		Title:   "CPU使用率过高",
+		Content: "当前值 95.00",
+		Labels:  map[string]string{"server_id": "2", "metric": "cpu"},
+		Time:    time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRender(t *testing.T) {
+	title, body := render(nil, testMessage())
+	assert.Equal(t, "[严重] CPU使用率过高", title)
+	assert.Equal(t, "当前值 95.00\n\nmetric: cpu\nserver_id: 2\n时间: 2024-01-01 08:00:00", body)
+}
+
+// capture 记录收到的请求，返回 response 作为响应
+func capture(t *testing.T, status int, response string) (*httptest.Server, *http.Request, *[]byte) {
+	t.Helper()
+	var req http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r.Clone(context.Background())
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &req, &body
+}
+
+func TestSendWebhookSigned(t *testing.T) {
+	server, req, body := capture(t, http.StatusOK, "")
+	channel := &model.NotifyChannel{
+		Type:   model.NotifyChannelWebhook,
+		Config: model.NotifyChannelConfig{URL: server.URL, Secret: "s3cret"},
+	}
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(*body, &msg))
+	assert.Equal(t, "CPU使用率过高", msg.Title)
+	assert.Equal(t, "2", msg.Labels["server_id"])
+
+	timestamp := req.Header.Get(HeaderTimestamp)
+	require.NotEmpty(t, timestamp)
+	assert.Equal(t, "sha256="+Sign("s3cret", timestamp, *body), req.Header.Get(HeaderSignature))
+
+	// 未配置模板时附带默认格式的标题和正文
+	var payload webhookPayload
+	require.NoError(t, json.Unmarshal(*body, &payload))
+	assert.Equal(t, "[严重] CPU使用率过高", payload.RenderedTitle)
+	assert.Contains(t, payload.RenderedBody, "当前值 95.00")
+
+	// 渠道模板对 Webhook 同样生效
+	channel.TitleTemplate = "{{.Title}} @ {{.Labels.server_id}}"
+	channel.BodyTemplate = "level={{.Level}}"
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+	payload = webhookPayload{}
+	require.NoError(t, json.Unmarshal(*body, &payload))
+	assert.Equal(t, "CPU使用率过高 @ 2", payload.RenderedTitle)
+	assert.Equal(t, "level=critical", payload.RenderedBody)
+	assert.Equal(t, "CPU使用率过高", payload.Title)
+
+	// 未配置密钥时不签名
+	channel.Config.Secret = ""
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+	assert.Empty(t, req.Header.Get(HeaderSignature))
+}
+
+func TestSendDingTalk(t *testing.T) {
+	server, req, body := capture(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	channel := &model.NotifyChannel{
+		Type:   model.NotifyChannelDingTalk,
+		Config: model.NotifyChannelConfig{URL: server.URL + "/robot/send?access_token=abc", Secret: "SEC1"},
+	}
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+
+	q := req.URL.Query()
+	assert.Equal(t, "abc", q.Get("access_token"))
+	mac := hmac.New(sha256.New, []byte("SEC1"))
+	mac.Write([]byte(q.Get("timestamp") + "\nSEC1"))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), q.Get("sign"))
+
+	var payload struct {
+		Msgtype  string            `json:"msgtype"`
+		Markdown map[string]string `json:"markdown"`
+	}
+	require.NoError(t, json.Unmarshal(*body, &payload))
+	assert.Equal(t, "markdown", payload.Msgtype)
+	assert.Equal(t, "[严重] CPU使用率过高", payload.Markdown["title"])
+	assert.Contains(t, payload.Markdown["text"], "当前值 95.00")
+
+	// 钉钉返回错误码时不重试
+	server, _, _ = capture(t, http.StatusOK, `{"errcode":310000,"errmsg":"sign not match"}`)
+	channel.Config.URL = server.URL
+	err := send(context.Background(), server.Client(), channel, testMessage())
+	assert.ErrorIs(t, err, errPermanent)
+	assert.Contains(t, err.Error(), "sign not match")
+}
+
+func TestSendWeCom(t *testing.T) {
+	server, _, body := capture(t, http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)
+	channel := &model.NotifyChannel{Type: model.NotifyChannelWeCom, Config: model.NotifyChannelConfig{URL: server.URL}}
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+
+	var payload struct {
+		Msgtype  string            `json:"msgtype"`
+		Markdown map[string]string `json:"markdown"`
+	}
+	require.NoError(t, json.Unmarshal(*body, &payload))
+	assert.Equal(t, "markdown", payload.Msgtype)
+	assert.True(t, strings.HasPrefix(payload.Markdown["content"], "### [严重] CPU使用率过高"))
+}
+
+func TestSendFeishu(t *testing.T) {
+	server, _, body := capture(t, http.StatusOK, `{"code":0,"msg":"success"}`)
+	channel := &model.NotifyChannel{
+		Type:   model.NotifyChannelFeishu,
+		Config: model.NotifyChannelConfig{URL: server.URL, Secret: "fs"},
+	}
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+
+	var payload struct {
+		MsgType   string            `json:"msg_type"`
+		Content   map[string]string `json:"content"`
+		Timestamp string            `json:"timestamp"`
+		Sign      string            `json:"sign"`
+	}
+	require.NoError(t, json.Unmarshal(*body, &payload))
+	assert.Equal(t, "text", payload.MsgType)
+	assert.Contains(t, payload.Content["text"], "CPU使用率过高")
+	mac := hmac.New(sha256.New, []byte(payload.Timestamp+"\nfs"))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), payload.Sign)
+}
+
+func TestSendSlack(t *testing.T) {
+	server, _, body := capture(t, http.StatusOK, "ok")
+	channel := &model.NotifyChannel{Type: model.NotifyChannelSlack, Config: model.NotifyChannelConfig{URL: server.URL}}
+	require.NoError(t, send(context.Background(), server.Client(), channel, testMessage()))
+
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal(*body, &payload))
+	assert.True(t, strings.HasPrefix(payload["text"], "*[严重] CPU使用率过高*\n"))
+
+	// 4xx 不重试，5xx 可以重试
+	server, _, _ = capture(t, http.StatusNotFound, "no_service")
+	channel.Config.URL = server.URL
+	assert.ErrorIs(t, send(context.Background(), server.Client(), channel, testMessage()), errPermanent)
+
+	server, _, _ = capture(t, http.StatusBadGateway, "")
+	channel.Config.URL = server.URL
+	err := send(context.Background(), server.Client(), channel, testMessage())
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, errPermanent)
+}
+
+// smtpStandIn 最简单的 SMTP 服务器，记录一封邮件的发件人、收件人和内容
+func smtpStandIn(t *testing.T) (string, int, chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+
+		var lines []string
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 Go ahead")
+				data, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				lines = append(lines, strings.Join(data, "\r\n"))
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				received <- lines
+				return
+			default:
+				tp.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return host, p, received
+}
+
+func TestSendEmail(t *testing.T) {
+	host, port, received := smtpStandIn(t)
+	channel := &model.NotifyChannel{
+		Type: model.NotifyChannelEmail,
+		Config: model.NotifyChannelConfig{
+			Host: host,
+			Port: port,
+			From: "devops@example.com",
+			To:   []string{"oncall@example.com", "ops@example.com"},
+		},
+	}
+	require.NoError(t, send(context.Background(), http.DefaultClient, channel, testMessage()))
+
+	lines := <-received
+	require.Len(t, lines, 4)
+	assert.Equal(t, "MAIL FROM:<devops@example.com>", lines[0])
+	assert.Equal(t, "RCPT TO:<oncall@example.com>", lines[1])
+	assert.Equal(t, "RCPT TO:<ops@example.com>", lines[2])
+
+	m, err := mail.ReadMessage(strings.NewReader(lines[3]))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "[严重] CPU使用率过高", subject)
+	encoded, err := io.ReadAll(m.Body)
+	require.NoError(t, err)
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "当前值 95.00")
+}
+
+func TestValidateChannel(t *testing.T) {
+	valid := []model.NotifyChannel{
+		{Name: "oncall", Type: model.NotifyChannelEmail, Config: model.NotifyChannelConfig{Host: "smtp", From: "a@b", To: []string{"c@d"}}},
+		{Name: "hook", Type: model.NotifyChannelSlack, Config: model.NotifyChannelConfig{URL: "https://hooks.slack.com/services/x"}},
+	}
+	for i := range valid {
+		assert.NoError(t, validateChannel(&valid[i]))
+	}
+
+	invalid := []model.NotifyChannel{
+		{Type: model.NotifyChannelSlack, Config: model.NotifyChannelConfig{URL: "https://x"}},
+		{Name: "a", Type: "sms", Config: model.NotifyChannelConfig{URL: "https://x"}},
+		{Name: "a", Type: model.NotifyChannelEmail, Config: model.NotifyChannelConfig{Host: "smtp", From: "a@b"}},
+		{Name: "a", Type: model.NotifyChannelWebhook, Config: model.NotifyChannelConfig{URL: "ftp://x"}},
+		{Name: "a", Type: model.NotifyChannelWebhook, Config: model.NotifyChannelConfig{URL: "https://x"}, RateLimit: -1},
+	}
+	for i := range invalid {
+		assert.Error(t, validateChannel(&invalid[i]), "channel %d", i)
+	}
+}
@@ -0,0 +1,54 @@
+// Package notify 告警通知的统一出口
+//
+// 监控告警和任务通知都以 Message 的形式交给 Notifier 发送，
+// 通知渠道只需在此接入一次即可覆盖所有告警来源。
+// Service 按消息的路由选择通知渠道，发送时按渠道限流、失败重试并记录发送结果。
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// 通知级别
+const (
+	LevelInfo     = "info" // 恢复等提示
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+// 通知来源
+const (
+	SourceMonitor = "monitor"
+	SourceTask    = "task"
+)
+
+// Message 通知消息
+type Message struct {
+	Source   string            `json:"source"`           // monitor, task
+	Key      string            `json:"key"`              // 告警标识，同一来源的同类告警相同，如 task:3:failure
+	Level    string            `json:"level"`            // info, warning, critical
+	Title    string            `json:"title"`            // 摘要
+	Content  string            `json:"content"`          // 详细信息
+	Labels   map[string]string `json:"labels,omitempty"` // 附加信息，如服务器、任务
+	Time     time.Time         `json:"time"`
+	Alert    *Alert            `json:"alert,omitempty"` // 监控告警的详细信息，其他来源为空
+	Route    Route             `json:"-"`               // 发送目标
+	Template Template          `json:"-"`               // 告警规则的通知模板，优先于渠道的模板
+}
+
+// Route 通知的发送目标，为空时发送到所有默认渠道
+type Route struct {
+	Channels []uint   // 指定的通知渠道
+	Types    []string // 发送到该类型的所有启用渠道，如告警规则勾选邮件通知时为 email
+}
+
+// empty 判断是否未指定发送目标
+func (r Route) empty() bool {
+	return len(r.Channels) == 0 && len(r.Types) == 0
+}
+
+// Notifier 发送通知
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,341 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"devops/internal/model"
+	"devops/pkg/cache"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 发送失败后的重试参数，重试间隔从 retryDelay 开始翻倍
+const (
+	maxAttempts     = 3
+	retryDelay      = 2 * time.Second
+	deliveryTimeout = 2 * time.Minute // 一条通知在一个渠道上的发送（含重试）的最长时间
+)
+
+// secretMask 查询渠道时代替密钥和密码返回，更新时传回该值表示保持不变
+const secretMask = "******"
+
+// ErrChannelNotFound 通知渠道不存在
+var ErrChannelNotFound = errors.New("通知渠道不存在")
+
+// channelTypes 支持的通知渠道类型
+var channelTypes = map[string]bool{
+	model.NotifyChannelEmail:    true,
+	model.NotifyChannelWebhook:  true,
+	model.NotifyChannelDingTalk: true,
+	model.NotifyChannelWeCom:    true,
+	model.NotifyChannelFeishu:   true,
+	model.NotifyChannelSlack:    true,
+}
+
+// Service 通知服务，按路由将通知发送到通知渠道
+type Service struct {
+	db         *gorm.DB
+	cache      *cache.CacheService
+	keys       *cache.CacheKeys
+	client     *http.Client
+	retryDelay time.Duration
+}
+
+// NewService 创建通知服务
+func NewService(db *gorm.DB, rdb *redis.Client) *Service {
+	return &Service{
+		db:         db,
+		cache:      cache.NewCacheService(rdb, "devops"),
+		keys:       cache.NewCacheKeys(),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		retryDelay: retryDelay,
+	}
+}
+
+// allow 判断渠道当前这一分钟内是否还能发送，允许时记录一次发送
+// 计数保存在Redis中，所有通知服务和后端实例共用同一限额；Redis不可用时不限制
+func (s *Service) allow(ctx context.Context, channel *model.NotifyChannel, now time.Time) bool {
+	if channel.ID == 0 || channel.RateLimit <= 0 {
+		return true
+	}
+
+	key := s.keys.NotifyRateLimit(channel.ID, now.Unix()/60)
+	count, err := s.cache.Increment(ctx, key)
+	if err != nil {
+		log.Printf("检查通知渠道 %s 的发送频率失败: %v", channel.Name, err)
+		return true
+	}
+	if count == 1 {
+		s.cache.SetExpire(ctx, key, cache.TTLNotifyLimit)
+	}
+	return count <= int64(channel.RateLimit)
+}
+
+// Notify 按消息的路由异步发送通知，每个渠道的发送结果记录在发送记录中
+func (s *Service) Notify(ctx context.Context, msg Message) error {
+	log.Printf("告警[%s/%s]: %s %s", msg.Source, msg.Level, msg.Title, msg.Content)
+
+	channels, err := s.routeChannels(msg.Route)
+	if err != nil {
+		return err
+	}
+	for i := range channels {
+		go func(channel *model.NotifyChannel) {
+			ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+			defer cancel()
+			s.deliver(ctx, channel, msg)
+		}(&channels[i])
+	}
+	return nil
+}
+
+// routeChannels 确定消息的发送渠道
+func (s *Service) routeChannels(route Route) ([]model.NotifyChannel, error) {
+	var channels []model.NotifyChannel
+	query := s.db.Where("enabled = ?", true)
+	switch {
+	case route.empty():
+		query = query.Where("is_default = ?", true)
+	case len(route.Channels) > 0 && len(route.Types) > 0:
+		query = query.Where("id IN ? OR type IN ?", route.Channels, route.Types)
+	case len(route.Channels) > 0:
+		query = query.Where("id IN ?", route.Channels)
+	default:
+		query = query.Where("type IN ?", route.Types)
+	}
+	if err := query.Order("id").Find(&channels).Error; err != nil {
+		return nil, fmt.Errorf("查询通知渠道失败: %w", err)
+	}
+	return channels, nil
+}
+
+// deliver 通过一个渠道发送通知并记录发送结果
+func (s *Service) deliver(ctx context.Context, channel *model.NotifyChannel, msg Message) error {
+	delivery := model.NotifyDelivery{
+		ChannelID:   channel.ID,
+		ChannelType: channel.Type,
+		Source:      msg.Source,
+		Key:         msg.Key,
+		Level:       msg.Level,
+		Title:       msg.Title,
+		Status:      model.NotifyDeliverySent,
+	}
+
+	var err error
+	if !s.allow(ctx, channel, time.Now()) {
+		delivery.Status = model.NotifyDeliveryRateLimited
+		err = fmt.Errorf("超出渠道 %s 的发送频率限制（每分钟 %d 条）", channel.Name, channel.RateLimit)
+	} else {
+		delivery.Attempts, err = s.sendWithRetry(ctx, channel, msg)
+		if err != nil {
+			delivery.Status = model.NotifyDeliveryFailed
+		}
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		log.Printf("通过渠道 %s 发送通知失败: %v", channel.Name, err)
+	}
+
+	if dbErr := s.db.Create(&delivery).Error; dbErr != nil {
+		log.Printf("保存通知发送记录失败: %v", dbErr)
+	}
+	return err
+}
+
+// sendWithRetry 发送通知，失败时按退避间隔重试，返回尝试次数
+func (s *Service) sendWithRetry(ctx context.Context, channel *model.NotifyChannel, msg Message) (int, error) {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = send(ctx, s.client, channel, msg); err == nil {
+			return attempt, nil
+		}
+		if attempt >= maxAttempts || errors.Is(err, errPermanent) {
+			return attempt, err
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// TestChannel 通过渠道同步发送一条测试通知
+func (s *Service) TestChannel(ctx context.Context, id uint) error {
+	channel, err := s.GetChannel(id)
+	if err != nil {
+		return err
+	}
+	return s.deliver(ctx, channel, Message{
+		Source:  "test",
+		Key:     fmt.Sprintf("test:%d", id),
+		Level:   LevelInfo,
+		Title:   "测试通知",
+		Content: fmt.Sprintf("这是一条来自通知渠道「%s」的测试消息", channel.Name),
+		Time:    time.Now(),
+	})
+}
+
+// validateChannel 校验通知渠道配置
+func validateChannel(channel *model.NotifyChannel) error {
+	if strings.TrimSpace(channel.Name) == "" {
+		return errors.New("通知渠道名称不能为空")
+	}
+	if !channelTypes[channel.Type] {
+		return fmt.Errorf("不支持的渠道类型: %s", channel.Type)
+	}
+	if channel.RateLimit < 0 {
+		return errors.New("发送频率限制不能为负数")
+	}
+	if err := ValidateTemplate(channel.TitleTemplate); err != nil {
+		return fmt.Errorf("通知标题%w", err)
+	}
+	if err := ValidateTemplate(channel.BodyTemplate); err != nil {
+		return fmt.Errorf("通知正文%w", err)
+	}
+
+	cfg := channel.Config
+	if channel.Type == model.NotifyChannelEmail {
+		if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return errors.New("邮件渠道需要配置SMTP服务器、发件人和收件人")
+		}
+		if cfg.Port < 0 || cfg.Port > 65535 {
+			return errors.New("SMTP端口无效")
+		}
+		return nil
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Webhook 地址无效")
+	}
+	return nil
+}
+
+// maskChannel 隐藏渠道配置中的密钥和密码
+func maskChannel(channel model.NotifyChannel) model.NotifyChannel {
+	if channel.Config.Secret != "" {
+		channel.Config.Secret = secretMask
+	}
+	if channel.Config.Password != "" {
+		channel.Config.Password = secretMask
+	}
+	return channel
+}
+
+// ListChannels 获取通知渠道列表，密钥和密码不返回
+func (s *Service) ListChannels() ([]model.NotifyChannel, error) {
+	var channels []model.NotifyChannel
+	if err := s.db.Order("id DESC").Find(&channels).Error; err != nil {
+		return nil, fmt.Errorf("查询通知渠道失败: %w", err)
+	}
+	for i := range channels {
+		channels[i] = maskChannel(channels[i])
+	}
+	return channels, nil
+}
+
+// GetChannel 获取通知渠道，包含密钥和密码，仅供内部使用
+func (s *Service) GetChannel(id uint) (*model.NotifyChannel, error) {
+	var channel model.NotifyChannel
+	if err := s.db.First(&channel, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, fmt.Errorf("查询通知渠道失败: %w", err)
+	}
+	return &channel, nil
+}
+
+// CreateChannel 创建通知渠道
+func (s *Service) CreateChannel(channel *model.NotifyChannel) error {
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+	if err := s.db.Create(channel).Error; err != nil {
+		return fmt.Errorf("创建通知渠道失败: %w", err)
+	}
+	*channel = maskChannel(*channel)
+	return nil
+}
+
+// UpdateChannel 更新通知渠道，密钥或密码为 secretMask 时保持原值
+func (s *Service) UpdateChannel(id uint, channel *model.NotifyChannel) error {
+	existing, err := s.GetChannel(id)
+	if err != nil {
+		return err
+	}
+	if channel.Config.Secret == secretMask {
+		channel.Config.Secret = existing.Config.Secret
+	}
+	if channel.Config.Password == secretMask {
+		channel.Config.Password = existing.Config.Password
+	}
+	if err := validateChannel(channel); err != nil {
+		return err
+	}
+
+	config, err := json.Marshal(channel.Config)
+	if err != nil {
+		return fmt.Errorf("序列化渠道配置失败: %w", err)
+	}
+	err = s.db.Model(&model.NotifyChannel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":           channel.Name,
+		"type":           channel.Type,
+		"config":         string(config),
+		"rate_limit":     channel.RateLimit,
+		"is_default":     channel.Default,
+		"enabled":        channel.Enabled,
+		"title_template": channel.TitleTemplate,
+		"body_template":  channel.BodyTemplate,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("更新通知渠道失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteChannel 删除通知渠道
+func (s *Service) DeleteChannel(id uint) error {
+	result := s.db.Delete(&model.NotifyChannel{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除通知渠道失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+// ListDeliveries 分页查询发送记录，channelID 为0、status 为空时不过滤
+func (s *Service) ListDeliveries(channelID uint, status string, page, pageSize int) ([]model.NotifyDelivery, int64, error) {
+	var deliveries []model.NotifyDelivery
+	var total int64
+
+	query := s.db.Model(&model.NotifyDelivery{})
+	if channelID > 0 {
+		query = query.Where("channel_id = ?", channelID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询发送记录总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询发送记录失败: %w", err)
+	}
+	return deliveries, total, nil
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", DB: 15})
+	defer rdb.Close()
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		t.Skip("Redis not available, skipping rate limit tests")
+	}
+	rdb.FlushDB(context.Background())
+
+	// 两个通知服务（如不同实例）共用同一限额
+	a, b := NewService(nil, rdb), NewService(nil, rdb)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	channel := &model.NotifyChannel{ID: 1, RateLimit: 2}
+
+	assert.True(t, a.allow(ctx, channel, now))
+	assert.True(t, b.allow(ctx, channel, now.Add(10*time.Second)))
+	assert.False(t, a.allow(ctx, channel, now.Add(20*time.Second)))
+	// 渠道之间分别计数，不限制时总是允许
+	assert.True(t, b.allow(ctx, &model.NotifyChannel{ID: 2, RateLimit: 2}, now))
+	assert.True(t, a.allow(ctx, &model.NotifyChannel{ID: 3}, now))
+
+	// 下一分钟重新计数
+	assert.True(t, a.allow(ctx, channel, now.Add(time.Minute)))
+}
+
+func TestSendWithRetry(t *testing.T) {
+	calls := 0
+	status := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status[min(calls, len(status)-1)])
+		calls++
+	}))
+	defer server.Close()
+
+	s := &Service{client: server.Client(), retryDelay: time.Millisecond}
+	channel := &model.NotifyChannel{Type: model.NotifyChannelSlack, Config: model.NotifyChannelConfig{URL: server.URL}}
+
+	// 暂时性错误重试后成功
+	attempts, err := s.sendWithRetry(context.Background(), channel, testMessage())
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// 一直失败时最多尝试 maxAttempts 次
+	calls, status = 0, []int{http.StatusInternalServerError}
+	attempts, err = s.sendWithRetry(context.Background(), channel, testMessage())
+	assert.Error(t, err)
+	assert.Equal(t, maxAttempts, attempts)
+	assert.Equal(t, maxAttempts, calls)
+
+	// 接收方拒绝时不重试
+	calls, status = 0, []int{http.StatusForbidden}
+	attempts, err = s.sendWithRetry(context.Background(), channel, testMessage())
+	assert.ErrorIs(t, err, errPermanent)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestMaskChannel(t *testing.T) {
+	channel := model.NotifyChannel{Config: model.NotifyChannelConfig{Secret: "s", Password: "p", URL: "https://x"}}
+	masked := maskChannel(channel)
+	assert.Equal(t, secretMask, masked.Config.Secret)
+	assert.Equal(t, secretMask, masked.Config.Password)
+	assert.Equal(t, "https://x", masked.Config.URL)
+	assert.Equal(t, "s", channel.Config.Secret)
+
+	assert.Empty(t, maskChannel(model.NotifyChannel{}).Config.Secret)
+}
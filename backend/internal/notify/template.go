@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"devops/internal/model"
+)
+
+// Alert 告警通知的详细信息，供通知模板和通用 Webhook 使用
+type Alert struct {
+	ID         uint              `json:"id"`
+	Status     string            `json:"status"` // firing, resolved
+	RuleID     uint              `json:"rule_id"`
+	RuleName   string            `json:"rule_name"`
+	ServerID   uint              `json:"server_id"`
+	ServerName string            `json:"server_name"`
+	ServerHost string            `json:"server_host"`
+	Metric     string            `json:"metric"`
+	Labels     map[string]string `json:"labels,omitempty"` // 如磁盘告警的 mountpoint
+	Condition  string            `json:"condition"`
+	Value      float64           `json:"value"`
+	Threshold  float64           `json:"threshold"`
+	StartsAt   time.Time         `json:"starts_at"` // 开始持续满足条件的时间
+	FiredAt    time.Time         `json:"fired_at"`
+	ResolvedAt *time.Time        `json:"resolved_at,omitempty"`
+	Duration   time.Duration     `json:"duration"` // 触发时为已持续的时间，恢复时为告警的总时长
+}
+
+// Resolved 判断告警是否已恢复
+func (a *Alert) Resolved() bool {
+	return a.Status == model.AlertStatusResolved
+}
+
+// Template 通知模板，为空的部分使用默认格式
+type Template struct {
+	Title string
+	Body  string
+}
+
+// templateFuncs 通知模板可用的函数
+var templateFuncs = template.FuncMap{
+	// level 级别的显示名称，如 严重
+	"level": func(level string) string {
+		if name, ok := levelNames[level]; ok {
+			return name
+		}
+		return level
+	},
+	// time 格式化时间，如 2006-01-02 15:04:05
+	"time": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+	// duration 将时长取整到秒，如 5m30s
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// executeTemplate 使用消息执行模板
+func executeTemplate(text string, msg Message) (string, error) {
+	tmpl, err := template.New("notify").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("模板语法错误: %w", err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, msg); err != nil {
+		return "", fmt.Errorf("模板执行失败: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ValidateTemplate 校验通知模板，分别使用触发和恢复状态的示例告警执行
+func ValidateTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	for _, status := range []string{model.AlertStatusFiring, model.AlertStatusResolved} {
+		if _, err := executeTemplate(text, SampleMessage(status)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Preview 使用示例告警渲染模板，模板为空的部分使用默认格式
+func Preview(tmpl Template, status string) (string, string, error) {
+	msg := SampleMessage(status)
+	msg.Template = tmpl
+	for _, text := range []string{tmpl.Title, tmpl.Body} {
+		if _, err := executeTemplate(text, msg); err != nil {
+			return "", "", err
+		}
+	}
+	title, body := render(nil, msg)
+	return title, body, nil
+}
+
+// SampleMessage 用于预览和校验模板的示例告警通知
+func SampleMessage(status string) Message {
+	firedAt := time.Date(2024, 1, 1, 8, 5, 0, 0, time.Local)
+	alert := &Alert{
+		ID:         1,
+		Status:     model.AlertStatusFiring,
+		RuleID:     1,
+		RuleName:   "CPU使用率过高",
+		ServerID:   1,
+		ServerName: "web-01",
+		ServerHost: "10.0.0.5",
+		Metric:     model.MetricCPU,
+		Condition:  ">",
+		Value:      92.5,
+		Threshold:  80,
+		StartsAt:   firedAt.Add(-5 * time.Minute),
+		FiredAt:    firedAt,
+		Duration:   5 * time.Minute,
+	}
+	msg := Message{
+		Source:  SourceMonitor,
+		Key:     "alert:1:1:",
+		Level:   LevelCritical,
+		Title:   "web-01 CPU使用率过高",
+		Content: "web-01（10.0.0.5） cpu 当前值 92.50，条件 > 80.00，已持续 5m0s",
+		Labels:  map[string]string{"server": "web-01", "host": "10.0.0.5", "metric": model.MetricCPU},
+		Time:    firedAt,
+		Alert:   alert,
+	}
+
+	if status == model.AlertStatusResolved {
+		resolvedAt := firedAt.Add(10 * time.Minute)
+		alert.Status = model.AlertStatusResolved
+		alert.Value = 35.2
+		alert.ResolvedAt = &resolvedAt
+		alert.Duration = 15 * time.Minute
+		msg.Level = LevelInfo
+		msg.Title = "web-01 CPU使用率过高 已恢复"
+		msg.Content = "web-01（10.0.0.5） cpu 当前值 35.20，告警持续 15m0s"
+		msg.Time = resolvedAt
+	}
+	return msg
+}
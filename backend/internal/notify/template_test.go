@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"testing"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	msg := SampleMessage(model.AlertStatusFiring)
+	channel := &model.NotifyChannel{
+		TitleTemplate: `{{level .Level}} {{.Alert.ServerName}}`,
+		BodyTemplate:  `{{.Alert.ServerHost}} {{.Alert.Metric}} {{printf "%.1f" .Alert.Value}}/{{.Alert.Threshold}} {{duration .Alert.Duration}}`,
+	}
+	title, body := render(channel, msg)
+	assert.Equal(t, "严重 web-01", title)
+	assert.Equal(t, "10.0.0.5 cpu 92.5/80 5m0s", body)
+
+	// 告警规则的模板优先于渠道模板，为空的部分使用渠道模板
+	msg.Template = Template{Title: `{{if .Alert.Resolved}}恢复{{else}}告警{{end}} {{.Alert.RuleName}}`}
+	title, body = render(channel, msg)
+	assert.Equal(t, "告警 CPU使用率过高", title)
+	assert.Equal(t, "10.0.0.5 cpu 92.5/80 5m0s", body)
+
+	// 任务通知没有告警信息，模板执行失败时使用默认格式
+	title, body = render(channel, testMessage())
+	defaultTitle, defaultBody := defaultRender(testMessage())
+	assert.Equal(t, defaultTitle, title)
+	assert.Equal(t, defaultBody, body)
+}
+
+func TestValidateTemplate(t *testing.T) {
+	assert.NoError(t, ValidateTemplate(""))
+	assert.NoError(t, ValidateTemplate(`{{.Alert.ServerName}} {{time .Time}}{{with .Alert.ResolvedAt}} {{time .}}{{end}}`))
+
+	assert.Error(t, ValidateTemplate(`{{.Alert.ServerName`))
+	assert.Error(t, ValidateTemplate(`{{.Alert.Hostname}}`))
+	assert.Error(t, ValidateTemplate(`{{unknown .Title}}`))
+	// 只在恢复时出错的模板同样无效
+	assert.Error(t, ValidateTemplate(`{{time .Alert.ResolvedAt}}`))
+}
+
+func TestPreview(t *testing.T) {
+	title, body, err := Preview(Template{Title: `{{.Alert.ServerName}} {{.Alert.Status}}`}, model.AlertStatusResolved)
+	require.NoError(t, err)
+	assert.Equal(t, "web-01 resolved", title)
+	assert.Contains(t, body, "告警持续 15m0s")
+
+	_, _, err = Preview(Template{Body: `{{.Nope}}`}, model.AlertStatusFiring)
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"devops/pkg/cache"
+)
+
+// elector 基于Redis租约的主节点选举
+//
+// 主节点每 ttl/3 续约一次，续约失败立即退位；其他实例在租约到期时立即尝试接管，
+// 主节点宕机后最迟一个租约周期内完成切换。每次当选时自增任期计数作为fencing令牌，
+// 租约值中携带该令牌，任务触发时以租约值校验主节点身份。
+type elector struct {
+	cache    *cache.CacheService
+	keys     *cache.CacheKeys
+	instance string
+	ttl      time.Duration
+
+	// onElected 当选后回调
+	onElected func()
+
+	mu        sync.RWMutex
+	value     string    // 当前持有的租约值，非主节点为空
+	token     int64     // 当前任期令牌
+	expiresAt time.Time // 本地估算的租约到期时间
+}
+
+func newElector(c *cache.CacheService, keys *cache.CacheKeys, instance string, ttl time.Duration) *elector {
+	return &elector{
+		cache:    c,
+		keys:     keys,
+		instance: instance,
+		ttl:      ttl,
+	}
+}
+
+// current 返回当前租约值和任期令牌，非主节点或租约可能已过期时返回false
+func (e *elector) current() (string, int64, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.value == "" || !time.Now().Before(e.expiresAt) {
+		return "", 0, false
+	}
+	return e.value, e.token, true
+}
+
+// run 选举主循环，stop 关闭时主动释放租约
+func (e *elector) run(stop <-chan struct{}) {
+	for {
+		wait := e.step()
+		select {
+		case <-stop:
+			e.release()
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// step 执行一次续约或竞选，返回距下一次尝试的等待时间
+func (e *elector) step() time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+
+	e.mu.RLock()
+	value := e.value
+	e.mu.RUnlock()
+
+	if value != "" {
+		start := time.Now()
+		ok, err := e.cache.CompareAndExpire(ctx, e.keys.SchedulerLeader(), value, e.ttl)
+		if err != nil || !ok {
+			log.Printf("调度主节点续约失败，退位: ok=%v err=%v", ok, err)
+			e.setLeader("", 0, time.Time{})
+			return e.ttl / 3
+		}
+		e.mu.Lock()
+		e.expiresAt = start.Add(e.ttl)
+		e.mu.Unlock()
+		return e.ttl / 3
+	}
+
+	// 租约仍被持有时等到其到期再竞选
+	remaining, err := e.cache.TTL(ctx, e.keys.SchedulerLeader())
+	if err != nil {
+		return e.ttl / 3
+	}
+	if remaining > 0 {
+		return minDuration(remaining, e.ttl/3) + 10*time.Millisecond
+	}
+
+	token, err := e.cache.Increment(ctx, e.keys.SchedulerEpoch())
+	if err != nil {
+		return e.ttl / 3
+	}
+	candidate := fmt.Sprintf("%s:%d", e.instance, token)
+	start := time.Now()
+	ok, err := e.cache.SetNX(ctx, e.keys.SchedulerLeader(), candidate, e.ttl)
+	if err != nil || !ok {
+		return e.ttl / 3
+	}
+
+	e.setLeader(candidate, token, start.Add(e.ttl))
+	log.Printf("当选调度主节点，任期令牌 %d", token)
+	if e.onElected != nil {
+		go e.onElected()
+	}
+	return e.ttl / 3
+}
+
+// release 主动释放租约，便于其他实例立即接管
+func (e *elector) release() {
+	e.mu.RLock()
+	value := e.value
+	e.mu.RUnlock()
+	if value == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e.cache.CompareAndDelete(ctx, e.keys.SchedulerLeader(), value)
+	e.setLeader("", 0, time.Time{})
+}
+
+func (e *elector) setLeader(value string, token int64, expiresAt time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.value = value
+	e.token = token
+	e.expiresAt = expiresAt
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"devops/pkg/cache"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestRedis(t *testing.T) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+		DB:   15, // 使用测试数据库
+	})
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		t.Skip("Redis not available, skipping scheduler tests")
+	}
+	rdb.FlushDB(context.Background())
+	return rdb
+}
+
+func TestElectorFailover(t *testing.T) {
+	rdb := setupTestRedis(t)
+	defer rdb.Close()
+
+	c := cache.NewCacheService(rdb, "test")
+	keys := cache.NewCacheKeys()
+	ttl := 600 * time.Millisecond
+
+	a := newElector(c, keys, "a", ttl)
+	b := newElector(c, keys, "b", ttl)
+
+	a.step()
+	b.step()
+	valueA, tokenA, ok := a.current()
+	require.True(t, ok)
+	_, _, ok = b.current()
+	assert.False(t, ok)
+
+	// a 停止续约，b 应在一个租约周期内接管并拿到更大的令牌
+	deadline := time.Now().Add(ttl + 100*time.Millisecond)
+	for time.Now().Before(deadline) {
+		time.Sleep(b.step())
+		if _, _, ok := b.current(); ok {
+			break
+		}
+	}
+	valueB, tokenB, ok := b.current()
+	require.True(t, ok)
+	assert.Greater(t, tokenB, tokenA)
+
+	// 旧主节点的租约值无法再写入触发标记
+	ctx := context.Background()
+	_, err := c.FencedAdvance(ctx, keys.SchedulerLeader(), valueA, keys.TaskLock(1), 100, time.Minute)
+	assert.ErrorIs(t, err, cache.ErrNotOwner)
+
+	// 同一触发时间只能写入一次
+	acquired, err := c.FencedAdvance(ctx, keys.SchedulerLeader(), valueB, keys.TaskLock(1), 100, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = c.FencedAdvance(ctx, keys.SchedulerLeader(), valueB, keys.TaskLock(1), 100, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// 旧主节点续约失败后退位
+	a.step()
+	_, _, ok = a.current()
+	assert.False(t, ok)
+}
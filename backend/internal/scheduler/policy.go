@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"devops/internal/model"
+)
+
+// maxCatchUp 补执行错过触发的上限，超出的最早触发记为跳过
+const maxCatchUp = 100
+
+// maxMissedScan 统计错过触发次数时最多遍历的触发时间
+const maxMissedScan = 100000
+
+// missedDecision 计划触发时间已错过时的处理结果
+type missedDecision struct {
+	run     time.Time // 需要执行的计划触发时间，零值表示不执行
+	skipped int       // 记为跳过的触发次数
+	after   time.Time // 从该时间之后安排下一次触发
+}
+
+// decideMissed 按错过触发策略处理 (fireAt, now] 内已错过的触发
+//
+// skip 跳过全部；run_once 只执行最近一次；catch_up 执行 fireAt 并从 fireAt 开始安排下一次，
+// 后续错过的触发会依次到期，积压超过 maxCatchUp 时跳过最早的部分
+func decideMissed(policy string, next func(time.Time) time.Time, fireAt, now time.Time) missedDecision {
+	// fires[0] 为 fireAt，其后为截至 now 已错过的触发
+	fires := []time.Time{fireAt}
+	total := 1
+	for t := next(fireAt); !t.IsZero() && !t.After(now) && total < maxMissedScan; t = next(t) {
+		total++
+		fires = append(fires, t)
+		if len(fires) > maxCatchUp {
+			fires = fires[1:]
+		}
+	}
+
+	switch policy {
+	case model.TaskMissedRunOnce:
+		return missedDecision{run: fires[len(fires)-1], skipped: total - 1, after: now}
+	case model.TaskMissedCatchUp:
+		if total <= maxCatchUp {
+			return missedDecision{run: fireAt, after: fireAt}
+		}
+		return missedDecision{run: fires[0], skipped: total - maxCatchUp, after: fires[0]}
+	default:
+		return missedDecision{skipped: total, after: now}
+	}
+}
+
+// runningSet 本实例上各任务正在执行（含重试等待）的触发
+type runningSet struct {
+	mu    sync.Mutex
+	seq   uint64
+	tasks map[uint]map[uint64]context.CancelFunc
+}
+
+func newRunningSet() *runningSet {
+	return &runningSet{tasks: make(map[uint]map[uint64]context.CancelFunc)}
+}
+
+// add 登记一次执行，返回用于移除的编号
+func (r *runningSet) add(taskID uint, cancel context.CancelFunc) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	if r.tasks[taskID] == nil {
+		r.tasks[taskID] = make(map[uint64]context.CancelFunc)
+	}
+	r.tasks[taskID][r.seq] = cancel
+	return r.seq
+}
+
+func (r *runningSet) remove(taskID uint, id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tasks[taskID], id)
+	if len(r.tasks[taskID]) == 0 {
+		delete(r.tasks, taskID)
+	}
+}
+
+func (r *runningSet) has(taskID uint) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tasks[taskID]) > 0
+}
+
+// cancels 返回任务在本实例上全部执行的取消函数
+func (r *runningSet) cancels(taskID uint) []context.CancelFunc {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]context.CancelFunc, 0, len(r.tasks[taskID]))
+	for _, cancel := range r.tasks[taskID] {
+		list = append(list, cancel)
+	}
+	return list
+}
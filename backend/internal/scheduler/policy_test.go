@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"devops/internal/model"
+	"devops/pkg/cron"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecideMissed(t *testing.T) {
+	schedule, err := cron.Parse("*/10 * * * *")
+	require.NoError(t, err)
+
+	fireAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 00:10、00:20、00:30 也已错过
+	now := fireAt.Add(35 * time.Minute)
+
+	d := decideMissed(model.TaskMissedSkip, schedule.Next, fireAt, now)
+	assert.True(t, d.run.IsZero())
+	assert.Equal(t, 4, d.skipped)
+	assert.Equal(t, now, d.after)
+
+	d = decideMissed(model.TaskMissedRunOnce, schedule.Next, fireAt, now)
+	assert.Equal(t, fireAt.Add(30*time.Minute), d.run)
+	assert.Equal(t, 3, d.skipped)
+	assert.Equal(t, now, d.after)
+
+	d = decideMissed(model.TaskMissedCatchUp, schedule.Next, fireAt, now)
+	assert.Equal(t, fireAt, d.run)
+	assert.Equal(t, 0, d.skipped)
+	assert.Equal(t, fireAt, d.after)
+
+	// 积压超过上限时跳过最早的触发
+	now = fireAt.Add(time.Duration(maxCatchUp+20) * 10 * time.Minute)
+	d = decideMissed(model.TaskMissedCatchUp, schedule.Next, fireAt, now)
+	assert.Equal(t, 21, d.skipped)
+	assert.Equal(t, fireAt.Add(21*10*time.Minute), d.run)
+	assert.Equal(t, d.run, d.after)
+}
+
+func TestRunningSet(t *testing.T) {
+	r := newRunningSet()
+	assert.False(t, r.has(1))
+
+	_, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := r.add(1, cancel)
+	b := r.add(1, cancel)
+	assert.True(t, r.has(1))
+	assert.Len(t, r.cancels(1), 2)
+
+	r.remove(1, a)
+	assert.True(t, r.has(1))
+	r.remove(1, b)
+	assert.False(t, r.has(1))
+	assert.Empty(t, r.cancels(1))
+}
@@ -0,0 +1,391 @@
+// Package scheduler 定时任务调度器
+//
+// 任务的下一次触发时间保存在 Redis 有序集合（cache.TaskNextRun）中，
+// 调度器定期取出到期任务，通过 SSH 在任务所属服务器上执行并写入执行记录。
+// 多个后端实例同时运行时通过 Redis 租约选出唯一主节点负责触发，
+// 每次触发再以 cache.TaskLock 上的幂等标记保证同一触发时间在集群内只执行一次。
+//
+// 触发晚于计划时间超过任务的启动期限（例如停机恢复后）时按错过触发策略处理，
+// 上一次执行仍在运行时按并发策略处理，被跳过的触发写入状态为已跳过的执行记录。
+// 工作流使用独立的调度队列（cache.WorkflowNextRun），由同一主节点触发。
+// 主节点同时定期按保留策略清理历史执行记录和部署日志。
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/internal/monitor"
+	"devops/internal/service"
+	"devops/pkg/cache"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// pollInterval 检查到期任务的间隔
+const pollInterval = time.Second
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	tasks     *service.TaskService
+	workflows *service.WorkflowService
+	retention *service.RetentionService
+	history   *monitor.HistoryStore
+	leader    *elector
+	running   *runningSet
+
+	// 执行中的任务在调度器停止时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New 创建调度器
+func New(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		tasks:     service.NewTaskService(db, rdb, cfg.Task),
+		workflows: service.NewWorkflowService(db, rdb, cfg.Task),
+		retention: service.NewRetentionService(db, cfg.Retention),
+		history:   monitor.NewHistoryStore(db, cfg.Monitor.History),
+		running:   newRunningSet(),
+		leader:    newElector(cache.NewCacheService(rdb, "devops"), cache.NewCacheKeys(), service.InstanceID(), cache.TTLSchedulerLease),
+		ctx:       ctx,
+		cancel:    cancel,
+		stopChan:  make(chan struct{}),
+	}
+	// 当选后补齐调度队列，队列中已有的触发时间保留，切换期间到期的触发由新主节点执行
+	s.leader.onElected = func() {
+		if err := s.reload(); err != nil {
+			log.Printf("重建调度队列失败: %v", err)
+		}
+	}
+	return s
+}
+
+// Start 参与主节点选举并开始调度，只有主节点会触发任务
+func (s *Scheduler) Start() error {
+	s.wg.Add(3)
+	go func() {
+		defer s.wg.Done()
+		s.leader.run(s.stopChan)
+	}()
+	go s.loop()
+	go s.janitor()
+
+	log.Println("任务调度器已启动")
+	return nil
+}
+
+// Stop 停止调度并等待执行中的任务结束，超时后取消剩余任务
+func (s *Scheduler) Stop(ctx context.Context) error {
+	close(s.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+// IsLeader 判断当前实例是否为调度主节点
+func (s *Scheduler) IsLeader() bool {
+	_, _, ok := s.leader.current()
+	return ok
+}
+
+// reload 将不在调度队列中的启用任务和工作流按当前时间加入队列
+func (s *Scheduler) reload() error {
+	tasks, err := s.tasks.ListSchedulable()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range tasks {
+		if err := s.tasks.EnsureScheduled(s.ctx, &tasks[i], now); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+
+	workflows, err := s.workflows.ListSchedulable()
+	if err != nil {
+		return err
+	}
+	for i := range workflows {
+		if err := s.workflows.EnsureScheduled(s.ctx, &workflows[i], now); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	return nil
+}
+
+// loop 调度主循环
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	notifyTicker := time.NewTicker(service.NotifyCheckInterval)
+	defer notifyTicker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.dispatchDue()
+		case now := <-notifyTicker.C:
+			if _, _, ok := s.leader.current(); ok {
+				if err := s.tasks.CheckNoSuccess(s.ctx, now); err != nil {
+					log.Printf("检查任务成功执行情况失败: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// janitor 主节点定期按保留策略清理历史记录、汇总监控历史数据，调度器停止时中断正在进行的清理
+func (s *Scheduler) janitor() {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(s.retention.Interval())
+	defer ticker.Stop()
+	compactTicker := time.NewTicker(monitor.CompactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-compactTicker.C:
+			if _, _, ok := s.leader.current(); !ok {
+				continue
+			}
+			if err := s.history.Compact(ctx, now); err != nil && ctx.Err() == nil {
+				log.Printf("汇总监控历史数据失败: %v", err)
+			}
+		case <-ticker.C:
+			if _, _, ok := s.leader.current(); !ok {
+				continue
+			}
+			report, err := s.retention.Purge(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("清理历史记录失败（已删除%s）: %v", report, err)
+				continue
+			}
+			if report.TaskExecutions+report.OutputChunks+report.DeploymentLogs > 0 {
+				log.Printf("历史记录清理完成: %s", report)
+			}
+		}
+	}
+}
+
+// dispatchDue 取出所有到期任务并异步执行
+func (s *Scheduler) dispatchDue() {
+	leader, token, ok := s.leader.current()
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	due, err := s.tasks.DueTasks(s.ctx, now)
+	if err != nil {
+		log.Printf("查询到期任务失败: %v", err)
+		return
+	}
+
+	for _, d := range due {
+		acquired, err := s.tasks.AcquireFire(s.ctx, d.TaskID, d.FireAt, leader)
+		if errors.Is(err, cache.ErrNotOwner) {
+			// 已失去主节点身份，剩余任务交给新主节点
+			return
+		}
+		if err != nil {
+			log.Printf("获取任务 %d 触发标记失败: %v", d.TaskID, err)
+			continue
+		}
+
+		task, err := s.tasks.GetByID(d.TaskID)
+		if err != nil {
+			// 任务已删除
+			s.tasks.Unschedule(s.ctx, d.TaskID)
+			continue
+		}
+
+		// 该触发时间已被执行过（例如前任主节点已处理），只需推进调度
+		if !acquired || task.Status != model.TaskStatusEnabled {
+			if err := s.tasks.Schedule(s.ctx, task, now); err != nil {
+				log.Printf("%v", err)
+			}
+			continue
+		}
+
+		fireAt, after := d.FireAt, now
+		if now.Sub(d.FireAt) > service.StartingDeadline(task) {
+			fireAt, after = s.handleMissed(task, d.FireAt, now, token)
+		}
+
+		// 先安排下一次触发，避免长时间运行的任务推迟后续调度
+		if err := s.tasks.Schedule(s.ctx, task, after); err != nil {
+			log.Printf("%v", err)
+		}
+		if !fireAt.IsZero() {
+			s.fire(task, fireAt, token)
+		}
+	}
+
+	s.dispatchWorkflows(leader, token, now)
+}
+
+// dispatchWorkflows 取出所有到期工作流并异步运行，晚于计划时间超过默认启动期限的触发记为跳过
+func (s *Scheduler) dispatchWorkflows(leader string, token int64, now time.Time) {
+	due, err := s.workflows.DueWorkflows(s.ctx, now)
+	if err != nil {
+		log.Printf("查询到期工作流失败: %v", err)
+		return
+	}
+
+	for _, d := range due {
+		acquired, err := s.workflows.AcquireFire(s.ctx, d.WorkflowID, d.FireAt, leader)
+		if errors.Is(err, cache.ErrNotOwner) {
+			return
+		}
+		if err != nil {
+			log.Printf("获取工作流 %d 触发标记失败: %v", d.WorkflowID, err)
+			continue
+		}
+
+		workflow, err := s.workflows.GetByID(d.WorkflowID)
+		if err != nil {
+			// 工作流已删除
+			s.workflows.Unschedule(s.ctx, d.WorkflowID)
+			continue
+		}
+		if err := s.workflows.Schedule(s.ctx, workflow, now); err != nil {
+			log.Printf("%v", err)
+		}
+		if !acquired || workflow.Status != model.TaskStatusEnabled {
+			continue
+		}
+
+		if now.Sub(d.FireAt) > service.DefaultStartingDeadline {
+			reason := "调度延迟超过启动期限，跳过错过的触发"
+			if err := s.workflows.RecordSkipped(workflow, d.FireAt, reason, token); err != nil {
+				log.Printf("%v", err)
+			}
+			continue
+		}
+
+		fireAt := d.FireAt
+		run, err := s.workflows.Start(workflow, service.WorkflowRunOptions{
+			Trigger:      model.TaskTriggerSchedule,
+			ScheduledAt:  &fireAt,
+			FencingToken: token,
+		})
+		if err != nil {
+			log.Printf("工作流 %d 启动失败: %v", workflow.ID, err)
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.workflows.Execute(s.ctx, workflow, run)
+		}()
+	}
+}
+
+// handleMissed 按错过触发策略处理已错过的触发，返回需要执行的计划时间和安排下一次触发的起点
+func (s *Scheduler) handleMissed(task *model.Task, fireAt, now time.Time, token int64) (time.Time, time.Time) {
+	next, err := service.TaskSchedule(task)
+	if err != nil {
+		return time.Time{}, now
+	}
+
+	decision := decideMissed(task.MissedRunPolicy, next, fireAt, now)
+	if decision.skipped > 0 {
+		reason := fmt.Sprintf("调度延迟超过启动期限，跳过 %d 次错过的触发", decision.skipped)
+		if err := s.tasks.RecordSkipped(task, fireAt, reason, token); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	return decision.run, decision.after
+}
+
+// fire 按并发策略执行一次计划触发
+func (s *Scheduler) fire(task *model.Task, fireAt time.Time, token int64) {
+	var previous []model.TaskExecution
+	var cancels []context.CancelFunc
+	if task.ConcurrencyPolicy == model.TaskConcurrencyForbid || task.ConcurrencyPolicy == model.TaskConcurrencyReplace {
+		running, err := s.tasks.RunningExecutions(task.ID)
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		local := s.running.cancels(task.ID)
+		if len(running) > 0 || len(local) > 0 {
+			if task.ConcurrencyPolicy == model.TaskConcurrencyForbid {
+				if err := s.tasks.RecordSkipped(task, fireAt, "上一次执行仍在运行", token); err != nil {
+					log.Printf("%v", err)
+				}
+				return
+			}
+			previous, cancels = running, local
+		}
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	id := s.running.add(task.ID, cancel)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.remove(task.ID, id)
+		defer cancel()
+
+		// 先将旧记录标记为被替换再取消本实例上的执行，避免其写入的结果覆盖替换原因
+		for i := range previous {
+			if err := s.tasks.TerminateExecution(ctx, &previous[i], "已被新的触发替换"); err != nil {
+				log.Printf("终止任务 %d 的执行 %d 失败: %v", task.ID, previous[i].ID, err)
+			}
+		}
+		for _, c := range cancels {
+			c()
+		}
+
+		execution := s.tasks.Execute(ctx, task, service.ExecuteOptions{
+			Trigger:      model.TaskTriggerSchedule,
+			ScheduledAt:  &fireAt,
+			FencingToken: token,
+		})
+		if execution.Status == model.TaskExecutionFailed {
+			log.Printf("任务 %d 执行失败: %s", task.ID, execution.Error)
+		}
+	}()
+}
@@ -0,0 +1,740 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/pkg/cache"
+	"devops/pkg/git"
+	"devops/pkg/ssh"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// cancelWaitTimeout 取消请求等待执行实例响应的最长时间，超时后由当前实例强制结束
+const cancelWaitTimeout = 5 * time.Second
+
+// previewCommitLimit 变更预览最多返回的提交数
+const previewCommitLimit = 200
+
+// ErrDeploymentFrozen 部署处于冻结窗口
+var ErrDeploymentFrozen = errors.New("当前处于部署冻结窗口")
+
+// DeploymentService 部署服务
+type DeploymentService struct {
+	db       *gorm.DB
+	rdb      *redis.Client
+	cache    *cache.CacheService
+	keys     *cache.CacheKeys
+	config   config.Deploy
+	freezes  *FreezeService
+	scripts  *ScriptService
+	instance string
+
+	// 本实例正在执行的运行，用于响应取消通知
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc
+}
+
+// NewDeploymentService 创建部署服务
+func NewDeploymentService(db *gorm.DB, rdb *redis.Client, cfg config.Deploy) *DeploymentService {
+	cacheService := cache.NewCacheService(rdb, "devops")
+	s := &DeploymentService{
+		db:       db,
+		rdb:      rdb,
+		cache:    cacheService,
+		keys:     cache.NewCacheKeys(),
+		config:   cfg,
+		freezes:  NewFreezeService(db),
+		scripts:  NewScriptService(db),
+		instance: InstanceID(),
+		running:  make(map[uint]context.CancelFunc),
+	}
+	s.subscribeCancel()
+	return s
+}
+
+// GetByID 根据ID获取部署
+func (s *DeploymentService) GetByID(id uint) (*model.Deployment, error) {
+	var deployment model.Deployment
+	err := s.db.Preload("Server").First(&deployment, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("部署不存在")
+		}
+		return nil, fmt.Errorf("查询部署失败: %w", err)
+	}
+	return &deployment, nil
+}
+
+// List 获取部署列表
+func (s *DeploymentService) List(page, pageSize int) ([]model.Deployment, int64, error) {
+	var deployments []model.Deployment
+	var total int64
+
+	if err := s.db.Model(&model.Deployment{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询部署总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := s.db.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deployments).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询部署列表失败: %w", err)
+	}
+
+	return deployments, total, nil
+}
+
+// Create 创建部署
+func (s *DeploymentService) Create(deployment *model.Deployment) error {
+	if deployment.Strategy == "" {
+		deployment.Strategy = model.DeploymentStrategyScript
+	}
+	if err := validateDeployment(deployment); err != nil {
+		return err
+	}
+	if deployment.ScriptID != nil && deployment.Strategy == model.DeploymentStrategyScript {
+		if err := s.scripts.CheckReference(*deployment.ScriptID, deployment.ScriptVersion, nil); err != nil {
+			return err
+		}
+	}
+
+	var count int64
+	s.db.Model(&model.Server{}).Where("id = ?", deployment.ServerID).Count(&count)
+	if count == 0 {
+		return errors.New("服务器不存在")
+	}
+
+	if err := s.db.Create(deployment).Error; err != nil {
+		return fmt.Errorf("创建部署失败: %w", err)
+	}
+	return nil
+}
+
+// GetRun 获取部署运行记录
+func (s *DeploymentService) GetRun(runID uint) (*model.DeploymentRun, error) {
+	var run model.DeploymentRun
+	if err := s.db.First(&run, runID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("部署运行记录不存在")
+		}
+		return nil, fmt.Errorf("查询部署运行记录失败: %w", err)
+	}
+	return &run, nil
+}
+
+// ListRuns 获取部署的运行记录
+func (s *DeploymentService) ListRuns(deploymentID uint, page, pageSize int) ([]model.DeploymentRun, int64, error) {
+	var runs []model.DeploymentRun
+	var total int64
+
+	query := s.db.Model(&model.DeploymentRun{}).Where("deployment_id = ?", deploymentID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询运行记录总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询运行记录失败: %w", err)
+	}
+
+	return runs, total, nil
+}
+
+// GetRunLogs 获取部署运行日志
+func (s *DeploymentService) GetRunLogs(runID uint) ([]model.DeploymentLog, error) {
+	var logs []model.DeploymentLog
+	if err := s.db.Where("run_id = ?", runID).Order("id ASC").Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("查询部署日志失败: %w", err)
+	}
+	return logs, nil
+}
+
+// TriggerOptions 触发部署的选项
+type TriggerOptions struct {
+	Tag            string // 镜像标签，仅Docker/Compose部署使用
+	OverrideReason string // 冻结期间强制部署的理由，非空表示管理员覆盖冻结窗口
+}
+
+// Trigger 触发一次部署
+func (s *DeploymentService) Trigger(deploymentID, userID uint, opts TriggerOptions) (*model.DeploymentRun, error) {
+	deployment, err := s.GetByID(deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &model.DeploymentRun{
+		Action: model.DeploymentActionDeploy,
+		Ref:    deployment.Branch,
+	}
+	if isImageStrategy(deployment.Strategy) {
+		run.ImageTag = opts.Tag
+		if run.ImageTag == "" {
+			// Compose 部署只能按标签回滚，必须指定标签
+			if deployment.Strategy == model.DeploymentStrategyCompose {
+				return nil, errors.New("Compose部署需要指定镜像标签")
+			}
+			run.ImageTag = "latest"
+		}
+		run.PreviousTag = deployment.CurrentTag
+	}
+
+	return s.start(deployment, run, userID, opts.OverrideReason)
+}
+
+// Rollback 回滚到上一次部署的镜像
+// Docker部署优先使用上一次实际运行的镜像摘要，重复部署同一标签（如 latest）时同样可以回滚
+func (s *DeploymentService) Rollback(deploymentID, userID uint, overrideReason string) (*model.DeploymentRun, error) {
+	deployment, err := s.GetByID(deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	if !isImageStrategy(deployment.Strategy) {
+		return nil, errors.New("仅Docker/Compose部署支持回滚")
+	}
+
+	run := &model.DeploymentRun{
+		Action:      model.DeploymentActionRollback,
+		Ref:         deployment.Branch,
+		ImageTag:    deployment.PreviousTag,
+		PreviousTag: deployment.CurrentTag,
+	}
+	if deployment.Strategy == model.DeploymentStrategyDocker {
+		run.ImageDigest = deployment.PreviousImage
+	}
+	if run.ImageDigest == "" && run.ImageTag == "" {
+		return nil, errors.New("没有可回滚的镜像")
+	}
+
+	return s.start(deployment, run, userID, overrideReason)
+}
+
+// start 创建运行记录并异步执行
+// 同一部署同时只允许一个运行，互斥通过Redis锁保证，多个后端实例之间同样有效
+// 所有触发来源都经过此处，处于冻结窗口时除非提供覆盖理由否则拒绝
+func (s *DeploymentService) start(deployment *model.Deployment, run *model.DeploymentRun, userID uint, overrideReason string) (*model.DeploymentRun, error) {
+	deploymentID := deployment.ID
+	ctx := context.Background()
+
+	freeze, err := s.freezes.ActiveFreeze(deployment.Server.Environment, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if freeze != nil {
+		if overrideReason == "" {
+			return nil, fmt.Errorf("%w: %s", ErrDeploymentFrozen, freeze.Name)
+		}
+		run.FreezeOverride = overrideReason
+	}
+
+	token := s.instance + ":" + randomToken()
+	lockKey := s.keys.DeploymentLock(deploymentID)
+	ok, err := s.cache.SetNX(ctx, lockKey, token, cache.TTLDeployLock)
+	if err != nil {
+		return nil, fmt.Errorf("获取部署锁失败: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("该部署正在执行中")
+	}
+
+	run.DeploymentID = deploymentID
+	run.Status = model.DeploymentStatusPending
+	run.Environment = deployment.Server.Environment
+	run.Instance = s.instance
+	run.LockToken = token
+	run.TriggeredBy = userID
+	if err := s.db.Create(run).Error; err != nil {
+		s.cache.CompareAndDelete(ctx, lockKey, token)
+		return nil, fmt.Errorf("创建部署运行记录失败: %w", err)
+	}
+
+	s.db.Model(&model.Deployment{}).Where("id = ?", deploymentID).Update("status", model.DeploymentStatusPending)
+	if freeze != nil {
+		s.appendLog(run, "warn", fmt.Sprintf("冻结窗口 %s 生效期间强制部署，理由: %s", freeze.Name, overrideReason))
+	}
+
+	go s.execute(deployment, run)
+
+	return run, nil
+}
+
+// Cancel 取消部署运行
+// 取消通知通过Redis广播给执行该运行的实例；若执行实例未能及时响应（例如已宕机），
+// 由当前实例直接终止远程进程组、标记运行已取消并释放锁
+func (s *DeploymentService) Cancel(runID, userID uint) (*model.DeploymentRun, error) {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != model.DeploymentStatusPending && run.Status != model.DeploymentStatusRunning {
+		return nil, errors.New("当前状态无法取消")
+	}
+
+	s.db.Model(&model.DeploymentRun{}).Where("id = ?", runID).Update("cancelled_by", userID)
+
+	ctx := context.Background()
+	if err := s.cache.Set(ctx, s.keys.DeploymentRunCancel(runID), userID, cache.TTLDeployCancel); err != nil {
+		return nil, fmt.Errorf("设置取消标记失败: %w", err)
+	}
+	if err := s.cache.Publish(ctx, s.keys.DeploymentCancelChannel(), runID); err != nil {
+		log.Printf("广播部署取消通知失败: %v", err)
+	}
+
+	// 等待执行实例完成取消
+	deadline := time.Now().Add(cancelWaitTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+		if run, err = s.GetRun(runID); err != nil {
+			return nil, err
+		}
+		if run.Status == model.DeploymentStatusCancelled {
+			return run, nil
+		}
+		if run.Status != model.DeploymentStatusPending && run.Status != model.DeploymentStatusRunning {
+			return run, errors.New("部署已结束，无法取消")
+		}
+	}
+
+	// 执行实例未响应，强制结束
+	if run.PGID > 0 {
+		if err := s.killRemote(ctx, run.DeploymentID, run.PGID); err != nil {
+			log.Printf("终止部署运行 %d 的远程进程失败: %v", runID, err)
+		}
+	}
+	s.finishRun(run, model.DeploymentStatusCancelled, "部署已取消")
+
+	return s.GetRun(runID)
+}
+
+// execute 执行部署运行
+func (s *DeploymentService) execute(deployment *model.Deployment, run *model.DeploymentRun) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mu.Lock()
+	s.running[run.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, run.ID)
+		s.mu.Unlock()
+	}()
+
+	go s.renewLock(ctx, deployment.ID, run.LockToken)
+
+	// 取消请求可能先于执行到达
+	if s.cancelRequested(run.ID) {
+		s.finishRun(run, model.DeploymentStatusCancelled, "部署已取消")
+		return
+	}
+
+	now := time.Now()
+	run.StartedAt = &now
+	s.db.Model(&model.DeploymentRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"status":     model.DeploymentStatusRunning,
+		"started_at": now,
+	})
+	s.db.Model(&model.Deployment{}).Where("id = ?", deployment.ID).Update("status", model.DeploymentStatusRunning)
+
+	status, message := s.runDeploy(ctx, deployment, run)
+	s.finishRun(run, status, message)
+}
+
+// runDeploy 通过SSH在目标服务器上按部署策略执行部署
+func (s *DeploymentService) runDeploy(ctx context.Context, deployment *model.Deployment, run *model.DeploymentRun) (int, string) {
+	client, err := ssh.Dial(ctx, SSHConfig(&deployment.Server))
+	if err != nil {
+		s.appendLog(run, "error", err.Error())
+		return model.DeploymentStatusFailed, err.Error()
+	}
+	defer client.Close()
+
+	// 引用脚本库的部署在执行时解析脚本版本，跟随最新版本的部署使用此刻的最新版本
+	if deployment.ScriptID != nil && deployment.Strategy == model.DeploymentStrategyScript {
+		rendered, err := s.scripts.Render(*deployment.ScriptID, deployment.ScriptVersion, nil, nil)
+		if err != nil {
+			s.appendLog(run, "error", err.Error())
+			return model.DeploymentStatusFailed, err.Error()
+		}
+		s.appendLog(run, "info", fmt.Sprintf("使用脚本 %d 的版本 %d", *deployment.ScriptID, rendered.scriptVersion))
+		deployment.Script = rendered.command
+	}
+	cmd := deployCommand(deployment, run)
+
+	stdout := newLineWriter(func(line string) { s.appendLog(run, "info", line) })
+	stderr := newLineWriter(func(line string) { s.appendLog(run, "error", line) })
+
+	code, err := client.RunGroup(ctx, cmd, func(pgid int) {
+		run.PGID = pgid
+		s.db.Model(&model.DeploymentRun{}).Where("id = ?", run.ID).Update("pgid", pgid)
+	}, stdout, stderr)
+	stdout.Flush()
+	stderr.Flush()
+
+	cancelled := ctx.Err() != nil
+	if !cancelled && (err != nil || code != 0) {
+		// 取消实例强制结束时本实例可能未收到取消通知，进程被终止导致的失败同样视为取消
+		cancelled = s.cancelRequested(run.ID)
+	}
+	if cancelled {
+		// 取消时本地会话已关闭，需要另开连接终止远程进程组
+		if ctx.Err() != nil && run.PGID > 0 {
+			killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := s.killRemote(killCtx, deployment.ID, run.PGID); err != nil {
+				s.appendLog(run, "warn", err.Error())
+			}
+		}
+		s.appendLog(run, "warn", "部署已取消")
+		return model.DeploymentStatusCancelled, "部署已取消"
+	}
+	if err != nil {
+		s.appendLog(run, "error", err.Error())
+		return model.DeploymentStatusFailed, err.Error()
+	}
+	if code != 0 {
+		message := fmt.Sprintf("部署命令退出码 %d", code)
+		s.appendLog(run, "error", message)
+		return model.DeploymentStatusFailed, message
+	}
+
+	if isImageStrategy(deployment.Strategy) {
+		s.recordImage(ctx, client, deployment, run)
+	} else {
+		s.recordCommit(ctx, client, deployment, run)
+	}
+
+	s.appendLog(run, "info", "部署成功")
+	return model.DeploymentStatusSuccess, ""
+}
+
+// recordCommit 记录目标服务器上实际部署的提交
+// 优先读取部署目录的 HEAD，部署目录不是git工作区时以镜像中分支的最新提交为准
+func (s *DeploymentService) recordCommit(ctx context.Context, client *ssh.Client, deployment *model.Deployment, run *model.DeploymentRun) {
+	var commit string
+	var commitAt *time.Time
+	if deployment.Path != "" {
+		out, err := client.Output(ctx, fmt.Sprintf("git -C %s log -1 --format='%%H %%ct' HEAD", ssh.Quote(deployment.Path)))
+		if fields := strings.Fields(out); err == nil && len(fields) == 2 && len(fields[0]) == 40 {
+			commit = fields[0]
+			if sec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				t := time.Unix(sec, 0)
+				commitAt = &t
+			}
+		}
+	}
+	if commit == "" && deployment.Repository != "" {
+		mirror := s.mirror(deployment)
+		if err := mirror.Sync(ctx); err == nil {
+			if hash, err := mirror.ResolveCommit(ctx, run.Ref); err == nil {
+				if c, err := mirror.GetCommit(ctx, hash); err == nil {
+					commit = c.Hash
+					commitAt = &c.CommittedAt
+				}
+			}
+		}
+	}
+	if commit == "" {
+		s.appendLog(run, "warn", "无法确定部署的提交")
+		return
+	}
+
+	run.Commit = commit
+	run.CommitAt = commitAt
+	s.db.Model(&model.DeploymentRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"commit":    commit,
+		"commit_at": commitAt,
+	})
+	s.db.Model(&model.Deployment{}).Where("id = ?", deployment.ID).Update("deployed_commit", commit)
+}
+
+// recordImage 记录当前运行的镜像标签和镜像摘要，原标签和摘要保留用于回滚
+// 标签或摘要未变化时保留原来的回滚目标
+func (s *DeploymentService) recordImage(ctx context.Context, client *ssh.Client, deployment *model.Deployment, run *model.DeploymentRun) {
+	updates := map[string]interface{}{}
+	if run.ImageTag != "" {
+		updates["current_tag"] = run.ImageTag
+		if run.PreviousTag != "" && run.PreviousTag != run.ImageTag {
+			updates["previous_tag"] = run.PreviousTag
+		}
+	}
+
+	if deployment.Strategy == model.DeploymentStrategyDocker {
+		out, err := client.Output(ctx, imageDigestCommand(deployment))
+		if digest := pickImageDigest(deployment.Image, out); err == nil && digest != "" {
+			run.ImageDigest = digest
+			updates["current_image"] = digest
+			if deployment.CurrentImage != "" && deployment.CurrentImage != digest {
+				updates["previous_image"] = deployment.CurrentImage
+			}
+			s.db.Model(&model.DeploymentRun{}).Where("id = ?", run.ID).Update("image_digest", digest)
+		} else {
+			s.appendLog(run, "warn", "无法确定运行的镜像摘要")
+		}
+	}
+
+	if len(updates) > 0 {
+		s.db.Model(&model.Deployment{}).Where("id = ?", deployment.ID).Updates(updates)
+	}
+}
+
+// DeploymentPreview 部署变更预览
+type DeploymentPreview struct {
+	DeploymentID   uint          `json:"deployment_id"`
+	ServerID       uint          `json:"server_id"`
+	DeployedCommit string        `json:"deployed_commit"`
+	TargetRef      string        `json:"target_ref"`
+	TargetCommit   string        `json:"target_commit"`
+	FirstDeploy    bool          `json:"first_deploy"`
+	Commits        []git.Commit  `json:"commits"`
+	Truncated      bool          `json:"truncated"`
+	DiffStat       *git.DiffStat `json:"diff_stat"`
+}
+
+// Preview 预览当前已部署提交与目标引用之间的变更
+func (s *DeploymentService) Preview(ctx context.Context, deploymentID uint, ref string) (*DeploymentPreview, error) {
+	deployment, err := s.GetByID(deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Repository == "" {
+		return nil, errors.New("部署未配置代码仓库")
+	}
+	if ref == "" {
+		ref = deployment.Branch
+	}
+
+	mirror := s.mirror(deployment)
+	if err := mirror.Sync(ctx); err != nil {
+		return nil, err
+	}
+
+	target, err := mirror.ResolveCommit(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &DeploymentPreview{
+		DeploymentID:   deployment.ID,
+		ServerID:       deployment.ServerID,
+		DeployedCommit: deployment.DeployedCommit,
+		TargetRef:      ref,
+		TargetCommit:   target,
+		FirstDeploy:    deployment.DeployedCommit == "",
+	}
+
+	from := deployment.DeployedCommit
+	if from != "" {
+		if _, err := mirror.ResolveCommit(ctx, from); err != nil {
+			return nil, fmt.Errorf("已部署的提交 %s 在仓库中不存在", from)
+		}
+	}
+
+	commits, err := mirror.Log(ctx, from, target, previewCommitLimit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) > previewCommitLimit {
+		commits = commits[:previewCommitLimit]
+		preview.Truncated = true
+	}
+	preview.Commits = commits
+
+	// 首次部署没有可比较的基准，不计算变更统计
+	if from != "" {
+		if preview.DiffStat, err = mirror.DiffStat(ctx, from, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return preview, nil
+}
+
+// mirror 获取部署代码仓库的本地镜像
+func (s *DeploymentService) mirror(deployment *model.Deployment) *git.Mirror {
+	return git.NewMirror(s.config.MirrorDir, deployment.Repository)
+}
+
+// killRemote 终止部署在目标服务器上的进程组
+func (s *DeploymentService) killRemote(ctx context.Context, deploymentID uint, pgid int) error {
+	deployment, err := s.GetByID(deploymentID)
+	if err != nil {
+		return err
+	}
+
+	client, err := ssh.Dial(ctx, SSHConfig(&deployment.Server))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.KillGroup(ctx, pgid, "TERM")
+}
+
+// finishRun 结束部署运行并释放部署锁
+// 仅当运行仍处于待部署或部署中时才会更新，避免执行实例与取消实例相互覆盖结果
+func (s *DeploymentService) finishRun(run *model.DeploymentRun, status int, message string) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      status,
+		"error":       message,
+		"finished_at": now,
+	}
+	if run.StartedAt != nil {
+		updates["duration"] = int(now.Sub(*run.StartedAt).Seconds())
+	}
+
+	result := s.db.Model(&model.DeploymentRun{}).
+		Where("id = ? AND status IN ?", run.ID, []int{model.DeploymentStatusPending, model.DeploymentStatusRunning}).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("更新部署运行 %d 状态失败: %v", run.ID, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		s.db.Model(&model.Deployment{}).Where("id = ?", run.DeploymentID).Update("status", status)
+	}
+
+	ctx := context.Background()
+	if _, err := s.cache.CompareAndDelete(ctx, s.keys.DeploymentLock(run.DeploymentID), run.LockToken); err != nil {
+		log.Printf("释放部署锁失败: %v", err)
+	}
+	s.cache.Delete(ctx, s.keys.DeploymentRunCancel(run.ID))
+}
+
+// renewLock 执行期间持续续约部署锁
+func (s *DeploymentService) renewLock(ctx context.Context, deploymentID uint, token string) {
+	ticker := time.NewTicker(cache.TTLDeployLock / 3)
+	defer ticker.Stop()
+
+	lockKey := s.keys.DeploymentLock(deploymentID)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := s.cache.CompareAndExpire(ctx, lockKey, token, cache.TTLDeployLock)
+			if err != nil {
+				log.Printf("部署锁续约失败: %v", err)
+			} else if !ok {
+				log.Printf("部署 %d 的锁已丢失", deploymentID)
+			}
+		}
+	}
+}
+
+// cancelRequested 判断运行是否已被请求取消
+func (s *DeploymentService) cancelRequested(runID uint) bool {
+	exists, _ := s.cache.Exists(context.Background(), s.keys.DeploymentRunCancel(runID))
+	return exists
+}
+
+// subscribeCancel 订阅取消通知并等待订阅确认，确保之后开始的运行都能收到取消通知
+// 订阅确认失败时仍继续监听，连接恢复后自动重新订阅
+func (s *DeploymentService) subscribeCancel() {
+	pubsub := s.cache.Subscribe(context.Background(), s.keys.DeploymentCancelChannel())
+	ctx, cancel := context.WithTimeout(context.Background(), cancelWaitTimeout)
+	defer cancel()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Printf("订阅部署取消通知失败: %v", err)
+	}
+	go s.listenCancel(pubsub)
+}
+
+// listenCancel 监听取消通知，取消本实例上正在执行的运行
+func (s *DeploymentService) listenCancel(pubsub *redis.PubSub) {
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		runID, err := strconv.ParseUint(msg.Payload, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		cancel, ok := s.running[uint(runID)]
+		s.mu.Unlock()
+		if ok {
+			cancel()
+		}
+	}
+}
+
+// appendLog 写入部署日志
+func (s *DeploymentService) appendLog(run *model.DeploymentRun, level, message string) {
+	entry := &model.DeploymentLog{
+		DeploymentID: run.DeploymentID,
+		RunID:        run.ID,
+		Level:        level,
+		Message:      message,
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		log.Printf("写入部署日志失败: %v", err)
+	}
+}
+
+// lineWriter 按行回调的输出写入器
+type lineWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	onLine func(string)
+}
+
+func newLineWriter(onLine func(string)) *lineWriter {
+	return &lineWriter{onLine: onLine}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// 不完整的行留到下次写入
+			w.buf.WriteString(line)
+			break
+		}
+		w.onLine(line[:len(line)-1])
+	}
+	return len(p), nil
+}
+
+// Flush 输出剩余的不完整行
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.onLine(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// InstanceID 当前后端实例标识
+func InstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// randomToken 生成随机令牌
+func randomToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
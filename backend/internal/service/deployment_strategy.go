@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"devops/internal/model"
+	"devops/pkg/ssh"
+)
+
+// defaultHealthTimeout 容器健康检查默认超时（秒）
+const defaultHealthTimeout = 60
+
+// validateDeployment 按部署策略校验必填配置
+func validateDeployment(d *model.Deployment) error {
+	if strings.HasPrefix(d.Branch, "-") {
+		return errors.New("分支名称不能以 - 开头")
+	}
+
+	switch d.Strategy {
+	case "", model.DeploymentStrategyScript:
+		if strings.TrimSpace(d.Script) == "" && d.ScriptID == nil {
+			return errors.New("脚本部署需要提供部署脚本或引用脚本库中的脚本")
+		}
+	case model.DeploymentStrategyDocker:
+		if d.Image == "" || d.ContainerName == "" {
+			return errors.New("Docker部署需要提供镜像和容器名称")
+		}
+	case model.DeploymentStrategyCompose:
+		if d.Path == "" {
+			return errors.New("Compose部署需要提供compose文件所在目录")
+		}
+	default:
+		return fmt.Errorf("不支持的部署策略: %s", d.Strategy)
+	}
+	return nil
+}
+
+// isImageStrategy 是否为基于镜像标签的部署策略
+func isImageStrategy(strategy string) bool {
+	return strategy == model.DeploymentStrategyDocker || strategy == model.DeploymentStrategyCompose
+}
+
+// deployCommand 生成部署在目标服务器上执行的命令
+func deployCommand(d *model.Deployment, run *model.DeploymentRun) string {
+	switch d.Strategy {
+	case model.DeploymentStrategyDocker:
+		return dockerCommand(d, run)
+	case model.DeploymentStrategyCompose:
+		return composeCommand(d, run.ImageTag)
+	default:
+		if d.Path != "" {
+			return fmt.Sprintf("cd %s && %s", ssh.Quote(d.Path), d.Script)
+		}
+		return d.Script
+	}
+}
+
+// dockerCommand 拉取镜像并以新标签重建容器，指定了镜像摘要时（回滚）使用摘要
+// 摘要为本地镜像ID时无法拉取，直接使用本地镜像
+func dockerCommand(d *model.Deployment, run *model.DeploymentRun) string {
+	image := d.Image + ":" + run.ImageTag
+	if run.ImageDigest != "" {
+		image = run.ImageDigest
+	}
+	name := ssh.Quote(d.ContainerName)
+
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	if !strings.HasPrefix(image, "sha256:") {
+		fmt.Fprintf(&b, "docker pull %s\n", ssh.Quote(image))
+	}
+	fmt.Fprintf(&b, "docker rm -f %s >/dev/null 2>&1 || true\n", name)
+	args := "--name " + name
+	if d.RunOptions != "" {
+		args += " " + d.RunOptions
+	}
+	fmt.Fprintf(&b, "docker run -d %s %s\n", args, ssh.Quote(image))
+	b.WriteString(healthCheckScript(name, healthTimeout(d)))
+	return b.String()
+}
+
+// composeCommand 在部署目录中执行 docker compose pull && up -d
+// 镜像标签通过 IMAGE_TAG 环境变量传入，compose 文件中以 ${IMAGE_TAG} 引用
+func composeCommand(d *model.Deployment, tag string) string {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	fmt.Fprintf(&b, "cd %s\n", ssh.Quote(d.Path))
+	if tag != "" {
+		fmt.Fprintf(&b, "export IMAGE_TAG=%s\n", ssh.Quote(tag))
+	}
+	b.WriteString("docker compose pull\n")
+	b.WriteString("docker compose up -d\n")
+	b.WriteString(healthCheckScript("$(docker compose ps -q)", healthTimeout(d)))
+	return b.String()
+}
+
+// healthCheckScript 等待容器就绪：配置了健康检查的容器需为 healthy，否则需为 running
+func healthCheckScript(containers string, timeout int) string {
+	return fmt.Sprintf(`deadline=$(( $(date +%%s) + %d ))
+while :; do
+	pending=0
+	found=0
+	failed=""
+	for c in %s; do
+		found=1
+		status=$(docker inspect -f '{{if .State.Health}}{{.State.Health.Status}}{{else}}{{.State.Status}}{{end}}' "$c")
+		case "$status" in
+			healthy|running) ;;
+			unhealthy|exited|dead) failed="$failed $c:$status" ;;
+			*) pending=1 ;;
+		esac
+	done
+	if [ -n "$failed" ]; then
+		echo "容器状态异常:$failed" >&2
+		exit 1
+	fi
+	if [ "$found" -eq 1 ] && [ "$pending" -eq 0 ]; then
+		echo "容器健康检查通过"
+		break
+	fi
+	if [ "$(date +%%s)" -ge "$deadline" ]; then
+		if [ "$found" -eq 0 ]; then
+			echo "没有找到运行中的容器" >&2
+		else
+			echo "等待容器健康检查超时" >&2
+		fi
+		exit 1
+	fi
+	sleep 2
+done
+`, timeout, containers)
+}
+
+// imageDigestCommand 查询容器实际运行的镜像，先输出镜像的仓库摘要，最后一行为本地镜像ID
+func imageDigestCommand(d *model.Deployment) string {
+	return fmt.Sprintf(`id=$(docker inspect -f '{{.Image}}' %s) && docker image inspect -f '{{range .RepoDigests}}{{println .}}{{end}}' "$id" && echo "$id"`,
+		ssh.Quote(d.ContainerName))
+}
+
+// pickImageDigest 从 imageDigestCommand 的输出中选择回滚使用的镜像
+// 优先使用部署镜像仓库的摘要（可重新拉取），没有时使用本地镜像ID
+func pickImageDigest(image, out string) string {
+	lines := strings.Fields(out)
+	if len(lines) == 0 {
+		return ""
+	}
+	for _, line := range lines[:len(lines)-1] {
+		if strings.HasPrefix(line, image+"@") {
+			return line
+		}
+	}
+	if id := lines[len(lines)-1]; strings.HasPrefix(id, "sha256:") {
+		return id
+	}
+	return ""
+}
+
+// healthTimeout 健康检查超时（秒）
+func healthTimeout(d *model.Deployment) int {
+	if d.HealthTimeout > 0 {
+		return d.HealthTimeout
+	}
+	return defaultHealthTimeout
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDeployment(t *testing.T) {
+	assert.NoError(t, validateDeployment(&model.Deployment{Script: "make deploy"}))
+	assert.Error(t, validateDeployment(&model.Deployment{Strategy: model.DeploymentStrategyScript}))
+	assert.Error(t, validateDeployment(&model.Deployment{Strategy: model.DeploymentStrategyDocker, Image: "nginx"}))
+	assert.NoError(t, validateDeployment(&model.Deployment{Strategy: model.DeploymentStrategyDocker, Image: "nginx", ContainerName: "web"}))
+	assert.Error(t, validateDeployment(&model.Deployment{Strategy: model.DeploymentStrategyCompose}))
+	assert.Error(t, validateDeployment(&model.Deployment{Strategy: "k8s"}))
+}
+
+func TestDeployCommand(t *testing.T) {
+	t.Run("Script", func(t *testing.T) {
+		d := &model.Deployment{Path: "/srv/app", Script: "make deploy"}
+		assert.Equal(t, "cd '/srv/app' && make deploy", deployCommand(d, &model.DeploymentRun{}))
+	})
+
+	t.Run("Docker", func(t *testing.T) {
+		d := &model.Deployment{
+			Strategy:      model.DeploymentStrategyDocker,
+			Image:         "registry.local/web",
+			ContainerName: "web",
+			RunOptions:    "-p 80:80",
+			HealthTimeout: 30,
+		}
+		cmd := deployCommand(d, &model.DeploymentRun{ImageTag: "v1.2.0"})
+
+		assert.Contains(t, cmd, "docker pull 'registry.local/web:v1.2.0'")
+		assert.Contains(t, cmd, "docker rm -f 'web'")
+		assert.Contains(t, cmd, "docker run -d --name 'web' -p 80:80 'registry.local/web:v1.2.0'")
+		assert.Contains(t, cmd, "+ 30 ))")
+
+		// 按摘要回滚，本地镜像ID不拉取
+		cmd = deployCommand(d, &model.DeploymentRun{ImageTag: "latest", ImageDigest: "registry.local/web@sha256:abc"})
+		assert.Contains(t, cmd, "docker pull 'registry.local/web@sha256:abc'")
+		assert.Contains(t, cmd, "-p 80:80 'registry.local/web@sha256:abc'")
+		cmd = deployCommand(d, &model.DeploymentRun{ImageDigest: "sha256:def"})
+		assert.NotContains(t, cmd, "docker pull")
+		assert.Contains(t, cmd, "-p 80:80 'sha256:def'")
+	})
+
+	t.Run("Compose", func(t *testing.T) {
+		d := &model.Deployment{Strategy: model.DeploymentStrategyCompose, Path: "/srv/stack"}
+		cmd := deployCommand(d, &model.DeploymentRun{ImageTag: "2024.06"})
+
+		assert.Contains(t, cmd, "cd '/srv/stack'")
+		assert.Contains(t, cmd, "export IMAGE_TAG='2024.06'")
+		assert.Contains(t, cmd, "docker compose pull\ndocker compose up -d\n")
+		assert.Contains(t, cmd, "for c in $(docker compose ps -q); do")
+		// 没有容器时不能通过健康检查
+		assert.Contains(t, cmd, `if [ "$found" -eq 1 ] && [ "$pending" -eq 0 ]; then`)
+
+		cmd = deployCommand(d, &model.DeploymentRun{})
+		assert.NotContains(t, cmd, "IMAGE_TAG")
+	})
+}
+
+func TestPickImageDigest(t *testing.T) {
+	out := "registry.local/web@sha256:aaa\nmirror.local/web@sha256:bbb\n\nsha256:ccc\n"
+	assert.Equal(t, "registry.local/web@sha256:aaa", pickImageDigest("registry.local/web", out))
+	assert.Equal(t, "sha256:ccc", pickImageDigest("other/web", out))
+	assert.Equal(t, "sha256:ccc", pickImageDigest("registry.local/web", "\nsha256:ccc\n"))
+	assert.Empty(t, pickImageDigest("registry.local/web", ""))
+	assert.Empty(t, pickImageDigest("registry.local/web", "Error: No such container"))
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"devops/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// weekdays 重复窗口中可用的星期缩写
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// FreezeService 部署冻结窗口服务
+type FreezeService struct {
+	db *gorm.DB
+}
+
+// NewFreezeService 创建部署冻结窗口服务
+func NewFreezeService(db *gorm.DB) *FreezeService {
+	return &FreezeService{db: db}
+}
+
+// List 获取冻结窗口列表
+func (s *FreezeService) List(environment string) ([]model.DeploymentFreeze, error) {
+	var freezes []model.DeploymentFreeze
+	query := s.db.Order("id DESC")
+	if environment != "" {
+		query = query.Where("environment = ? OR environment = ''", environment)
+	}
+	if err := query.Find(&freezes).Error; err != nil {
+		return nil, fmt.Errorf("查询冻结窗口失败: %w", err)
+	}
+	return freezes, nil
+}
+
+// Create 创建冻结窗口
+func (s *FreezeService) Create(freeze *model.DeploymentFreeze) error {
+	if err := validateFreeze(freeze); err != nil {
+		return err
+	}
+	if err := s.db.Create(freeze).Error; err != nil {
+		return fmt.Errorf("创建冻结窗口失败: %w", err)
+	}
+	return nil
+}
+
+// Update 更新冻结窗口
+func (s *FreezeService) Update(id uint, freeze *model.DeploymentFreeze) error {
+	if err := validateFreeze(freeze); err != nil {
+		return err
+	}
+
+	result := s.db.Model(&model.DeploymentFreeze{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":        freeze.Name,
+		"environment": freeze.Environment,
+		"type":        freeze.Type,
+		"start_at":    freeze.StartAt,
+		"end_at":      freeze.EndAt,
+		"start_spec":  freeze.StartSpec,
+		"end_spec":    freeze.EndSpec,
+		"timezone":    freeze.Timezone,
+		"reason":      freeze.Reason,
+		"enabled":     freeze.Enabled,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("更新冻结窗口失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("冻结窗口不存在")
+	}
+	return nil
+}
+
+// Delete 删除冻结窗口
+func (s *FreezeService) Delete(id uint) error {
+	result := s.db.Delete(&model.DeploymentFreeze{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除冻结窗口失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("冻结窗口不存在")
+	}
+	return nil
+}
+
+// ActiveFreeze 返回指定环境在给定时间生效的冻结窗口，没有则返回nil
+func (s *FreezeService) ActiveFreeze(environment string, now time.Time) (*model.DeploymentFreeze, error) {
+	var freezes []model.DeploymentFreeze
+	err := s.db.Where("enabled = ? AND (environment = ? OR environment = '')", true, environment).
+		Find(&freezes).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询冻结窗口失败: %w", err)
+	}
+
+	for i := range freezes {
+		if FreezeActive(&freezes[i], now) {
+			return &freezes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// FreezeActive 判断冻结窗口在给定时间是否生效
+func FreezeActive(f *model.DeploymentFreeze, now time.Time) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	switch f.Type {
+	case model.FreezeTypeOnce:
+		if f.StartAt == nil || f.EndAt == nil {
+			return false
+		}
+		return !now.Before(*f.StartAt) && now.Before(*f.EndAt)
+	case model.FreezeTypeRecurring:
+		loc, err := loadLocation(f.Timezone)
+		if err != nil {
+			return false
+		}
+		start, startWeekly, err := parseFreezeSpec(f.StartSpec)
+		if err != nil {
+			return false
+		}
+		end, _, err := parseFreezeSpec(f.EndSpec)
+		if err != nil {
+			return false
+		}
+
+		local := now.In(loc)
+		current := local.Hour()*60 + local.Minute()
+		if startWeekly {
+			current += int(local.Weekday()) * 24 * 60
+		}
+
+		// 开始晚于结束表示窗口跨越周末或午夜
+		if start <= end {
+			return current >= start && current < end
+		}
+		return current >= start || current < end
+	}
+	return false
+}
+
+// validateFreeze 校验冻结窗口配置
+func validateFreeze(f *model.DeploymentFreeze) error {
+	switch f.Type {
+	case model.FreezeTypeOnce:
+		if f.StartAt == nil || f.EndAt == nil {
+			return errors.New("一次性冻结窗口需要提供开始和结束时间")
+		}
+		if !f.EndAt.After(*f.StartAt) {
+			return errors.New("冻结窗口结束时间必须晚于开始时间")
+		}
+	case model.FreezeTypeRecurring:
+		start, startWeekly, err := parseFreezeSpec(f.StartSpec)
+		if err != nil {
+			return fmt.Errorf("开始时间格式错误: %w", err)
+		}
+		end, endWeekly, err := parseFreezeSpec(f.EndSpec)
+		if err != nil {
+			return fmt.Errorf("结束时间格式错误: %w", err)
+		}
+		if startWeekly != endWeekly {
+			return errors.New("开始和结束时间需同时指定星期或同时不指定")
+		}
+		if start == end {
+			return errors.New("冻结窗口开始和结束时间不能相同")
+		}
+		if _, err := loadLocation(f.Timezone); err != nil {
+			return fmt.Errorf("无效的时区: %s", f.Timezone)
+		}
+	default:
+		return fmt.Errorf("不支持的冻结窗口类型: %s", f.Type)
+	}
+	return nil
+}
+
+// parseFreezeSpec 解析重复窗口时间点，返回距周日零点（或当天零点）的分钟数
+// 支持 "Fri 18:00" 形式的每周时间点和 "18:00" 形式的每天时间点
+func parseFreezeSpec(spec string) (int, bool, error) {
+	fields := strings.Fields(spec)
+	var day string
+	var clock string
+	switch len(fields) {
+	case 1:
+		clock = fields[0]
+	case 2:
+		day, clock = fields[0], fields[1]
+	default:
+		return 0, false, fmt.Errorf("无法解析 %q，应为 \"Fri 18:00\" 或 \"18:00\"", spec)
+	}
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false, fmt.Errorf("无法解析时间 %q", clock)
+	}
+	minutes := t.Hour()*60 + t.Minute()
+
+	if day == "" {
+		return minutes, false, nil
+	}
+	weekday, ok := weekdays[strings.ToLower(day)]
+	if !ok {
+		return 0, false, fmt.Errorf("无法解析星期 %q", day)
+	}
+	return int(weekday)*24*60 + minutes, true, nil
+}
+
+// loadLocation 加载IANA时区，为空时使用服务器本地时区
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreezeActive(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("缺少时区数据")
+	}
+
+	t.Run("Weekend", func(t *testing.T) {
+		f := &model.DeploymentFreeze{
+			Type:      model.FreezeTypeRecurring,
+			StartSpec: "Fri 18:00",
+			EndSpec:   "Mon 09:00",
+			Timezone:  "Asia/Shanghai",
+			Enabled:   true,
+		}
+		// 2024-06-07 为周五
+		assert.False(t, FreezeActive(f, time.Date(2024, 6, 7, 17, 59, 0, 0, loc)))
+		assert.True(t, FreezeActive(f, time.Date(2024, 6, 7, 18, 0, 0, 0, loc)))
+		assert.True(t, FreezeActive(f, time.Date(2024, 6, 9, 12, 0, 0, 0, loc)))
+		assert.True(t, FreezeActive(f, time.Date(2024, 6, 10, 8, 59, 0, 0, loc)))
+		assert.False(t, FreezeActive(f, time.Date(2024, 6, 10, 9, 0, 0, 0, loc)))
+		assert.False(t, FreezeActive(f, time.Date(2024, 6, 12, 12, 0, 0, 0, loc)))
+		// 按窗口时区计算：UTC 周五 10:00 即上海 18:00
+		assert.True(t, FreezeActive(f, time.Date(2024, 6, 7, 10, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("Nightly", func(t *testing.T) {
+		f := &model.DeploymentFreeze{
+			Type:      model.FreezeTypeRecurring,
+			StartSpec: "22:00",
+			EndSpec:   "06:00",
+			Timezone:  "Asia/Shanghai",
+			Enabled:   true,
+		}
+		assert.True(t, FreezeActive(f, time.Date(2024, 6, 5, 23, 0, 0, 0, loc)))
+		assert.True(t, FreezeActive(f, time.Date(2024, 6, 6, 5, 0, 0, 0, loc)))
+		assert.False(t, FreezeActive(f, time.Date(2024, 6, 6, 12, 0, 0, 0, loc)))
+	})
+
+	t.Run("Once", func(t *testing.T) {
+		start := time.Date(2024, 12, 20, 0, 0, 0, 0, loc)
+		end := time.Date(2025, 1, 3, 0, 0, 0, 0, loc)
+		f := &model.DeploymentFreeze{Type: model.FreezeTypeOnce, StartAt: &start, EndAt: &end, Enabled: true}
+		assert.True(t, FreezeActive(f, start))
+		assert.True(t, FreezeActive(f, time.Date(2024, 12, 25, 0, 0, 0, 0, loc)))
+		assert.False(t, FreezeActive(f, end))
+
+		f.Enabled = false
+		assert.False(t, FreezeActive(f, start))
+	})
+}
+
+func TestValidateFreeze(t *testing.T) {
+	start := time.Now()
+	end := start.Add(time.Hour)
+
+	assert.NoError(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeOnce, StartAt: &start, EndAt: &end}))
+	assert.Error(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeOnce, StartAt: &end, EndAt: &start}))
+	assert.Error(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeOnce}))
+	assert.NoError(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeRecurring, StartSpec: "fri 18:00", EndSpec: "Mon 09:00"}))
+	assert.Error(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeRecurring, StartSpec: "Fri 18:00", EndSpec: "09:00"}))
+	assert.Error(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeRecurring, StartSpec: "Fri 25:00", EndSpec: "Mon 09:00"}))
+	assert.Error(t, validateFreeze(&model.DeploymentFreeze{Type: model.FreezeTypeRecurring, StartSpec: "22:00", EndSpec: "06:00", Timezone: "Mars/Base"}))
+	assert.Error(t, validateFreeze(&model.DeploymentFreeze{Type: "cron"}))
+}
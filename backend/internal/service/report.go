@@ -0,0 +1,308 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"devops/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 报表时间分桶粒度
+const (
+	ReportBucketDay   = "day"
+	ReportBucketWeek  = "week"
+	ReportBucketMonth = "month"
+)
+
+// DeploymentReportQuery 部署报表查询条件
+type DeploymentReportQuery struct {
+	Start        time.Time
+	End          time.Time
+	Bucket       string
+	DeploymentID uint
+	Environment  string
+}
+
+// DORAMetrics DORA 四项指标
+type DORAMetrics struct {
+	Deployments       int     `json:"deployments"`         // 成功部署次数
+	DeploymentsPerDay float64 `json:"deployments_per_day"` // 部署频率
+	LeadTime          float64 `json:"lead_time"`           // 平均变更前置时间（秒），提交时间到部署完成
+	ChangeFailures    int     `json:"change_failures"`     // 失败或被回滚的部署次数
+	Changes           int     `json:"changes"`             // 已完成的部署次数
+	ChangeFailureRate float64 `json:"change_failure_rate"` // 变更失败率
+	Restores          int     `json:"restores"`            // 已恢复的故障次数
+	MTTR              float64 `json:"mttr"`                // 平均恢复时间（秒），故障部署完成到下一次成功运行完成
+}
+
+// ReportBucket 单个时间桶的指标
+type ReportBucket struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	DORAMetrics
+}
+
+// DeploymentMetrics 单个部署的指标
+type DeploymentMetrics struct {
+	DeploymentID uint   `json:"deployment_id"`
+	Name         string `json:"name"`
+	DORAMetrics
+}
+
+// EnvironmentMetrics 单个环境的指标
+type EnvironmentMetrics struct {
+	Environment string `json:"environment"`
+	DORAMetrics
+}
+
+// DeploymentReport 部署报表
+type DeploymentReport struct {
+	Start         time.Time            `json:"start"`
+	End           time.Time            `json:"end"`
+	Bucket        string               `json:"bucket"`
+	Summary       DORAMetrics          `json:"summary"`
+	Buckets       []ReportBucket       `json:"buckets"`
+	ByDeployment  []DeploymentMetrics  `json:"by_deployment"`
+	ByEnvironment []EnvironmentMetrics `json:"by_environment"`
+}
+
+// ReportService 报表服务
+type ReportService struct {
+	db *gorm.DB
+}
+
+// NewReportService 创建报表服务
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// DeploymentReport 统计部署的 DORA 指标
+func (s *ReportService) DeploymentReport(q DeploymentReportQuery) (*DeploymentReport, error) {
+	// 恢复时间需要参考统计区间之后的运行，因此不限制结束时间
+	query := s.db.Where("finished_at >= ? AND status IN ?", q.Start,
+		[]int{model.DeploymentStatusSuccess, model.DeploymentStatusFailed})
+	if q.DeploymentID != 0 {
+		query = query.Where("deployment_id = ?", q.DeploymentID)
+	}
+	if q.Environment != "" {
+		query = query.Where("environment = ?", q.Environment)
+	}
+
+	var runs []model.DeploymentRun
+	if err := query.Order("finished_at ASC").Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("查询部署运行记录失败: %w", err)
+	}
+
+	report := buildDeploymentReport(runs, q)
+
+	if len(report.ByDeployment) > 0 {
+		ids := make([]uint, len(report.ByDeployment))
+		for i, m := range report.ByDeployment {
+			ids[i] = m.DeploymentID
+		}
+		var deployments []model.Deployment
+		if err := s.db.Select("id", "name").Where("id IN ?", ids).Find(&deployments).Error; err != nil {
+			return nil, fmt.Errorf("查询部署失败: %w", err)
+		}
+		names := make(map[uint]string, len(deployments))
+		for _, d := range deployments {
+			names[d.ID] = d.Name
+		}
+		for i := range report.ByDeployment {
+			report.ByDeployment[i].Name = names[report.ByDeployment[i].DeploymentID]
+		}
+	}
+
+	return report, nil
+}
+
+// doraAccumulator 累计单个维度的指标
+type doraAccumulator struct {
+	deployments  int
+	changes      int
+	failures     int
+	restores     int
+	leadCount    int
+	leadTotal    time.Duration
+	restoreTotal time.Duration
+}
+
+func (a *doraAccumulator) metrics(days float64) DORAMetrics {
+	m := DORAMetrics{
+		Deployments:    a.deployments,
+		ChangeFailures: a.failures,
+		Changes:        a.changes,
+		Restores:       a.restores,
+	}
+	if days > 0 {
+		m.DeploymentsPerDay = float64(a.deployments) / days
+	}
+	if a.leadCount > 0 {
+		m.LeadTime = a.leadTotal.Seconds() / float64(a.leadCount)
+	}
+	if a.changes > 0 {
+		m.ChangeFailureRate = float64(a.failures) / float64(a.changes)
+	}
+	if a.restores > 0 {
+		m.MTTR = a.restoreTotal.Seconds() / float64(a.restores)
+	}
+	return m
+}
+
+// buildDeploymentReport 按时间桶、部署和环境汇总指标，runs 需按完成时间升序
+//
+// 只有部署动作计入变更；部署失败或紧随其后的运行是回滚时视为变更失败，
+// 恢复时间为该次部署完成到同一部署下一次成功运行完成的间隔
+func buildDeploymentReport(runs []model.DeploymentRun, q DeploymentReportQuery) *DeploymentReport {
+	buckets := reportBuckets(q.Start, q.End, q.Bucket)
+	bucketAcc := make([]doraAccumulator, len(buckets))
+	var summary doraAccumulator
+	byDeployment := make(map[uint]*doraAccumulator)
+	byEnvironment := make(map[string]*doraAccumulator)
+
+	grouped := make(map[uint][]model.DeploymentRun)
+	for _, run := range runs {
+		grouped[run.DeploymentID] = append(grouped[run.DeploymentID], run)
+	}
+
+	for deploymentID, list := range grouped {
+		for i, run := range list {
+			if run.FinishedAt == nil || run.FinishedAt.Before(q.Start) || !run.FinishedAt.Before(q.End) {
+				continue
+			}
+			if run.Action != model.DeploymentActionDeploy {
+				continue
+			}
+
+			idx := bucketIndex(buckets, *run.FinishedAt)
+			if byDeployment[deploymentID] == nil {
+				byDeployment[deploymentID] = &doraAccumulator{}
+			}
+			if byEnvironment[run.Environment] == nil {
+				byEnvironment[run.Environment] = &doraAccumulator{}
+			}
+			accs := []*doraAccumulator{&summary, byDeployment[deploymentID], byEnvironment[run.Environment]}
+			if idx >= 0 {
+				accs = append(accs, &bucketAcc[idx])
+			}
+
+			failed := run.Status == model.DeploymentStatusFailed ||
+				(i+1 < len(list) && list[i+1].Action == model.DeploymentActionRollback)
+
+			var restore time.Duration
+			restored := false
+			if failed {
+				for _, next := range list[i+1:] {
+					if next.Status == model.DeploymentStatusSuccess && next.FinishedAt != nil {
+						restore = next.FinishedAt.Sub(*run.FinishedAt)
+						restored = true
+						break
+					}
+				}
+			}
+
+			for _, acc := range accs {
+				acc.changes++
+				if run.Status == model.DeploymentStatusSuccess {
+					acc.deployments++
+					if run.CommitAt != nil && run.FinishedAt.After(*run.CommitAt) {
+						acc.leadCount++
+						acc.leadTotal += run.FinishedAt.Sub(*run.CommitAt)
+					}
+				}
+				if failed {
+					acc.failures++
+				}
+				if restored {
+					acc.restores++
+					acc.restoreTotal += restore
+				}
+			}
+		}
+	}
+
+	totalDays := q.End.Sub(q.Start).Hours() / 24
+	report := &DeploymentReport{
+		Start:         q.Start,
+		End:           q.End,
+		Bucket:        q.Bucket,
+		Summary:       summary.metrics(totalDays),
+		Buckets:       make([]ReportBucket, len(buckets)),
+		ByDeployment:  make([]DeploymentMetrics, 0, len(byDeployment)),
+		ByEnvironment: make([]EnvironmentMetrics, 0, len(byEnvironment)),
+	}
+	for i, b := range buckets {
+		report.Buckets[i] = ReportBucket{
+			Start:       b.start,
+			End:         b.end,
+			DORAMetrics: bucketAcc[i].metrics(b.end.Sub(b.start).Hours() / 24),
+		}
+	}
+	for id, acc := range byDeployment {
+		report.ByDeployment = append(report.ByDeployment, DeploymentMetrics{
+			DeploymentID: id,
+			DORAMetrics:  acc.metrics(totalDays),
+		})
+	}
+	sort.Slice(report.ByDeployment, func(i, j int) bool {
+		return report.ByDeployment[i].DeploymentID < report.ByDeployment[j].DeploymentID
+	})
+	for env, acc := range byEnvironment {
+		report.ByEnvironment = append(report.ByEnvironment, EnvironmentMetrics{
+			Environment: env,
+			DORAMetrics: acc.metrics(totalDays),
+		})
+	}
+	sort.Slice(report.ByEnvironment, func(i, j int) bool {
+		return report.ByEnvironment[i].Environment < report.ByEnvironment[j].Environment
+	})
+
+	return report
+}
+
+type timeRange struct {
+	start time.Time
+	end   time.Time
+}
+
+// reportBuckets 将统计区间按粒度切分，首尾时间桶截断到区间边界
+func reportBuckets(start, end time.Time, bucket string) []timeRange {
+	var ranges []timeRange
+	cursor := start
+	for cursor.Before(end) {
+		next := nextBucket(cursor, bucket)
+		if next.After(end) {
+			next = end
+		}
+		ranges = append(ranges, timeRange{start: cursor, end: next})
+		cursor = next
+	}
+	return ranges
+}
+
+// nextBucket 返回 t 所在时间桶的下一个桶起点
+func nextBucket(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch bucket {
+	case ReportBucketWeek:
+		// 以周一为一周的开始
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-offset)
+	case ReportBucketMonth:
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day.AddDate(0, 0, 1)
+	}
+}
+
+// bucketIndex 返回时间所在的时间桶下标，不在任何桶内返回-1
+func bucketIndex(ranges []timeRange, t time.Time) int {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].end.After(t) })
+	if i < len(ranges) && !t.Before(ranges[i].start) {
+		return i
+	}
+	return -1
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDeploymentReport(t *testing.T) {
+	base := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC) // 周一
+	at := func(day, hour int) *time.Time {
+		t := base.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+		return &t
+	}
+	run := func(deploymentID uint, env, action string, status int, finished, commit *time.Time) model.DeploymentRun {
+		return model.DeploymentRun{
+			DeploymentID: deploymentID,
+			Environment:  env,
+			Action:       action,
+			Status:       status,
+			FinishedAt:   finished,
+			CommitAt:     commit,
+		}
+	}
+
+	runs := []model.DeploymentRun{
+		// 部署1：成功 -> 失败 -> 2小时后恢复
+		run(1, "prod", model.DeploymentActionDeploy, model.DeploymentStatusSuccess, at(0, 10), at(0, 8)),
+		run(1, "prod", model.DeploymentActionDeploy, model.DeploymentStatusFailed, at(1, 10), nil),
+		run(1, "prod", model.DeploymentActionDeploy, model.DeploymentStatusSuccess, at(1, 12), at(1, 11)),
+		// 部署2：成功后被回滚，回滚1小时后完成
+		run(2, "test", model.DeploymentActionDeploy, model.DeploymentStatusSuccess, at(8, 10), at(8, 6)),
+		run(2, "test", model.DeploymentActionRollback, model.DeploymentStatusSuccess, at(8, 11), nil),
+	}
+	q := DeploymentReportQuery{Start: base, End: base.AddDate(0, 0, 14), Bucket: ReportBucketWeek}
+
+	report := buildDeploymentReport(runs, q)
+
+	s := report.Summary
+	assert.Equal(t, 4, s.Changes)
+	assert.Equal(t, 3, s.Deployments)
+	assert.Equal(t, 2, s.ChangeFailures)
+	assert.InDelta(t, 0.5, s.ChangeFailureRate, 1e-9)
+	assert.InDelta(t, 3.0/14, s.DeploymentsPerDay, 1e-9)
+	// 前置时间 (2h + 1h + 4h) / 3
+	assert.InDelta(t, (7*time.Hour).Seconds()/3, s.LeadTime, 1e-6)
+	// 恢复时间 (2h + 1h) / 2
+	assert.Equal(t, 2, s.Restores)
+	assert.InDelta(t, (90 * time.Minute).Seconds(), s.MTTR, 1e-6)
+
+	require.Len(t, report.Buckets, 2)
+	assert.Equal(t, 3, report.Buckets[0].Changes)
+	assert.Equal(t, 1, report.Buckets[1].Changes)
+	assert.InDelta(t, 1.0, report.Buckets[1].ChangeFailureRate, 1e-9)
+
+	require.Len(t, report.ByDeployment, 2)
+	assert.Equal(t, uint(1), report.ByDeployment[0].DeploymentID)
+	assert.Equal(t, 3, report.ByDeployment[0].Changes)
+
+	require.Len(t, report.ByEnvironment, 2)
+	assert.Equal(t, "prod", report.ByEnvironment[0].Environment)
+	assert.Equal(t, "test", report.ByEnvironment[1].Environment)
+}
+
+func TestReportBuckets(t *testing.T) {
+	start := time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC) // 周三
+	end := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	months := reportBuckets(start, end, ReportBucketMonth)
+	require.Len(t, months, 3)
+	assert.Equal(t, start, months[0].start)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), months[1].start)
+	assert.Equal(t, end, months[2].end)
+
+	weeks := reportBuckets(start, end, ReportBucketWeek)
+	assert.Equal(t, time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), weeks[0].end)
+	assert.Equal(t, -1, bucketIndex(weeks, end))
+	assert.Equal(t, 0, bucketIndex(weeks, start))
+}
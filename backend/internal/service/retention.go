@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 历史记录清理参数
+const (
+	defaultRetentionInterval  = time.Hour              // 未配置时的清理间隔
+	defaultRetentionBatchSize = 500                    // 未配置时每批删除的记录数
+	retentionBatchPause       = 100 * time.Millisecond // 批次之间的间隔，让出数据库给正常请求
+)
+
+// PurgeReport 一次清理删除的记录数
+type PurgeReport struct {
+	TaskExecutions int64         `json:"task_executions"`
+	OutputChunks   int64         `json:"output_chunks"`
+	DeploymentLogs int64         `json:"deployment_logs"`
+	Duration       time.Duration `json:"duration"`
+}
+
+func (r *PurgeReport) String() string {
+	return fmt.Sprintf("任务执行记录 %d 条，输出分块 %d 个，部署日志 %d 条，耗时 %s",
+		r.TaskExecutions, r.OutputChunks, r.DeploymentLogs, r.Duration.Round(time.Millisecond))
+}
+
+// RetentionService 按保留策略清理任务执行记录和部署日志
+//
+// 清理直接硬删除记录（包括此前已软删除的记录），每批先按主键查询再按主键删除，避免长时间锁表。
+// 部署运行记录用于部署分析，不会被删除，只清理其日志。
+type RetentionService struct {
+	db     *gorm.DB
+	config config.Retention
+}
+
+// NewRetentionService 创建历史记录清理服务
+func NewRetentionService(db *gorm.DB, cfg config.Retention) *RetentionService {
+	return &RetentionService{db: db, config: cfg}
+}
+
+// Interval 清理间隔
+func (s *RetentionService) Interval() time.Duration {
+	if s.config.Interval <= 0 {
+		return defaultRetentionInterval
+	}
+	return time.Duration(s.config.Interval) * time.Minute
+}
+
+func (s *RetentionService) batchSize() int {
+	if s.config.BatchSize <= 0 {
+		return defaultRetentionBatchSize
+	}
+	return s.config.BatchSize
+}
+
+// Purge 按保留策略清理一次，ctx 结束时在当前批次完成后停止
+func (s *RetentionService) Purge(ctx context.Context) (*PurgeReport, error) {
+	start := time.Now()
+	report := &PurgeReport{}
+	defer func() { report.Duration = time.Since(start) }()
+
+	if policyEnabled(s.config.TaskExecutions) {
+		if err := s.purgeTaskExecutions(ctx, report, start); err != nil {
+			return report, err
+		}
+	}
+	if policyEnabled(s.config.DeploymentLogs) {
+		if err := s.purgeDeploymentLogs(ctx, report, start); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// purgeTaskExecutions 按任务从新到旧遍历执行记录，删除超出保留策略的记录及其输出分块
+// 运行中的执行计入保留数量但不会被删除；工作流运行节点引用的执行随工作流运行一直保留，
+// 保留的重试执行所指向的首次执行同样保留，避免运行图和重试链引用已删除的记录
+func (s *RetentionService) purgeTaskExecutions(ctx context.Context, report *PurgeReport, now time.Time) error {
+	var taskIDs []uint
+	if err := s.db.Unscoped().Model(&model.TaskExecution{}).Distinct().Pluck("task_id", &taskIDs).Error; err != nil {
+		return fmt.Errorf("查询执行记录所属任务失败: %w", err)
+	}
+
+	for _, taskID := range taskIDs {
+		tracker := newRetentionTracker(s.config.TaskExecutions, now)
+		pinned := make(map[uint]bool)
+		var lastID uint
+		for {
+			var executions []model.TaskExecution
+			query := s.db.Unscoped().Select("id", "status", "retry_of", "created_at", "deleted_at").Where("task_id = ?", taskID)
+			if lastID > 0 {
+				query = query.Where("id < ?", lastID)
+			}
+			if err := query.Order("id DESC").Limit(s.batchSize()).Find(&executions).Error; err != nil {
+				return fmt.Errorf("查询任务 %d 的执行记录失败: %w", taskID, err)
+			}
+			if len(executions) == 0 {
+				break
+			}
+			lastID = executions[len(executions)-1].ID
+
+			ids := make([]uint, len(executions))
+			for i, e := range executions {
+				ids[i] = e.ID
+			}
+			var referenced []uint
+			err := s.db.Model(&model.WorkflowRunNode{}).Where("execution_id IN ?", ids).Pluck("execution_id", &referenced).Error
+			if err != nil {
+				return fmt.Errorf("查询工作流引用的执行记录失败: %w", err)
+			}
+			for _, id := range referenced {
+				pinned[id] = true
+			}
+
+			if expired := expiredExecutions(executions, tracker, pinned); len(expired) > 0 {
+				n, err := s.deleteBatches(ctx, &model.TaskOutputChunk{}, "execution_id IN ?", expired)
+				report.OutputChunks += n
+				if err != nil {
+					return fmt.Errorf("删除执行输出失败: %w", err)
+				}
+				n, err = s.deleteBatches(ctx, &model.TaskExecution{}, "id IN ?", expired)
+				report.TaskExecutions += n
+				if err != nil {
+					return fmt.Errorf("删除执行记录失败: %w", err)
+				}
+			}
+			if len(executions) < s.batchSize() {
+				break
+			}
+			if err := pause(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// purgeDeploymentLogs 按部署从新到旧遍历运行记录，删除超出保留策略的运行的日志
+// 未关联运行的早期日志只按保留天数清理
+func (s *RetentionService) purgeDeploymentLogs(ctx context.Context, report *PurgeReport, now time.Time) error {
+	policy := s.config.DeploymentLogs
+
+	n, err := s.deleteBatches(ctx, &model.DeploymentLog{}, "deleted_at IS NOT NULL")
+	report.DeploymentLogs += n
+	if err != nil {
+		return fmt.Errorf("删除部署日志失败: %w", err)
+	}
+	if policy.MaxAge > 0 {
+		n, err := s.deleteBatches(ctx, &model.DeploymentLog{}, "run_id = 0 AND created_at < ?", now.AddDate(0, 0, -policy.MaxAge))
+		report.DeploymentLogs += n
+		if err != nil {
+			return fmt.Errorf("删除部署日志失败: %w", err)
+		}
+	}
+
+	var deploymentIDs []uint
+	if err := s.db.Unscoped().Model(&model.DeploymentRun{}).Distinct().Pluck("deployment_id", &deploymentIDs).Error; err != nil {
+		return fmt.Errorf("查询部署运行记录失败: %w", err)
+	}
+
+	for _, deploymentID := range deploymentIDs {
+		tracker := newRetentionTracker(policy, now)
+		var lastID uint
+		for {
+			var runs []model.DeploymentRun
+			query := s.db.Unscoped().Select("id", "status", "created_at").Where("deployment_id = ?", deploymentID)
+			if lastID > 0 {
+				query = query.Where("id < ?", lastID)
+			}
+			if err := query.Order("id DESC").Limit(s.batchSize()).Find(&runs).Error; err != nil {
+				return fmt.Errorf("查询部署 %d 的运行记录失败: %w", deploymentID, err)
+			}
+			if len(runs) == 0 {
+				break
+			}
+			lastID = runs[len(runs)-1].ID
+
+			var expired []uint
+			for _, r := range runs {
+				switch {
+				case r.Status == model.DeploymentStatusPending || r.Status == model.DeploymentStatusRunning:
+					tracker.keep(false, r.CreatedAt)
+				case !tracker.keep(r.Status == model.DeploymentStatusFailed, r.CreatedAt):
+					expired = append(expired, r.ID)
+				}
+			}
+			if len(expired) > 0 {
+				n, err := s.deleteBatches(ctx, &model.DeploymentLog{}, "run_id IN ?", expired)
+				report.DeploymentLogs += n
+				if err != nil {
+					return fmt.Errorf("删除部署日志失败: %w", err)
+				}
+			}
+			if len(runs) < s.batchSize() {
+				break
+			}
+			if err := pause(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expiredExecutions 按从新到旧的顺序返回一批执行记录中需要删除的记录
+// pinned 中的记录不计入保留策略且不删除，保留的重试执行将其首次执行加入 pinned
+func expiredExecutions(executions []model.TaskExecution, tracker *retentionTracker, pinned map[uint]bool) []uint {
+	var expired []uint
+	for _, e := range executions {
+		keep := true
+		switch {
+		case pinned[e.ID]:
+		case e.DeletedAt.Valid:
+			keep = false
+		case e.Status == model.TaskExecutionRunning:
+			tracker.keep(false, e.CreatedAt)
+		default:
+			keep = tracker.keep(e.Status == model.TaskExecutionFailed || e.Status == model.TaskExecutionTimeout, e.CreatedAt)
+		}
+		if !keep {
+			expired = append(expired, e.ID)
+		} else if e.RetryOf != nil {
+			pinned[*e.RetryOf] = true
+		}
+	}
+	return expired
+}
+
+// deleteBatches 分批硬删除满足条件的记录，返回删除的记录数
+func (s *RetentionService) deleteBatches(ctx context.Context, value interface{}, query string, args ...interface{}) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		if err := s.db.Unscoped().Model(value).Where(query, args...).Limit(s.batchSize()).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		result := s.db.Unscoped().Where("id IN ?", ids).Delete(value)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < s.batchSize() {
+			return total, nil
+		}
+		if err := pause(ctx); err != nil {
+			return total, err
+		}
+	}
+}
+
+// pause 批次之间等待，ctx 结束时返回其错误
+func pause(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(retentionBatchPause):
+		return nil
+	}
+}
+
+// policyEnabled 保留天数和保留数量都不限制时不需要清理
+func policyEnabled(policy config.RetentionPolicy) bool {
+	return policy.MaxAge > 0 || policy.MaxCount > 0
+}
+
+// retentionTracker 按从新到旧的顺序判断同一任务或部署的记录是否保留
+type retentionTracker struct {
+	policy config.RetentionPolicy
+	cutoff time.Time // 早于该时间的记录过期，不限制天数时为零值
+	seen   int
+	failed int
+}
+
+func newRetentionTracker(policy config.RetentionPolicy, now time.Time) *retentionTracker {
+	t := &retentionTracker{policy: policy}
+	if policy.MaxAge > 0 {
+		t.cutoff = now.AddDate(0, 0, -policy.MaxAge)
+	}
+	return t
+}
+
+// keep 记录一条记录并返回是否保留：最近的 KeepFailed 条失败记录总是保留，
+// 其余记录超出 MaxCount 或早于保留天数时删除
+func (t *retentionTracker) keep(failed bool, createdAt time.Time) bool {
+	t.seen++
+	if failed {
+		t.failed++
+		if t.failed <= t.policy.KeepFailed {
+			return true
+		}
+	}
+	if t.policy.MaxCount > 0 && t.seen > t.policy.MaxCount {
+		return false
+	}
+	return t.cutoff.IsZero() || !createdAt.Before(t.cutoff)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionTracker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newRetentionTracker(config.RetentionPolicy{MaxAge: 30, MaxCount: 3, KeepFailed: 1}, now)
+
+	// 从新到旧依次判断
+	assert.True(t, tracker.keep(false, now.AddDate(0, 0, -1)))
+	assert.True(t, tracker.keep(true, now.AddDate(0, 0, -2)))
+	assert.True(t, tracker.keep(false, now.AddDate(0, 0, -3)))
+	// 超出保留数量
+	assert.False(t, tracker.keep(false, now.AddDate(0, 0, -4)))
+	// 超出保留数量的第二条失败记录不再额外保留
+	assert.False(t, tracker.keep(true, now.AddDate(0, 0, -5)))
+
+	tracker = newRetentionTracker(config.RetentionPolicy{MaxAge: 30, KeepFailed: 1}, now)
+	assert.True(t, tracker.keep(false, now.AddDate(0, 0, -29)))
+	assert.False(t, tracker.keep(false, now.AddDate(0, 0, -31)))
+	// 最近一条失败记录即使过期也保留
+	assert.True(t, tracker.keep(true, now.AddDate(0, 0, -60)))
+	assert.False(t, tracker.keep(true, now.AddDate(0, 0, -61)))
+}
+
+func TestPolicyEnabled(t *testing.T) {
+	assert.False(t, policyEnabled(config.RetentionPolicy{}))
+	assert.False(t, policyEnabled(config.RetentionPolicy{KeepFailed: 5}))
+	assert.True(t, policyEnabled(config.RetentionPolicy{MaxCount: 100}))
+	assert.True(t, policyEnabled(config.RetentionPolicy{MaxAge: 30}))
+}
+
+func TestExpiredExecutions(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newRetentionTracker(config.RetentionPolicy{MaxCount: 1}, now)
+	first := uint(3)
+	executions := []model.TaskExecution{
+		{ID: 5, Status: model.TaskExecutionSuccess, RetryOf: &first, CreatedAt: now},
+		{ID: 4, Status: model.TaskExecutionFailed, RetryOf: &first, CreatedAt: now},
+		{ID: 3, Status: model.TaskExecutionFailed, CreatedAt: now},
+		{ID: 2, Status: model.TaskExecutionSuccess, CreatedAt: now},
+		{ID: 1, Status: model.TaskExecutionSuccess, CreatedAt: now},
+	}
+	// 执行2被工作流运行节点引用，执行3是保留的重试执行5的首次执行
+	pinned := map[uint]bool{2: true}
+	assert.Equal(t, []uint{4, 1}, expiredExecutions(executions, tracker, pinned))
+	assert.True(t, pinned[3])
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"devops/internal/model"
+	"devops/pkg/diff"
+	"devops/pkg/ssh"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// diffContext 脚本版本差异中每处变更前后保留的行数
+const diffContext = 3
+
+// ScriptService 脚本库服务
+type ScriptService struct {
+	db *gorm.DB
+}
+
+// NewScriptService 创建脚本库服务
+func NewScriptService(db *gorm.DB) *ScriptService {
+	return &ScriptService{db: db}
+}
+
+// List 获取脚本列表
+func (s *ScriptService) List(page, pageSize int) ([]model.Script, int64, error) {
+	var scripts []model.Script
+	var total int64
+
+	if err := s.db.Model(&model.Script{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询脚本总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := s.db.Order("id DESC").Offset(offset).Limit(pageSize).Find(&scripts).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询脚本列表失败: %w", err)
+	}
+	return scripts, total, nil
+}
+
+// GetByID 根据ID获取脚本
+func (s *ScriptService) GetByID(id uint) (*model.Script, error) {
+	var script model.Script
+	if err := s.db.First(&script, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("脚本不存在")
+		}
+		return nil, fmt.Errorf("查询脚本失败: %w", err)
+	}
+	return &script, nil
+}
+
+// Create 创建脚本及其第一个版本
+func (s *ScriptService) Create(script *model.Script, comment string) error {
+	if err := validateScript(script); err != nil {
+		return err
+	}
+
+	script.LatestVersion = 1
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(script).Error; err != nil {
+			return fmt.Errorf("创建脚本失败: %w", err)
+		}
+		return createScriptVersion(tx, script, comment, script.CreatedBy)
+	})
+}
+
+// Update 更新脚本，解释器、内容或参数定义变化时生成新版本，comment 为版本变更说明
+// 只修改名称和描述不会生成新版本
+func (s *ScriptService) Update(id, userID uint, comment string, updates map[string]interface{}) (*model.Script, error) {
+	var script *model.Script
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定脚本行，保证并发修改时版本号连续
+		var current model.Script
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("脚本不存在")
+			}
+			return fmt.Errorf("查询脚本失败: %w", err)
+		}
+
+		next := current
+		if name, ok := updates["name"].(string); ok {
+			next.Name = name
+		}
+		if interpreter, ok := updates["interpreter"].(string); ok {
+			next.Interpreter = interpreter
+		}
+		if content, ok := updates["content"].(string); ok {
+			next.Content = content
+		}
+		if params, ok := updates["parameters"].([]model.TaskParameter); ok {
+			next.Parameters = params
+			// map 更新不经过 GORM 的序列化器
+			data, err := json.Marshal(params)
+			if err != nil {
+				return fmt.Errorf("序列化参数定义失败: %w", err)
+			}
+			updates["parameters"] = string(data)
+		}
+		if err := validateScript(&next); err != nil {
+			return err
+		}
+
+		changed := next.Interpreter != current.Interpreter || next.Content != current.Content ||
+			!reflect.DeepEqual(next.Parameters, current.Parameters)
+		if changed {
+			next.LatestVersion = current.LatestVersion + 1
+			updates["latest_version"] = next.LatestVersion
+			if err := createScriptVersion(tx, &next, comment, userID); err != nil {
+				return err
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&model.Script{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return fmt.Errorf("更新脚本失败: %w", err)
+			}
+		}
+
+		script = &next
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(script.ID)
+}
+
+// Delete 删除脚本，仍被任务或部署引用时拒绝删除
+func (s *ScriptService) Delete(id uint) error {
+	usage, err := s.Usage(id)
+	if err != nil {
+		return err
+	}
+	if n := len(usage.Tasks) + len(usage.Deployments); n > 0 {
+		return fmt.Errorf("脚本仍被 %d 个任务和 %d 个部署引用", len(usage.Tasks), len(usage.Deployments))
+	}
+
+	result := s.db.Delete(&model.Script{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除脚本失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("脚本不存在")
+	}
+	return nil
+}
+
+// ScriptUsage 引用脚本的任务和部署
+type ScriptUsage struct {
+	Tasks       []ScriptReference `json:"tasks"`
+	Deployments []ScriptReference `json:"deployments"`
+}
+
+// ScriptReference 引用脚本的对象，Version 为0表示跟随最新版本
+type ScriptReference struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// Usage 查询引用脚本的任务和部署，便于修改脚本前评估影响范围
+func (s *ScriptService) Usage(id uint) (*ScriptUsage, error) {
+	usage := &ScriptUsage{Tasks: []ScriptReference{}, Deployments: []ScriptReference{}}
+	err := s.db.Model(&model.Task{}).Select("id", "name", "script_version AS version").
+		Where("script_id = ?", id).Order("id ASC").Scan(&usage.Tasks).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询引用脚本的任务失败: %w", err)
+	}
+	err = s.db.Model(&model.Deployment{}).Select("id", "name", "script_version AS version").
+		Where("script_id = ?", id).Order("id ASC").Scan(&usage.Deployments).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询引用脚本的部署失败: %w", err)
+	}
+	return usage, nil
+}
+
+// ListVersions 获取脚本的版本历史，从新到旧
+func (s *ScriptService) ListVersions(scriptID uint) ([]model.ScriptVersion, error) {
+	var versions []model.ScriptVersion
+	err := s.db.Omit("content").Where("script_id = ?", scriptID).Order("version DESC").Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询脚本版本失败: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion 获取脚本的指定版本，version 为0时返回最新版本
+func (s *ScriptService) GetVersion(scriptID uint, version int) (*model.ScriptVersion, error) {
+	if version == 0 {
+		script, err := s.GetByID(scriptID)
+		if err != nil {
+			return nil, err
+		}
+		version = script.LatestVersion
+	}
+
+	var v model.ScriptVersion
+	if err := s.db.Where("script_id = ? AND version = ?", scriptID, version).First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("脚本 %d 不存在版本 %d", scriptID, version)
+		}
+		return nil, fmt.Errorf("查询脚本版本失败: %w", err)
+	}
+	return &v, nil
+}
+
+// ScriptDiff 脚本两个版本之间的差异
+type ScriptDiff struct {
+	From            int    `json:"from"`
+	To              int    `json:"to"`
+	FromInterpreter string `json:"from_interpreter"`
+	ToInterpreter   string `json:"to_interpreter"`
+	Parameters      bool   `json:"parameters_changed"` // 参数定义是否变化
+	Diff            string `json:"diff"`               // 内容的统一格式差异，相同时为空
+}
+
+// Diff 比较脚本的两个版本
+func (s *ScriptService) Diff(scriptID uint, from, to int) (*ScriptDiff, error) {
+	a, err := s.GetVersion(scriptID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetVersion(scriptID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScriptDiff{
+		From:            a.Version,
+		To:              b.Version,
+		FromInterpreter: a.Interpreter,
+		ToInterpreter:   b.Interpreter,
+		Parameters:      !reflect.DeepEqual(a.Parameters, b.Parameters),
+		Diff:            diff.Unified(fmt.Sprintf("v%d", a.Version), fmt.Sprintf("v%d", b.Version), a.Content, b.Content, diffContext),
+	}, nil
+}
+
+// CheckReference 校验任务或部署对脚本的引用，overrides 为覆盖的参数默认值
+func (s *ScriptService) CheckReference(scriptID uint, version int, overrides map[string]string) error {
+	v, err := s.GetVersion(scriptID, version)
+	if err != nil {
+		return err
+	}
+	_, err = applyScriptOverrides(v.Parameters, overrides)
+	return err
+}
+
+// Render 代入参数生成执行引用脚本的命令，overrides 覆盖脚本参数的默认值，values 为本次执行的取值
+func (s *ScriptService) Render(scriptID uint, version int, overrides, values map[string]string) (*renderedCommand, error) {
+	v, err := s.GetVersion(scriptID, version)
+	if err != nil {
+		return nil, err
+	}
+	return renderScript(v, overrides, values)
+}
+
+// renderScript 代入参数后以脚本的解释器执行脚本内容
+func renderScript(v *model.ScriptVersion, overrides, values map[string]string) (*renderedCommand, error) {
+	params, err := applyScriptOverrides(v.Parameters, overrides)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := renderTemplate(v.Content, params, values, scriptQuote(v.Interpreter))
+	if err != nil {
+		return nil, err
+	}
+	rendered.command = scriptCommand(v.Interpreter, rendered.command)
+	rendered.scriptVersion = v.Version
+	return rendered, nil
+}
+
+// applyScriptOverrides 以引用方覆盖的取值作为脚本参数的默认值
+// 覆盖值以明文保存在引用方，密文参数不能覆盖
+func applyScriptOverrides(params []model.TaskParameter, overrides map[string]string) ([]model.TaskParameter, error) {
+	result := make([]model.TaskParameter, len(params))
+	copy(result, params)
+
+	declared := make(map[string]int, len(params))
+	for i, p := range params {
+		declared[p.Name] = i
+	}
+	for name, value := range overrides {
+		i, ok := declared[name]
+		if !ok {
+			return nil, fmt.Errorf("脚本未声明参数: %s", name)
+		}
+		if result[i].Type == model.TaskParamSecret {
+			return nil, fmt.Errorf("密文参数 %s 不能设置固定取值", name)
+		}
+		if value != "" {
+			if err := checkParamValue(result[i], value); err != nil {
+				return nil, fmt.Errorf("参数 %s 无效: %w", name, err)
+			}
+		}
+		result[i].Default = value
+	}
+	return result, nil
+}
+
+// scriptCommand 生成以解释器执行脚本内容的命令
+func scriptCommand(interpreter, content string) string {
+	switch interpreter {
+	case model.ScriptInterpreterPython:
+		return "python3 -c " + ssh.Quote(content)
+	case model.ScriptInterpreterSh:
+		return "sh -c " + ssh.Quote(content)
+	default:
+		return "bash -c " + ssh.Quote(content)
+	}
+}
+
+// scriptQuote 按解释器转义参数取值：shell 脚本使用 shell 引号，Python 脚本使用字符串字面量
+func scriptQuote(interpreter string) func(string) string {
+	if interpreter == model.ScriptInterpreterPython {
+		return strconv.Quote
+	}
+	return ssh.Quote
+}
+
+// validateScript 校验脚本定义
+func validateScript(script *model.Script) error {
+	if strings.TrimSpace(script.Name) == "" {
+		return errors.New("脚本名称不能为空")
+	}
+	switch script.Interpreter {
+	case model.ScriptInterpreterBash, model.ScriptInterpreterSh, model.ScriptInterpreterPython:
+	default:
+		return fmt.Errorf("不支持的解释器: %s", script.Interpreter)
+	}
+	if strings.TrimSpace(script.Content) == "" {
+		return errors.New("脚本内容不能为空")
+	}
+	return validateTaskParameters(script.Content, script.Parameters)
+}
+
+// createScriptVersion 以脚本的当前内容写入版本记录
+func createScriptVersion(tx *gorm.DB, script *model.Script, comment string, userID uint) error {
+	version := &model.ScriptVersion{
+		ScriptID:    script.ID,
+		Version:     script.LatestVersion,
+		Interpreter: script.Interpreter,
+		Content:     script.Content,
+		Parameters:  script.Parameters,
+		Comment:     comment,
+		CreatedBy:   userID,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return fmt.Errorf("写入脚本版本失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderScript(t *testing.T) {
+	params := []model.TaskParameter{
+		{Name: "dir", Type: model.TaskParamString, Default: "/tmp"},
+		{Name: "days", Type: model.TaskParamInt, Default: "7"},
+		{Name: "token", Type: model.TaskParamSecret},
+	}
+
+	v := &model.ScriptVersion{
+		Version:     3,
+		Interpreter: model.ScriptInterpreterBash,
+		Content:     "find {{dir}} -mtime +{{days}} -delete",
+		Parameters:  params,
+	}
+	r, err := renderScript(v, map[string]string{"dir": "/var/log/app"}, map[string]string{"days": "30"})
+	require.NoError(t, err)
+	assert.Equal(t, `bash -c 'find '\''/var/log/app'\'' -mtime +'\''30'\'' -delete'`, r.command)
+	assert.Equal(t, 3, r.scriptVersion)
+
+	// Python 脚本的取值代入为字符串字面量
+	v = &model.ScriptVersion{
+		Version:     1,
+		Interpreter: model.ScriptInterpreterPython,
+		Content:     "print({{dir}})",
+		Parameters:  params,
+	}
+	r, err = renderScript(v, nil, map[string]string{"dir": `it's "here"`})
+	require.NoError(t, err)
+	assert.Equal(t, `python3 -c 'print("it'\''s \"here\"")'`, r.command)
+
+	_, err = renderScript(v, map[string]string{"unknown": "x"}, nil)
+	assert.ErrorContains(t, err, "脚本未声明参数")
+	_, err = renderScript(v, map[string]string{"token": "x"}, nil)
+	assert.ErrorContains(t, err, "不能设置固定取值")
+	_, err = renderScript(v, map[string]string{"days": "many"}, nil)
+	assert.ErrorContains(t, err, "不是整数")
+}
+
+func TestValidateScript(t *testing.T) {
+	script := &model.Script{Name: "cleanup", Interpreter: model.ScriptInterpreterSh, Content: "rm -rf {{dir}}"}
+	assert.ErrorContains(t, validateScript(script), "未声明")
+
+	script.Parameters = []model.TaskParameter{{Name: "dir", Type: model.TaskParamString, Required: true}}
+	assert.NoError(t, validateScript(script))
+
+	script.Interpreter = "perl"
+	assert.ErrorContains(t, validateScript(script), "不支持的解释器")
+}
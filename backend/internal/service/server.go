@@ -7,6 +7,7 @@ import (
 
 	"devops/internal/model"
 	"devops/pkg/cache"
+	"devops/pkg/ssh"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
@@ -216,4 +217,15 @@ func (s *ServerService) InvalidateServerListCache(ctx context.Context) {
 	// 实际项目中可以维护用户ID列表，这里简化处理
 	// 清除所有可能的服务器列表缓存
 	s.cache.Delete(ctx, "server:list:*")
-}
\ No newline at end of file
+}
+
+// SSHConfig 根据服务器信息构建SSH连接配置
+func SSHConfig(server *model.Server) ssh.Config {
+	return ssh.Config{
+		Host:       server.Host,
+		Port:       server.Port,
+		Username:   server.Username,
+		Password:   server.Password,
+		PrivateKey: server.PrivateKey,
+	}
+}
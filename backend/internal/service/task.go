@@ -0,0 +1,698 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/internal/notify"
+	"devops/pkg/cache"
+	"devops/pkg/cron"
+	"devops/pkg/ssh"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// TaskService 定时任务服务
+type TaskService struct {
+	db       *gorm.DB
+	rdb      *redis.Client
+	cache    *cache.CacheService
+	keys     *cache.CacheKeys
+	config   config.Task
+	scripts  *ScriptService
+	notifier notify.Notifier
+}
+
+// NewTaskService 创建定时任务服务
+func NewTaskService(db *gorm.DB, rdb *redis.Client, cfg config.Task) *TaskService {
+	cacheService := cache.NewCacheService(rdb, "devops")
+	return &TaskService{
+		db:       db,
+		rdb:      rdb,
+		cache:    cacheService,
+		keys:     cache.NewCacheKeys(),
+		config:   cfg,
+		scripts:  NewScriptService(db),
+		notifier: notify.NewService(db, rdb),
+	}
+}
+
+// GetByID 根据ID获取任务
+func (s *TaskService) GetByID(id uint) (*model.Task, error) {
+	var task model.Task
+	err := s.db.Preload("Server").First(&task, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("任务不存在")
+		}
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	return &task, nil
+}
+
+// List 获取任务列表
+func (s *TaskService) List(page, pageSize int) ([]model.Task, int64, error) {
+	var tasks []model.Task
+	var total int64
+
+	if err := s.db.Model(&model.Task{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询任务总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	err := s.db.Preload("Server").Order("id DESC").Offset(offset).Limit(pageSize).Find(&tasks).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询任务列表失败: %w", err)
+	}
+	return tasks, total, nil
+}
+
+// Create 创建任务并加入调度
+func (s *TaskService) Create(task *model.Task) error {
+	if err := ValidateCron(task.CronExpr); err != nil {
+		return err
+	}
+	if _, err := TaskLocation(task); err != nil {
+		return err
+	}
+	if err := validateTaskPolicy(task); err != nil {
+		return err
+	}
+	if err := s.checkCommand(task); err != nil {
+		return err
+	}
+	if err := validateNotifyRules(task.NotifyRules); err != nil {
+		return err
+	}
+	if err := s.checkServer(task.ServerID); err != nil {
+		return err
+	}
+
+	if err := s.db.Create(task).Error; err != nil {
+		return fmt.Errorf("创建任务失败: %w", err)
+	}
+	return s.Schedule(context.Background(), task, time.Now())
+}
+
+// Update 更新任务，调度相关字段变化后重新计算下一次触发时间
+func (s *TaskService) Update(id uint, updates map[string]interface{}) (*model.Task, error) {
+	if expr, ok := updates["cron_expr"].(string); ok {
+		if err := ValidateCron(expr); err != nil {
+			return nil, err
+		}
+	}
+	if tz, ok := updates["timezone"].(string); ok {
+		if _, err := TaskLocation(&model.Task{Timezone: tz}); err != nil {
+			return nil, err
+		}
+	}
+	if codes, ok := updates["retry_exit_codes"].(string); ok {
+		if _, err := parseExitCodes(codes); err != nil {
+			return nil, err
+		}
+	}
+	if serverID, ok := updates["server_id"].(uint); ok {
+		if err := s.checkServer(serverID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkCommandUpdates(id, updates); err != nil {
+		return nil, err
+	}
+	if rules, ok := updates["notify_rules"].([]model.TaskNotifyRule); ok {
+		if err := validateNotifyRules(rules); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(rules)
+		if err != nil {
+			return nil, fmt.Errorf("序列化通知规则失败: %w", err)
+		}
+		updates["notify_rules"] = string(data)
+	}
+
+	result := s.db.Model(&model.Task{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("更新任务失败: %w", result.Error)
+	}
+
+	task, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Schedule(context.Background(), task, time.Now()); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// checkCommand 校验任务的命令或脚本引用：引用脚本时执行脚本，命令和参数定义不再使用
+func (s *TaskService) checkCommand(task *model.Task) error {
+	if task.ScriptID != nil {
+		if len(task.Parameters) > 0 {
+			return errors.New("引用脚本的任务使用脚本的参数定义，不能再定义命令参数")
+		}
+		return s.scripts.CheckReference(*task.ScriptID, task.ScriptVersion, task.ScriptParams)
+	}
+	if strings.TrimSpace(task.Command) == "" {
+		return errors.New("任务需要提供命令或引用脚本")
+	}
+	return validateTaskParameters(task.Command, task.Parameters)
+}
+
+// checkCommandUpdates 命令、参数定义或脚本引用变化时按更新后的任务校验
+// map 更新不经过 GORM 的序列化器，参数定义和脚本参数在此转换为 JSON
+func (s *TaskService) checkCommandUpdates(id uint, updates map[string]interface{}) error {
+	command, commandOK := updates["command"].(string)
+	params, paramsOK := updates["parameters"].([]model.TaskParameter)
+	scriptID, scriptOK := updates["script_id"]
+	version, versionOK := updates["script_version"].(int)
+	scriptParams, scriptParamsOK := updates["script_params"].(map[string]string)
+	if !commandOK && !paramsOK && !scriptOK && !versionOK && !scriptParamsOK {
+		return nil
+	}
+
+	task, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if commandOK {
+		task.Command = command
+	}
+	if paramsOK {
+		task.Parameters = params
+	}
+	if scriptOK {
+		// nil 表示取消引用脚本
+		task.ScriptID, _ = scriptID.(*uint)
+	}
+	if versionOK {
+		task.ScriptVersion = version
+	}
+	if scriptParamsOK {
+		task.ScriptParams = scriptParams
+	}
+	if err := s.checkCommand(task); err != nil {
+		return err
+	}
+
+	if paramsOK {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("序列化参数定义失败: %w", err)
+		}
+		updates["parameters"] = string(data)
+	}
+	if scriptParamsOK {
+		data, err := json.Marshal(scriptParams)
+		if err != nil {
+			return fmt.Errorf("序列化脚本参数失败: %w", err)
+		}
+		updates["script_params"] = string(data)
+	}
+	return nil
+}
+
+// render 代入参数得到本次执行的命令，引用脚本的任务使用脚本版本的内容和参数定义
+func (s *TaskService) render(task *model.Task, values map[string]string) (*renderedCommand, error) {
+	if task.ScriptID != nil {
+		return s.scripts.Render(*task.ScriptID, task.ScriptVersion, task.ScriptParams, values)
+	}
+	return renderCommand(task, values)
+}
+
+// RunNow 立即执行任务，不影响调度计划；params 为本次执行的参数取值
+// 返回首次尝试的执行记录，执行在后台进行
+func (s *TaskService) RunNow(id, userID uint, params map[string]string) (*model.TaskExecution, error) {
+	task, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.render(task, params); err != nil {
+		return nil, err
+	}
+	if task.ConcurrencyPolicy == model.TaskConcurrencyForbid {
+		running, err := s.RunningExecutions(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(running) > 0 {
+			return nil, ErrTaskRunning
+		}
+	}
+
+	created := make(chan *model.TaskExecution, 1)
+	done := make(chan *model.TaskExecution, 1)
+	go func() {
+		done <- s.Execute(context.Background(), task, ExecuteOptions{
+			Trigger:     model.TaskTriggerManual,
+			TriggeredBy: userID,
+			Params:      params,
+			onCreate: func(e *model.TaskExecution) {
+				// 执行过程中会继续修改记录，交给调用方的是副本
+				snapshot := *e
+				created <- &snapshot
+			},
+		})
+	}()
+
+	select {
+	case execution := <-created:
+		return execution, nil
+	case execution := <-done:
+		if execution.ID == 0 {
+			return nil, errors.New(execution.Error)
+		}
+		return execution, nil
+	}
+}
+
+// SetStatus 启用或禁用任务
+func (s *TaskService) SetStatus(id uint, status int) (*model.Task, error) {
+	return s.Update(id, map[string]interface{}{"status": status})
+}
+
+// Delete 删除任务并移出调度队列
+func (s *TaskService) Delete(id uint) error {
+	result := s.db.Delete(&model.Task{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除任务失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("任务不存在")
+	}
+	return s.Unschedule(context.Background(), id)
+}
+
+// ListExecutions 获取任务执行记录，status 为nil、trigger 为空时不过滤
+func (s *TaskService) ListExecutions(taskID uint, status *int, trigger string, page, pageSize int) ([]model.TaskExecution, int64, error) {
+	var executions []model.TaskExecution
+	var total int64
+
+	query := s.db.Model(&model.TaskExecution{}).Where("task_id = ?", taskID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if trigger != "" {
+		query = query.Where("`trigger` = ?", trigger)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询执行记录总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	// 列表不返回输出内容，输出可能很大
+	err := query.Omit("output").Order("id DESC").Offset(offset).Limit(pageSize).Find(&executions).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询执行记录失败: %w", err)
+	}
+	return executions, total, nil
+}
+
+// checkServer 检查服务器是否存在
+func (s *TaskService) checkServer(serverID uint) error {
+	var count int64
+	s.db.Model(&model.Server{}).Where("id = ?", serverID).Count(&count)
+	if count == 0 {
+		return errors.New("服务器不存在")
+	}
+	return nil
+}
+
+// ValidateCron 校验 cron 表达式，并确认其会触发
+func ValidateCron(expr string) error {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return fmt.Errorf("cron 表达式无效: %w", err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return errors.New("cron 表达式无效: 该表达式永远不会触发")
+	}
+	return nil
+}
+
+// PreviewCron 计算 cron 表达式在指定时区从 after 开始的后续 count 次触发时间
+func PreviewCron(expr, timezone string, after time.Time, count int) ([]time.Time, error) {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("cron 表达式无效: %w", err)
+	}
+	loc, err := loadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", timezone)
+	}
+
+	times := make([]time.Time, 0, count)
+	t := after.In(loc)
+	for len(times) < count {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// ListSchedulable 获取所有启用且配置了 cron 表达式的任务
+func (s *TaskService) ListSchedulable() ([]model.Task, error) {
+	var tasks []model.Task
+	err := s.db.Where("status = ? AND cron_expr <> ''", model.TaskStatusEnabled).Find(&tasks).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	return tasks, nil
+}
+
+// TaskLocation 返回任务 cron 表达式使用的时区
+func TaskLocation(task *model.Task) (*time.Location, error) {
+	return cronLocation(task.Timezone)
+}
+
+// TaskSchedule 返回按任务时区计算下一次触发时间的函数，不再触发时返回零值
+func TaskSchedule(task *model.Task) (func(time.Time) time.Time, error) {
+	return cronSchedule(task.CronExpr, task.Timezone)
+}
+
+// cronLocation 加载 cron 表达式使用的时区，为空时使用服务器本地时区
+func cronLocation(timezone string) (*time.Location, error) {
+	loc, err := loadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", timezone)
+	}
+	return loc, nil
+}
+
+// cronSchedule 返回在指定时区解释 cron 表达式的触发时间计算函数
+func cronSchedule(expr, timezone string) (func(time.Time) time.Time, error) {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := cronLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	return func(after time.Time) time.Time {
+		return schedule.Next(after.In(loc))
+	}, nil
+}
+
+// NextFireTime 计算任务在 after 之后的下一次触发时间，返回值位于任务时区
+func NextFireTime(task *model.Task, after time.Time) (time.Time, error) {
+	next, err := TaskSchedule(task)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := next(after)
+	if t.IsZero() {
+		return time.Time{}, errors.New("cron 表达式不会再触发")
+	}
+	return t, nil
+}
+
+// Schedule 计算任务的下一次触发时间并写入调度队列
+// 禁用或没有 cron 表达式的任务会从队列中移除
+func (s *TaskService) Schedule(ctx context.Context, task *model.Task, after time.Time) error {
+	if task.Status != model.TaskStatusEnabled || task.CronExpr == "" {
+		return s.Unschedule(ctx, task.ID)
+	}
+
+	next, err := NextFireTime(task, after)
+	if err != nil {
+		s.Unschedule(ctx, task.ID)
+		return fmt.Errorf("任务 %d 调度失败: %w", task.ID, err)
+	}
+
+	if err := s.cache.AddToSortedSet(ctx, s.keys.TaskNextRun(), float64(next.Unix()), taskMember(task.ID)); err != nil {
+		return fmt.Errorf("写入调度队列失败: %w", err)
+	}
+	task.NextRun = &next
+	return s.db.Model(&model.Task{}).Where("id = ?", task.ID).Update("next_run", next).Error
+}
+
+// EnsureScheduled 任务不在调度队列中时加入，已在队列中的保留原触发时间
+// 主节点切换时据此保留切换期间到期的触发
+func (s *TaskService) EnsureScheduled(ctx context.Context, task *model.Task, now time.Time) error {
+	_, err := s.cache.GetSortedSetScore(ctx, s.keys.TaskNextRun(), taskMember(task.ID))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("查询调度队列失败: %w", err)
+	}
+	return s.Schedule(ctx, task, now)
+}
+
+// Unschedule 将任务从调度队列中移除
+func (s *TaskService) Unschedule(ctx context.Context, taskID uint) error {
+	if err := s.cache.RemoveFromSortedSet(ctx, s.keys.TaskNextRun(), taskMember(taskID)); err != nil {
+		return fmt.Errorf("移除调度队列失败: %w", err)
+	}
+	return s.db.Model(&model.Task{}).Where("id = ?", taskID).Update("next_run", nil).Error
+}
+
+// DueTask 到期的任务触发
+type DueTask struct {
+	TaskID uint
+	FireAt time.Time
+}
+
+// DueTasks 返回到期的任务及其计划触发时间
+func (s *TaskService) DueTasks(ctx context.Context, now time.Time) ([]DueTask, error) {
+	members, err := s.cache.GetSortedSetRangeByScore(ctx, s.keys.TaskNextRun(), 0, float64(now.Unix()), 100)
+	if err != nil {
+		return nil, err
+	}
+	due := make([]DueTask, 0, len(members))
+	for _, m := range members {
+		member, _ := m.Member.(string)
+		id, err := strconv.ParseUint(member, 10, 32)
+		if err != nil {
+			// 无法识别的成员直接丢弃
+			s.cache.RemoveFromSortedSet(ctx, s.keys.TaskNextRun(), m.Member)
+			continue
+		}
+		due = append(due, DueTask{TaskID: uint(id), FireAt: time.Unix(int64(m.Score), 0)})
+	}
+	return due, nil
+}
+
+// AcquireFire 为一次计划触发获取幂等标记，保证每个触发时间在集群内只执行一次
+// leader 为调度主节点的租约值，租约已易主时返回 cache.ErrNotOwner
+func (s *TaskService) AcquireFire(ctx context.Context, taskID uint, fireAt time.Time, leader string) (bool, error) {
+	return s.cache.FencedAdvance(ctx, s.keys.SchedulerLeader(), leader, s.keys.TaskLock(taskID), fireAt.Unix(), cache.TTLTaskLock)
+}
+
+// ExecuteOptions 任务执行选项
+type ExecuteOptions struct {
+	Trigger      string            // 触发方式
+	ScheduledAt  *time.Time        // 计划触发时间，手动执行为空
+	FencingToken int64             // 调度主节点任期令牌，手动执行为0
+	TriggeredBy  uint              // 手动执行的用户
+	Params       map[string]string // 命令参数取值，未提供的使用默认值
+
+	// onCreate 首次尝试的执行记录创建后回调
+	onCreate func(*model.TaskExecution)
+}
+
+// Execute 在任务所属服务器上执行命令并记录执行结果，失败时按任务的重试策略重试
+// 每次尝试都是独立的执行记录，返回最后一次尝试
+func (s *TaskService) Execute(ctx context.Context, task *model.Task, opts ExecuteOptions) *model.TaskExecution {
+	rendered, err := s.render(task, opts.Params)
+	if err != nil {
+		// 参数无效时（例如定时执行缺少必填参数）只记录一次失败
+		now := time.Now()
+		execution := &model.TaskExecution{
+			TaskID:       task.ID,
+			Status:       model.TaskExecutionFailed,
+			Attempt:      1,
+			Trigger:      opts.Trigger,
+			ScheduledAt:  opts.ScheduledAt,
+			FencingToken: opts.FencingToken,
+			TriggeredBy:  opts.TriggeredBy,
+			Error:        err.Error(),
+			StartTime:    now,
+			EndTime:      &now,
+		}
+		s.db.Create(execution)
+		s.notifyResult(task, execution)
+		return execution
+	}
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var retryOf *uint
+	for attempt := 1; ; attempt++ {
+		execution, terminated := s.executeAttempt(ctx, task, rendered, opts, attempt, retryOf)
+		if retryOf == nil && execution.ID != 0 {
+			id := execution.ID
+			retryOf = &id
+		}
+		// 被并发策略终止的执行不再重试
+		if terminated || attempt > task.MaxRetries || !shouldRetry(task, execution) || ctx.Err() != nil {
+			s.notifyResult(task, execution)
+			return execution
+		}
+
+		select {
+		case <-ctx.Done():
+			s.notifyResult(task, execution)
+			return execution
+		case <-time.After(retryDelay(task, attempt, rnd)):
+		}
+	}
+}
+
+// executeAttempt 执行一次尝试，超过任务超时时间时终止远程进程组
+// 执行期间定期写入心跳；执行记录已被 TerminateExecution 结束时返回 terminated 为 true
+func (s *TaskService) executeAttempt(ctx context.Context, task *model.Task, rendered *renderedCommand, opts ExecuteOptions, attempt int, retryOf *uint) (execution *model.TaskExecution, terminated bool) {
+	start := time.Now()
+	execution = &model.TaskExecution{
+		TaskID:        task.ID,
+		Status:        model.TaskExecutionRunning,
+		Attempt:       attempt,
+		RetryOf:       retryOf,
+		Trigger:       opts.Trigger,
+		ScheduledAt:   opts.ScheduledAt,
+		FencingToken:  opts.FencingToken,
+		TriggeredBy:   opts.TriggeredBy,
+		Parameters:    rendered.params,
+		ScriptVersion: rendered.scriptVersion,
+		HeartbeatAt:   &start,
+		StartTime:     start,
+	}
+	if err := s.db.Create(execution).Error; err != nil {
+		execution.Status = model.TaskExecutionFailed
+		execution.Error = fmt.Sprintf("创建执行记录失败: %v", err)
+		return execution, false
+	}
+	if attempt == 1 && opts.onCreate != nil {
+		opts.onCreate(execution)
+	}
+	s.db.Model(&model.Task{}).Where("id = ?", task.ID).Update("last_run", start)
+
+	stopHeartbeat := s.heartbeat(execution.ID)
+	defer stopHeartbeat()
+	stopWatch := s.watchLongRunning(task, execution)
+	defer stopWatch()
+
+	runCtx := ctx
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(task.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	output, finishOutput := s.startOutput(execution.ID, rendered)
+	code, err := s.run(runCtx, task, rendered.command, output, func(pgid int) {
+		execution.PGID = pgid
+		s.db.Model(&model.TaskExecution{}).Where("id = ?", execution.ID).Update("pgid", pgid)
+	})
+	execution.OutputSize, execution.OutputTruncated = finishOutput()
+
+	end := time.Now()
+	execution.EndTime = &end
+	execution.Duration = int(end.Sub(start).Seconds())
+	switch {
+	case err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		execution.Status = model.TaskExecutionTimeout
+		execution.Error = fmt.Sprintf("执行超过 %d 秒，已终止", task.Timeout)
+	case err != nil:
+		execution.Status = model.TaskExecutionFailed
+		execution.Error = err.Error()
+	case code != 0:
+		execution.Status = model.TaskExecutionFailed
+		execution.ExitCode = &code
+		execution.Error = fmt.Sprintf("命令退出码 %d", code)
+	default:
+		execution.Status = model.TaskExecutionSuccess
+		execution.ExitCode = &code
+	}
+
+	// 只更新仍在运行的记录，已被终止的记录保留终止原因，只补充输出大小
+	result := s.db.Model(&model.TaskExecution{}).
+		Where("id = ? AND status = ?", execution.ID, model.TaskExecutionRunning).
+		Updates(map[string]interface{}{
+			"status":           execution.Status,
+			"output_size":      execution.OutputSize,
+			"output_truncated": execution.OutputTruncated,
+			"error":            execution.Error,
+			"exit_code":        execution.ExitCode,
+			"end_time":         end,
+			"duration":         execution.Duration,
+		})
+	if result.Error == nil && result.RowsAffected == 0 {
+		s.db.Model(&model.TaskExecution{}).Where("id = ?", execution.ID).Updates(map[string]interface{}{
+			"output_size":      execution.OutputSize,
+			"output_truncated": execution.OutputTruncated,
+		})
+		var current model.TaskExecution
+		if err := s.db.Select("status", "error", "reason").First(&current, execution.ID).Error; err == nil {
+			execution.Status = current.Status
+			execution.Error = current.Error
+			execution.Reason = current.Reason
+		}
+		return execution, true
+	}
+	return execution, false
+}
+
+// run 通过SSH在独立进程组中执行代入参数后的任务命令，标准输出和错误输出合并记录
+// 远程进程启动后以进程组ID回调 onStart；ctx 结束时先向进程组发送 TERM，宽限期后仍未退出则发送 KILL
+func (s *TaskService) run(ctx context.Context, task *model.Task, command string, output io.Writer, onStart func(pgid int)) (int, error) {
+	server := task.Server
+	if server.ID == 0 {
+		if err := s.db.First(&server, task.ServerID).Error; err != nil {
+			return 0, fmt.Errorf("查询任务服务器失败: %w", err)
+		}
+	}
+
+	client, err := ssh.Dial(ctx, SSHConfig(&server))
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	var pgid int
+	code, err := client.RunGroup(ctx, command, func(id int) {
+		pgid = id
+		if onStart != nil {
+			onStart(id)
+		}
+	}, output, output)
+	if ctx.Err() != nil && pgid > 0 {
+		terminateGroup(client, pgid)
+	}
+	return code, err
+}
+
+// terminateGroup 向远程进程组发送 TERM，宽限期后仍未退出则发送 KILL
+func terminateGroup(client *ssh.Client, pgid int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), killGracePeriod+10*time.Second)
+	defer cancel()
+	if err := client.KillGroup(ctx, pgid, "TERM"); err != nil {
+		return err
+	}
+	// 信号0只检查进程组是否仍存在
+	deadline := time.Now().Add(killGracePeriod)
+	for time.Now().Before(deadline) && client.KillGroup(ctx, pgid, "0") == nil {
+		time.Sleep(500 * time.Millisecond)
+	}
+	// 进程组已退出时 kill 会失败，忽略
+	client.KillGroup(ctx, pgid, "KILL")
+	return nil
+}
+
+// taskMember 任务在调度队列中的成员值
+func taskMember(taskID uint) string {
+	return strconv.FormatUint(uint64(taskID), 10)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"devops/internal/model"
+	"devops/pkg/ssh"
+)
+
+// 执行心跳间隔和超时，超过超时未更新心跳的执行视为执行实例已失联
+const (
+	taskHeartbeatInterval = 20 * time.Second
+	taskHeartbeatTimeout  = time.Minute
+)
+
+// ErrTaskRunning 禁止并发的任务上一次执行仍在运行
+var ErrTaskRunning = errors.New("上一次执行仍在运行")
+
+// DefaultStartingDeadline 任务未配置启动期限时使用的默认值
+const DefaultStartingDeadline = time.Minute
+
+// StartingDeadline 返回任务的启动期限，晚于计划时间超过该期限的触发视为错过
+func StartingDeadline(task *model.Task) time.Duration {
+	if task.StartingDeadline > 0 {
+		return time.Duration(task.StartingDeadline) * time.Second
+	}
+	return DefaultStartingDeadline
+}
+
+// heartbeat 定期刷新执行记录的心跳时间，返回停止函数
+func (s *TaskService) heartbeat(executionID uint) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(taskHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				s.db.Model(&model.TaskExecution{}).
+					Where("id = ? AND status = ?", executionID, model.TaskExecutionRunning).
+					Update("heartbeat_at", now)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// RunningExecutions 获取任务仍在运行的执行记录，心跳超时的记录不计入
+func (s *TaskService) RunningExecutions(taskID uint) ([]model.TaskExecution, error) {
+	var executions []model.TaskExecution
+	err := s.db.Omit("output").
+		Where("task_id = ? AND status = ? AND heartbeat_at > ?", taskID, model.TaskExecutionRunning, time.Now().Add(-taskHeartbeatTimeout)).
+		Find(&executions).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询运行中的执行记录失败: %w", err)
+	}
+	return executions, nil
+}
+
+// RecordSkipped 写入一条跳过的执行记录，reason 说明跳过的原因
+func (s *TaskService) RecordSkipped(task *model.Task, scheduledAt time.Time, reason string, fencingToken int64) error {
+	now := time.Now()
+	execution := &model.TaskExecution{
+		TaskID:       task.ID,
+		Status:       model.TaskExecutionSkipped,
+		Reason:       reason,
+		Trigger:      model.TaskTriggerSchedule,
+		ScheduledAt:  &scheduledAt,
+		FencingToken: fencingToken,
+		StartTime:    now,
+		EndTime:      &now,
+	}
+	if err := s.db.Create(execution).Error; err != nil {
+		return fmt.Errorf("写入跳过记录失败: %w", err)
+	}
+	return nil
+}
+
+// TerminateExecution 终止运行中的执行：结束远程进程组并将记录标记为失败
+// 执行所在实例随后写入结果时发现记录已结束，不会覆盖终止原因，也不会再重试
+func (s *TaskService) TerminateExecution(ctx context.Context, execution *model.TaskExecution, reason string) error {
+	now := time.Now()
+	result := s.db.Model(&model.TaskExecution{}).
+		Where("id = ? AND status = ?", execution.ID, model.TaskExecutionRunning).
+		Updates(map[string]interface{}{
+			"status":   model.TaskExecutionFailed,
+			"reason":   reason,
+			"error":    "执行已被终止: " + reason,
+			"end_time": now,
+			"duration": int(now.Sub(execution.StartTime).Seconds()),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("更新执行记录失败: %w", result.Error)
+	}
+	// 执行已自行结束
+	if result.RowsAffected == 0 || execution.PGID <= 0 {
+		return nil
+	}
+
+	task, err := s.GetByID(execution.TaskID)
+	if err != nil {
+		return err
+	}
+	client, err := ssh.Dial(ctx, SSHConfig(&task.Server))
+	if err != nil {
+		return fmt.Errorf("终止执行 %d 失败: %w", execution.ID, err)
+	}
+	defer client.Close()
+	return terminateGroup(client, execution.PGID)
+}
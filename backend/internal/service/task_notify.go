@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"devops/internal/model"
+	"devops/internal/notify"
+)
+
+// NotifyCheckInterval 检查“长时间没有成功执行”规则的间隔
+const NotifyCheckInterval = time.Minute
+
+// validateNotifyRules 校验任务的通知规则
+func validateNotifyRules(rules []model.TaskNotifyRule) error {
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		if seen[r.Type] {
+			return fmt.Errorf("通知规则重复: %s", r.Type)
+		}
+		seen[r.Type] = true
+
+		switch r.Type {
+		case model.TaskNotifyFailure, model.TaskNotifyRecovery:
+		case model.TaskNotifyConsecutive:
+			if r.Threshold < 2 {
+				return errors.New("连续失败通知的次数至少为2")
+			}
+		case model.TaskNotifyLongRunning, model.TaskNotifyNoSuccess:
+			if r.Threshold <= 0 {
+				return fmt.Errorf("通知规则 %s 需要设置时长（秒）", r.Type)
+			}
+		default:
+			return fmt.Errorf("不支持的通知规则: %s", r.Type)
+		}
+	}
+	return nil
+}
+
+// notifyRule 返回任务指定类型的通知规则
+func notifyRule(task *model.Task, ruleType string) (model.TaskNotifyRule, bool) {
+	for _, r := range task.NotifyRules {
+		if r.Type == ruleType {
+			return r, true
+		}
+	}
+	return model.TaskNotifyRule{}, false
+}
+
+// executionFailed 执行是否以失败结束
+func executionFailed(e *model.TaskExecution) bool {
+	return e.Status == model.TaskExecutionFailed || e.Status == model.TaskExecutionTimeout
+}
+
+// failureStreaks 按触发计算最近连续失败的次数，executions 为从新到旧的已结束执行记录
+// 同一次触发的多次重试只看最后一次尝试；current 为包括最新一次触发在内的连续失败次数，
+// previous 为最新一次触发之前的连续失败次数
+func failureStreaks(executions []model.TaskExecution) (current, previous int) {
+	seen := make(map[uint]bool)
+	triggers := 0
+	counting := true
+	for i := range executions {
+		e := &executions[i]
+		group := e.ID
+		if e.RetryOf != nil {
+			group = *e.RetryOf
+		}
+		if seen[group] {
+			continue
+		}
+		seen[group] = true
+		triggers++
+
+		if !executionFailed(e) {
+			if triggers == 1 {
+				counting = false
+				continue
+			}
+			break
+		}
+		if counting {
+			current++
+		}
+		if triggers > 1 {
+			previous++
+		}
+	}
+	return current, previous
+}
+
+// notifyResult 按任务的通知规则处理一次触发的最终结果
+func (s *TaskService) notifyResult(task *model.Task, execution *model.TaskExecution) {
+	if len(task.NotifyRules) == 0 || execution.ID == 0 || execution.Status == model.TaskExecutionRunning {
+		return
+	}
+
+	failure, hasFailure := notifyRule(task, model.TaskNotifyFailure)
+	consecutive, hasConsecutive := notifyRule(task, model.TaskNotifyConsecutive)
+	_, hasRecovery := notifyRule(task, model.TaskNotifyRecovery)
+	if !hasFailure && !hasConsecutive && !hasRecovery {
+		return
+	}
+
+	// 只需查询足以判断连续失败次数的记录
+	limit := (max(consecutive.Threshold, 1) + 1) * (task.MaxRetries + 1)
+	var executions []model.TaskExecution
+	err := s.db.Select("id", "status", "retry_of").
+		Where("task_id = ? AND id <= ? AND status IN ?", task.ID, execution.ID,
+			[]int{model.TaskExecutionSuccess, model.TaskExecutionFailed, model.TaskExecutionTimeout}).
+		Order("id DESC").Limit(limit).Find(&executions).Error
+	if err != nil {
+		log.Printf("查询任务 %d 的执行记录失败: %v", task.ID, err)
+		return
+	}
+	current, previous := failureStreaks(executions)
+
+	ctx := context.Background()
+	if executionFailed(execution) {
+		if hasFailure {
+			s.notify(ctx, task, failure.Type, notify.LevelWarning,
+				fmt.Sprintf("任务 %s 执行失败", task.Name),
+				fmt.Sprintf("执行 %d 失败: %s", execution.ID, execution.Error))
+		}
+		if hasConsecutive && current == consecutive.Threshold {
+			s.notify(ctx, task, consecutive.Type, notify.LevelCritical,
+				fmt.Sprintf("任务 %s 连续 %d 次执行失败", task.Name, current),
+				fmt.Sprintf("最近一次执行 %d 失败: %s", execution.ID, execution.Error))
+		}
+		return
+	}
+	if hasRecovery && execution.Status == model.TaskExecutionSuccess && previous > 0 {
+		s.notify(ctx, task, model.TaskNotifyRecovery, notify.LevelInfo,
+			fmt.Sprintf("任务 %s 已恢复", task.Name),
+			fmt.Sprintf("连续 %d 次失败后，执行 %d 成功", previous, execution.ID))
+	}
+}
+
+// watchLongRunning 执行超过规则时长仍未结束时发送通知，返回停止函数
+func (s *TaskService) watchLongRunning(task *model.Task, execution *model.TaskExecution) func() {
+	rule, ok := notifyRule(task, model.TaskNotifyLongRunning)
+	if !ok {
+		return func() {}
+	}
+	limit := time.Duration(rule.Threshold) * time.Second
+	timer := time.AfterFunc(limit, func() {
+		s.notify(context.Background(), task, rule.Type, notify.LevelWarning,
+			fmt.Sprintf("任务 %s 执行时间过长", task.Name),
+			fmt.Sprintf("执行 %d 已运行超过 %s 仍未结束", execution.ID, limit))
+	})
+	return func() { timer.Stop() }
+}
+
+// CheckNoSuccess 检查配置了“长时间没有成功执行”规则的任务，超过时长没有成功执行时发送通知
+// 同一任务在每个时长内最多通知一次，由调度主节点定期调用
+func (s *TaskService) CheckNoSuccess(ctx context.Context, now time.Time) error {
+	var tasks []model.Task
+	err := s.db.Where("status = ? AND notify_rules LIKE ?", model.TaskStatusEnabled, "%"+model.TaskNotifyNoSuccess+"%").
+		Find(&tasks).Error
+	if err != nil {
+		return fmt.Errorf("查询任务失败: %w", err)
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		rule, ok := notifyRule(task, model.TaskNotifyNoSuccess)
+		if !ok {
+			continue
+		}
+
+		// 从未成功过的任务从创建时间开始计算
+		last := task.CreatedAt
+		var lastSuccess model.TaskExecution
+		err := s.db.Select("end_time").
+			Where("task_id = ? AND status = ?", task.ID, model.TaskExecutionSuccess).
+			Order("id DESC").Limit(1).Find(&lastSuccess).Error
+		if err != nil {
+			return fmt.Errorf("查询任务 %d 最近一次成功执行失败: %w", task.ID, err)
+		}
+		if lastSuccess.EndTime != nil {
+			last = *lastSuccess.EndTime
+		}
+
+		window := time.Duration(rule.Threshold) * time.Second
+		if now.Sub(last) < window {
+			continue
+		}
+		ok, err = s.cache.SetNX(ctx, s.keys.TaskNoSuccessNotified(task.ID), last.Unix(), window)
+		if err != nil || !ok {
+			continue
+		}
+		s.notify(ctx, task, rule.Type, notify.LevelCritical,
+			fmt.Sprintf("任务 %s 长时间没有成功执行", task.Name),
+			fmt.Sprintf("最近一次成功执行于 %s，已超过 %s", last.Format(time.RFC3339), window))
+	}
+	return nil
+}
+
+// notify 发送任务通知
+func (s *TaskService) notify(ctx context.Context, task *model.Task, ruleType, level, title, content string) {
+	err := s.notifier.Notify(ctx, notify.Message{
+		Source:  notify.SourceTask,
+		Key:     fmt.Sprintf("task:%d:%s", task.ID, ruleType),
+		Level:   level,
+		Title:   title,
+		Content: content,
+		Labels:  map[string]string{"task_id": fmt.Sprint(task.ID), "task": task.Name, "rule": ruleType},
+		Time:    time.Now(),
+	})
+	if err != nil {
+		log.Printf("发送任务 %d 的通知失败: %v", task.ID, err)
+	}
+}
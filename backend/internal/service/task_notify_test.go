@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureStreaks(t *testing.T) {
+	first := uint(5)
+	failed := func(id uint, retryOf *uint) model.TaskExecution {
+		return model.TaskExecution{ID: id, Status: model.TaskExecutionFailed, RetryOf: retryOf}
+	}
+	success := func(id uint) model.TaskExecution {
+		return model.TaskExecution{ID: id, Status: model.TaskExecutionSuccess}
+	}
+
+	// 最新一次触发重试后仍失败，之前还有一次失败
+	current, previous := failureStreaks([]model.TaskExecution{
+		failed(6, &first), failed(5, nil), {ID: 4, Status: model.TaskExecutionTimeout}, success(3), failed(2, nil),
+	})
+	assert.Equal(t, 2, current)
+	assert.Equal(t, 1, previous)
+
+	// 成功恢复
+	current, previous = failureStreaks([]model.TaskExecution{success(4), failed(3, nil), failed(2, nil), success(1)})
+	assert.Equal(t, 0, current)
+	assert.Equal(t, 2, previous)
+
+	// 重试后成功的触发不算失败
+	retried := success(6)
+	retried.RetryOf = &first
+	current, previous = failureStreaks([]model.TaskExecution{retried, failed(5, nil), success(4)})
+	assert.Equal(t, 0, current)
+	assert.Equal(t, 0, previous)
+
+	current, previous = failureStreaks(nil)
+	assert.Equal(t, 0, current)
+	assert.Equal(t, 0, previous)
+}
+
+func TestValidateNotifyRules(t *testing.T) {
+	assert.NoError(t, validateNotifyRules(nil))
+	assert.NoError(t, validateNotifyRules([]model.TaskNotifyRule{
+		{Type: model.TaskNotifyFailure},
+		{Type: model.TaskNotifyConsecutive, Threshold: 3},
+		{Type: model.TaskNotifyRecovery},
+		{Type: model.TaskNotifyLongRunning, Threshold: 600},
+		{Type: model.TaskNotifyNoSuccess, Threshold: 86400},
+	}))
+
+	assert.Error(t, validateNotifyRules([]model.TaskNotifyRule{{Type: model.TaskNotifyFailure}, {Type: model.TaskNotifyFailure}}))
+	assert.Error(t, validateNotifyRules([]model.TaskNotifyRule{{Type: model.TaskNotifyConsecutive, Threshold: 1}}))
+	assert.Error(t, validateNotifyRules([]model.TaskNotifyRule{{Type: model.TaskNotifyLongRunning}}))
+	assert.Error(t, validateNotifyRules([]model.TaskNotifyRule{{Type: "unknown"}}))
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"devops/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 任务输出分块存储参数
+const (
+	defaultMaxTaskOutput = 10 << 20    // 未配置时单次执行保留的最大输出字节数
+	outputChunkSize      = 64 << 10    // 缓冲达到该大小时写入一个分块
+	outputFlushInterval  = time.Second // 缓冲未满时的写入间隔，保证实时输出的延迟
+	outputReadBatch      = 100         // 读取完整输出时每批查询的分块数
+)
+
+// OutputEvent 实时输出频道中的消息，End 为 true 表示执行已结束
+// Marker 为 true 表示省略标记，其序号位于头尾之间，订阅者应按序号替换已显示的标记
+type OutputEvent struct {
+	Seq     int    `json:"seq"`
+	Content string `json:"content,omitempty"`
+	Marker  bool   `json:"marker,omitempty"`
+	End     bool   `json:"end,omitempty"`
+}
+
+// outputStore 输出分块的存储操作，save 以序号覆盖写入，remove 删除指定序号的分块
+type outputStore struct {
+	save    func(seq int, content string)
+	remove  func(seqs []int)
+	publish func(event OutputEvent)
+}
+
+// tailChunk 已写入的尾部分块
+type tailChunk struct {
+	seq  int
+	size int64
+}
+
+// outputWriter 将执行输出按顺序分块写入并发布到实时输出频道
+//
+// 前 headLimit 字节作为头部永久保留；之后的分块属于尾部，尾部超过 tailLimit 时删除最早的分块，
+// 并在头尾之间预留的序号写入省略标记。密文在写入前遮盖，为避免密文跨越分块边界，
+// 缓冲末尾可能属于未完整密文的部分留到下一次写入。
+type outputWriter struct {
+	store     outputStore
+	headLimit int64
+	tailLimit int64
+	mask      func(string) string
+	holdBack  int
+
+	mu        sync.Mutex
+	pending   []byte
+	nextSeq   int
+	headBytes int64
+	tail      []tailChunk
+	tailBytes int64
+	markerSeq int // 省略标记的序号，-1 表示尚未进入尾部
+	dropped   int64
+	total     int64
+}
+
+func newOutputWriter(store outputStore, limit int64, rendered *renderedCommand) *outputWriter {
+	holdBack := 0
+	for _, secret := range rendered.secrets {
+		if len(secret)-1 > holdBack {
+			holdBack = len(secret) - 1
+		}
+	}
+	return &outputWriter{
+		store:     store,
+		headLimit: limit / 2,
+		tailLimit: limit - limit/2,
+		mask:      rendered.mask,
+		holdBack:  holdBack,
+		markerSeq: -1,
+	}
+}
+
+// startOutput 创建执行的输出写入器，返回的 finish 写入剩余输出并通知订阅者执行已结束，
+// 同时返回输出总字节数和是否有省略
+func (s *TaskService) startOutput(executionID uint, rendered *renderedCommand) (w *outputWriter, finish func() (int64, bool)) {
+	limit := s.config.MaxOutputSize
+	if limit <= 0 {
+		limit = defaultMaxTaskOutput
+	}
+	w = newOutputWriter(outputStore{
+		save: func(seq int, content string) {
+			chunk := &model.TaskOutputChunk{ExecutionID: executionID, Seq: seq, Content: content}
+			err := s.db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "execution_id"}, {Name: "seq"}},
+				DoUpdates: clause.AssignmentColumns([]string{"content"}),
+			}).Create(chunk).Error
+			if err != nil {
+				log.Printf("写入执行 %d 的输出失败: %v", executionID, err)
+			}
+		},
+		remove: func(seqs []int) {
+			s.db.Where("execution_id = ? AND seq IN ?", executionID, seqs).Delete(&model.TaskOutputChunk{})
+		},
+		publish: func(event OutputEvent) {
+			data, _ := json.Marshal(event)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			s.cache.Publish(ctx, s.keys.TaskOutputChannel(executionID), string(data))
+		},
+	}, limit, rendered)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(outputFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				w.Flush()
+			}
+		}
+	}()
+
+	return w, func() (int64, bool) {
+		close(done)
+		<-stopped
+		return w.Close()
+	}
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.total += int64(len(p))
+	w.pending = append(w.pending, p...)
+	if len(w.pending) >= outputChunkSize {
+		w.flush(false)
+	}
+	return len(p), nil
+}
+
+// Flush 写入已缓冲的输出
+func (w *outputWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush(false)
+}
+
+// Close 写入剩余输出并发布结束消息，返回输出总字节数和是否有省略
+func (w *outputWriter) Close() (int64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush(true)
+	w.store.publish(OutputEvent{Seq: w.nextSeq, End: true})
+	return w.total, w.dropped > 0
+}
+
+// flush 将缓冲写入分块，final 为 false 时保留末尾可能属于未完整密文的部分
+func (w *outputWriter) flush(final bool) {
+	if len(w.pending) == 0 {
+		return
+	}
+	masked := w.pending
+	if w.mask != nil {
+		masked = []byte(w.mask(string(w.pending)))
+	}
+
+	cut := len(masked)
+	if !final {
+		cut -= w.holdBack
+		// 不在多字节字符中间切分
+		for cut > 0 && cut < len(masked) && !utf8.RuneStart(masked[cut]) {
+			cut--
+		}
+		if cut <= 0 {
+			w.pending = masked
+			return
+		}
+	}
+	content := strings.ToValidUTF8(string(masked[:cut]), "")
+	w.pending = append([]byte(nil), masked[cut:]...)
+
+	if remain := w.headLimit - w.headBytes; remain > 0 {
+		head := content
+		if int64(len(head)) > remain {
+			head = head[:remain]
+			for len(head) > 0 && !utf8.ValidString(head) {
+				head = head[:len(head)-1]
+			}
+		}
+		if head != "" {
+			w.insert(head)
+			w.headBytes += int64(len(head))
+			content = content[len(head):]
+		}
+	}
+	if content == "" {
+		return
+	}
+
+	if w.markerSeq < 0 {
+		w.markerSeq = w.nextSeq
+		w.nextSeq++
+	}
+	seq := w.insert(content)
+	w.tail = append(w.tail, tailChunk{seq: seq, size: int64(len(content))})
+	w.tailBytes += int64(len(content))
+
+	// 至少保留最新的一个分块
+	var removed []int
+	for w.tailBytes > w.tailLimit && len(w.tail) > 1 {
+		removed = append(removed, w.tail[0].seq)
+		w.dropped += w.tail[0].size
+		w.tailBytes -= w.tail[0].size
+		w.tail = w.tail[1:]
+	}
+	if len(removed) > 0 {
+		w.store.remove(removed)
+		marker := fmt.Sprintf("\n...（输出过长，已省略 %d 字节）...\n", w.dropped)
+		w.store.save(w.markerSeq, marker)
+		w.store.publish(OutputEvent{Seq: w.markerSeq, Content: marker, Marker: true})
+	}
+}
+
+// insert 写入一个分块并发布到实时输出频道，返回分块序号
+func (w *outputWriter) insert(content string) int {
+	seq := w.nextSeq
+	w.nextSeq++
+	w.store.save(seq, content)
+	w.store.publish(OutputEvent{Seq: seq, Content: content})
+	return seq
+}
+
+// GetExecution 获取执行记录，不含输出内容
+func (s *TaskService) GetExecution(id uint) (*model.TaskExecution, error) {
+	var execution model.TaskExecution
+	if err := s.db.Omit("output").First(&execution, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("执行记录不存在")
+		}
+		return nil, fmt.Errorf("查询执行记录失败: %w", err)
+	}
+	return &execution, nil
+}
+
+// OutputChunks 按序号返回 afterSeq 之后的输出分块，最多 limit 个
+func (s *TaskService) OutputChunks(executionID uint, afterSeq, limit int) ([]model.TaskOutputChunk, error) {
+	var chunks []model.TaskOutputChunk
+	err := s.db.Where("execution_id = ? AND seq > ?", executionID, afterSeq).
+		Order("seq ASC").Limit(limit).Find(&chunks).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询执行输出失败: %w", err)
+	}
+	return chunks, nil
+}
+
+// WriteOutput 将执行保留的完整输出按顺序写入 w
+func (s *TaskService) WriteOutput(executionID uint, w io.Writer) error {
+	// 分块存储之前的执行输出保存在执行记录中
+	var legacy model.TaskExecution
+	if err := s.db.Select("output").First(&legacy, executionID).Error; err != nil {
+		return fmt.Errorf("查询执行输出失败: %w", err)
+	}
+	if legacy.Output != "" {
+		_, err := io.WriteString(w, legacy.Output)
+		return err
+	}
+
+	after := -1
+	for {
+		chunks, err := s.OutputChunks(executionID, after, outputReadBatch)
+		if err != nil {
+			return err
+		}
+		for _, c := range chunks {
+			if _, err := io.WriteString(w, c.Content); err != nil {
+				return err
+			}
+			after = c.Seq
+		}
+		if len(chunks) < outputReadBatch {
+			return nil
+		}
+	}
+}
+
+// SubscribeOutput 订阅执行的实时输出，ctx 结束或调用返回的 cancel 后停止
+// 返回前订阅已生效，之后发布的输出不会丢失
+func (s *TaskService) SubscribeOutput(ctx context.Context, executionID uint) (<-chan OutputEvent, func(), error) {
+	pubsub := s.cache.Subscribe(ctx, s.keys.TaskOutputChannel(executionID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("订阅执行输出失败: %w", err)
+	}
+
+	events := make(chan OutputEvent, 64)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event OutputEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, func() { pubsub.Close() }, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"devops/internal/model"
+	"devops/pkg/ssh"
+)
+
+// placeholderPattern 命令中的参数占位符，如 {{ db_name }}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// paramNamePattern 参数名称
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// maskedValue 密文参数在执行记录和输出中的替代文本
+const maskedValue = "******"
+
+// validateTaskParameters 校验参数定义，并确认命令中的占位符都已声明
+func validateTaskParameters(command string, params []model.TaskParameter) error {
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !paramNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("无效的参数名称: %q", p.Name)
+		}
+		if declared[p.Name] {
+			return fmt.Errorf("参数名称重复: %s", p.Name)
+		}
+		declared[p.Name] = true
+
+		switch p.Type {
+		case model.TaskParamString, model.TaskParamInt, model.TaskParamSecret:
+		case model.TaskParamEnum:
+			if len(p.Options) == 0 {
+				return fmt.Errorf("枚举参数 %s 缺少可选值", p.Name)
+			}
+		default:
+			return fmt.Errorf("参数 %s 的类型 %q 不支持", p.Name, p.Type)
+		}
+		// 密文不以明文保存在任务定义中
+		if p.Type == model.TaskParamSecret && p.Default != "" {
+			return fmt.Errorf("密文参数 %s 不能设置默认值", p.Name)
+		}
+		if p.Default != "" {
+			if err := checkParamValue(p, p.Default); err != nil {
+				return fmt.Errorf("参数 %s 的默认值无效: %w", p.Name, err)
+			}
+		}
+	}
+
+	for _, m := range placeholderPattern.FindAllStringSubmatch(command, -1) {
+		if !declared[m[1]] {
+			return fmt.Errorf("命令中的占位符 %s 未声明", m[1])
+		}
+	}
+	return nil
+}
+
+// checkParamValue 按参数类型校验取值
+func checkParamValue(p model.TaskParameter, value string) error {
+	switch p.Type {
+	case model.TaskParamInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q 不是整数", value)
+		}
+	case model.TaskParamEnum:
+		for _, option := range p.Options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q 不在可选值 %s 中", value, strings.Join(p.Options, ", "))
+	}
+	return nil
+}
+
+// renderedCommand 代入参数后的命令
+type renderedCommand struct {
+	command       string
+	params        map[string]string // 写入执行记录的取值，密文已遮盖
+	secrets       []string          // 需要在输出中遮盖的密文
+	scriptVersion int               // 引用脚本时使用的脚本版本
+}
+
+// renderCommand 校验参数取值并代入命令，未提供的参数使用默认值
+// 取值经过 shell 转义后整体替换占位符，占位符两侧不需要再加引号
+func renderCommand(task *model.Task, values map[string]string) (*renderedCommand, error) {
+	return renderTemplate(task.Command, task.Parameters, values, ssh.Quote)
+}
+
+// renderTemplate 校验参数取值并以 quote 转义后代入模板中的占位符
+func renderTemplate(template string, params []model.TaskParameter, values map[string]string, quote func(string) string) (*renderedCommand, error) {
+	declared := make(map[string]model.TaskParameter, len(params))
+	for _, p := range params {
+		declared[p.Name] = p
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("未声明的参数: %s", name)
+		}
+	}
+
+	result := &renderedCommand{params: make(map[string]string, len(params))}
+	resolved := make(map[string]string, len(params))
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok {
+			value = p.Default
+		}
+		if value == "" {
+			if p.Required {
+				return nil, fmt.Errorf("缺少参数: %s", p.Name)
+			}
+		} else if err := checkParamValue(p, value); err != nil {
+			return nil, fmt.Errorf("参数 %s 无效: %w", p.Name, err)
+		}
+
+		resolved[p.Name] = value
+		if p.Type == model.TaskParamSecret && value != "" {
+			result.params[p.Name] = maskedValue
+			result.secrets = append(result.secrets, value)
+		} else {
+			result.params[p.Name] = value
+		}
+	}
+
+	result.command = placeholderPattern.ReplaceAllStringFunc(template, func(m string) string {
+		value, ok := resolved[placeholderPattern.FindStringSubmatch(m)[1]]
+		if !ok {
+			// 参数功能之前创建的任务可能包含形似占位符的文本，原样保留
+			return m
+		}
+		return quote(value)
+	})
+	if len(result.params) == 0 {
+		result.params = nil
+	}
+	return result, nil
+}
+
+// mask 遮盖文本中出现的密文
+func (r *renderedCommand) mask(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, maskedValue)
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"devops/internal/model"
+)
+
+// maxRetryDelay 指数退避的最大间隔
+const maxRetryDelay = time.Hour
+
+// killGracePeriod 超时后发送 TERM 到 KILL 之间的等待时间
+const killGracePeriod = 5 * time.Second
+
+// validateTaskPolicy 校验任务的超时、重试和调度策略配置
+func validateTaskPolicy(task *model.Task) error {
+	if task.Timeout < 0 {
+		return errors.New("超时时间不能为负数")
+	}
+	if task.MaxRetries < 0 {
+		return errors.New("重试次数不能为负数")
+	}
+	if task.RetryDelay < 0 {
+		return errors.New("重试间隔不能为负数")
+	}
+	switch task.RetryBackoff {
+	case "", model.TaskBackoffFixed, model.TaskBackoffExponential:
+	default:
+		return fmt.Errorf("不支持的重试退避方式: %s", task.RetryBackoff)
+	}
+	if _, err := parseExitCodes(task.RetryExitCodes); err != nil {
+		return err
+	}
+	switch task.ConcurrencyPolicy {
+	case "", model.TaskConcurrencyAllow, model.TaskConcurrencyForbid, model.TaskConcurrencyReplace:
+	default:
+		return fmt.Errorf("不支持的并发策略: %s", task.ConcurrencyPolicy)
+	}
+	switch task.MissedRunPolicy {
+	case "", model.TaskMissedSkip, model.TaskMissedRunOnce, model.TaskMissedCatchUp:
+	default:
+		return fmt.Errorf("不支持的错过触发策略: %s", task.MissedRunPolicy)
+	}
+	if task.StartingDeadline < 0 {
+		return errors.New("启动期限不能为负数")
+	}
+	return nil
+}
+
+// parseExitCodes 解析逗号分隔的退出码列表
+func parseExitCodes(s string) (map[int]bool, error) {
+	codes := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 0 || code > 255 {
+			return nil, fmt.Errorf("无效的重试退出码: %q", part)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
+
+// shouldRetry 判断执行结果是否需要重试
+// 配置了重试退出码时只重试这些退出码，否则失败和超时都会重试
+func shouldRetry(task *model.Task, execution *model.TaskExecution) bool {
+	if execution.Status != model.TaskExecutionFailed && execution.Status != model.TaskExecutionTimeout {
+		return false
+	}
+	codes, _ := parseExitCodes(task.RetryExitCodes)
+	if len(codes) == 0 {
+		return true
+	}
+	return execution.ExitCode != nil && codes[*execution.ExitCode]
+}
+
+// retryDelay 计算第 attempt 次执行失败后的等待时间
+// 指数退避每次翻倍，并在 [d/2, d] 内随机抖动，避免大量任务同时重试
+func retryDelay(task *model.Task, attempt int, rnd *rand.Rand) time.Duration {
+	base := time.Duration(task.RetryDelay) * time.Second
+	if task.RetryBackoff != model.TaskBackoffExponential || base <= 0 {
+		return base
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rnd.Int63n(int64(half)+1))
+}
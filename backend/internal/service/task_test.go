@@ -0,0 +1,219 @@
+package service
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextFireTime(t *testing.T) {
+	after := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+
+	next, err := NextFireTime(&model.Task{CronExpr: "0 9 * * *", Timezone: "UTC"}, after)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), next)
+
+	// 按任务时区解释 cron 表达式：上海 09:00 即 UTC 01:00，当天已过
+	next, err = NextFireTime(&model.Task{CronExpr: "0 9 * * *", Timezone: "Asia/Shanghai"}, after)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 2, 1, 0, 0, 0, time.UTC), next.UTC())
+	assert.Equal(t, 9, next.Hour())
+
+	_, err = NextFireTime(&model.Task{CronExpr: "0 9 * * *", Timezone: "Mars/Olympus"}, after)
+	assert.Error(t, err)
+
+	_, err = NextFireTime(&model.Task{CronExpr: "0 9 * *"}, after)
+	assert.Error(t, err)
+
+	_, err = NextFireTime(&model.Task{CronExpr: "0 0 31 2 *"}, after)
+	assert.Error(t, err)
+}
+
+// memoryOutput 内存中的输出分块，按序号拼接得到保留的输出
+type memoryOutput struct {
+	chunks map[int]string
+	events []OutputEvent
+}
+
+func (m *memoryOutput) store() outputStore {
+	m.chunks = make(map[int]string)
+	return outputStore{
+		save: func(seq int, content string) { m.chunks[seq] = content },
+		remove: func(seqs []int) {
+			for _, seq := range seqs {
+				delete(m.chunks, seq)
+			}
+		},
+		publish: func(event OutputEvent) { m.events = append(m.events, event) },
+	}
+}
+
+func (m *memoryOutput) String() string {
+	seqs := make([]int, 0, len(m.chunks))
+	for seq := range m.chunks {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	var b strings.Builder
+	for _, seq := range seqs {
+		b.WriteString(m.chunks[seq])
+	}
+	return b.String()
+}
+
+func TestOutputWriter(t *testing.T) {
+	out := &memoryOutput{}
+	w := newOutputWriter(out.store(), 8, &renderedCommand{})
+	for _, line := range []string{"ab", "cd", "ef", "gh", "ij", "kl"} {
+		w.Write([]byte(line))
+		w.Flush()
+	}
+	total, truncated := w.Close()
+	assert.Equal(t, int64(12), total)
+	assert.True(t, truncated)
+	// 保留头部4字节和尾部4字节，中间替换为省略标记
+	assert.Equal(t, "abcd\n...（输出过长，已省略 4 字节）...\nijkl", out.String())
+
+	// 实时输出包含全部内容，省略标记随删除分块发布，最后一条为结束消息
+	var live strings.Builder
+	var markers []OutputEvent
+	for _, e := range out.events {
+		if e.Marker {
+			markers = append(markers, e)
+			continue
+		}
+		live.WriteString(e.Content)
+	}
+	assert.Equal(t, "abcdefghijkl", live.String())
+	require.NotEmpty(t, markers)
+	last := markers[len(markers)-1]
+	assert.Equal(t, 2, last.Seq)
+	assert.Equal(t, "\n...（输出过长，已省略 4 字节）...\n", last.Content)
+	assert.True(t, out.events[len(out.events)-1].End)
+}
+
+func TestOutputWriterMasksSecretAcrossWrites(t *testing.T) {
+	out := &memoryOutput{}
+	rendered := &renderedCommand{secrets: []string{"s3cret"}}
+	w := newOutputWriter(out.store(), 1<<10, rendered)
+	w.Write([]byte("token=s3c"))
+	w.Flush()
+	w.Write([]byte("ret 世界"))
+	total, truncated := w.Close()
+	assert.Equal(t, int64(len("token=s3cret 世界")), total)
+	assert.False(t, truncated)
+	assert.Equal(t, "token=****** 世界", out.String())
+}
+
+func TestValidateCron(t *testing.T) {
+	assert.NoError(t, ValidateCron("*/5 * * * *"))
+	assert.NoError(t, ValidateCron("0 */5 * * * *"))
+
+	err := ValidateCron("0 25 * * *")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "超出范围 0-23")
+
+	err = ValidateCron("0 0 30 2 *")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "永远不会触发")
+}
+
+func TestPreviewCron(t *testing.T) {
+	after := time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC)
+
+	times, err := PreviewCron("0 9 * * *", "Asia/Shanghai", after, 3)
+	require.NoError(t, err)
+	require.Len(t, times, 3)
+	// 上海 09:00 为 UTC 01:00
+	assert.Equal(t, time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC), times[0].UTC())
+	assert.Equal(t, "Asia/Shanghai", times[0].Location().String())
+	assert.Equal(t, 24*time.Hour, times[1].Sub(times[0]))
+
+	_, err = PreviewCron("0 9 * * *", "Nowhere/City", after, 3)
+	assert.Error(t, err)
+}
+
+func TestShouldRetry(t *testing.T) {
+	code := func(c int) *int { return &c }
+
+	task := &model.Task{}
+	assert.True(t, shouldRetry(task, &model.TaskExecution{Status: model.TaskExecutionFailed, ExitCode: code(1)}))
+	assert.True(t, shouldRetry(task, &model.TaskExecution{Status: model.TaskExecutionTimeout}))
+	assert.False(t, shouldRetry(task, &model.TaskExecution{Status: model.TaskExecutionSuccess, ExitCode: code(0)}))
+
+	task.RetryExitCodes = "75, 111"
+	assert.True(t, shouldRetry(task, &model.TaskExecution{Status: model.TaskExecutionFailed, ExitCode: code(75)}))
+	assert.False(t, shouldRetry(task, &model.TaskExecution{Status: model.TaskExecutionFailed, ExitCode: code(1)}))
+	assert.False(t, shouldRetry(task, &model.TaskExecution{Status: model.TaskExecutionTimeout}))
+
+	assert.Error(t, validateTaskPolicy(&model.Task{RetryExitCodes: "1,abc"}))
+	assert.Error(t, validateTaskPolicy(&model.Task{RetryBackoff: "linear"}))
+	assert.NoError(t, validateTaskPolicy(&model.Task{RetryBackoff: model.TaskBackoffExponential, RetryExitCodes: "1,2"}))
+}
+
+func TestRetryDelay(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	fixed := &model.Task{RetryBackoff: model.TaskBackoffFixed, RetryDelay: 30}
+	assert.Equal(t, 30*time.Second, retryDelay(fixed, 1, rnd))
+	assert.Equal(t, 30*time.Second, retryDelay(fixed, 5, rnd))
+
+	exp := &model.Task{RetryBackoff: model.TaskBackoffExponential, RetryDelay: 10}
+	for attempt, max := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 20: time.Hour} {
+		d := retryDelay(exp, attempt, rnd)
+		assert.GreaterOrEqual(t, d, max/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, d, max, "attempt %d", attempt)
+	}
+}
+
+func TestValidateTaskParameters(t *testing.T) {
+	params := []model.TaskParameter{
+		{Name: "db", Type: model.TaskParamString, Required: true},
+		{Name: "keep", Type: model.TaskParamInt, Default: "7"},
+		{Name: "mode", Type: model.TaskParamEnum, Options: []string{"full", "incr"}, Default: "full"},
+		{Name: "password", Type: model.TaskParamSecret},
+	}
+	command := "backup.sh {{db}} --keep {{ keep }} --mode {{mode}} --password {{password}}"
+	assert.NoError(t, validateTaskParameters(command, params))
+
+	assert.Error(t, validateTaskParameters(command+" {{other}}", params))
+	assert.Error(t, validateTaskParameters("", append(params, params[0])))
+	assert.Error(t, validateTaskParameters("", []model.TaskParameter{{Name: "n", Type: model.TaskParamInt, Default: "x"}}))
+	assert.Error(t, validateTaskParameters("", []model.TaskParameter{{Name: "e", Type: model.TaskParamEnum}}))
+	assert.Error(t, validateTaskParameters("", []model.TaskParameter{{Name: "s", Type: model.TaskParamSecret, Default: "x"}}))
+	assert.Error(t, validateTaskParameters("", []model.TaskParameter{{Name: "1x", Type: model.TaskParamString}}))
+}
+
+func TestRenderCommand(t *testing.T) {
+	task := &model.Task{
+		Command: "backup.sh {{db}} --keep {{ keep }} --mode {{mode}} --password {{password}} --format '{{.Name}}'",
+		Parameters: []model.TaskParameter{
+			{Name: "db", Type: model.TaskParamString, Required: true},
+			{Name: "keep", Type: model.TaskParamInt, Default: "7"},
+			{Name: "mode", Type: model.TaskParamEnum, Options: []string{"full", "incr"}, Default: "full"},
+			{Name: "password", Type: model.TaskParamSecret},
+		},
+	}
+
+	r, err := renderCommand(task, map[string]string{"db": "it's", "password": "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, `backup.sh 'it'\''s' --keep '7' --mode 'full' --password 's3cret' --format '{{.Name}}'`, r.command)
+	assert.Equal(t, map[string]string{"db": "it's", "keep": "7", "mode": "full", "password": maskedValue}, r.params)
+	assert.Equal(t, "token=******", r.mask("token=s3cret"))
+
+	_, err = renderCommand(task, nil)
+	assert.Error(t, err, "缺少必填参数")
+	_, err = renderCommand(task, map[string]string{"db": "a", "keep": "x"})
+	assert.Error(t, err)
+	_, err = renderCommand(task, map[string]string{"db": "a", "mode": "diff"})
+	assert.Error(t, err)
+	_, err = renderCommand(task, map[string]string{"db": "a", "unknown": "1"})
+	assert.Error(t, err)
+}
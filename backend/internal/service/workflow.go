@@ -0,0 +1,655 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"devops/internal/config"
+	"devops/internal/model"
+	"devops/pkg/cache"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// WorkflowService 工作流服务
+type WorkflowService struct {
+	db    *gorm.DB
+	cache *cache.CacheService
+	keys  *cache.CacheKeys
+	tasks *TaskService
+}
+
+// NewWorkflowService 创建工作流服务
+func NewWorkflowService(db *gorm.DB, rdb *redis.Client, cfg config.Task) *WorkflowService {
+	return &WorkflowService{
+		db:    db,
+		cache: cache.NewCacheService(rdb, "devops"),
+		keys:  cache.NewCacheKeys(),
+		tasks: NewTaskService(db, rdb, cfg),
+	}
+}
+
+// List 获取工作流列表
+func (s *WorkflowService) List(page, pageSize int) ([]model.Workflow, int64, error) {
+	var workflows []model.Workflow
+	var total int64
+
+	if err := s.db.Model(&model.Workflow{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询工作流总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	err := s.db.Preload("Nodes").Preload("Edges").
+		Offset(offset).Limit(pageSize).Order("id DESC").Find(&workflows).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询工作流列表失败: %w", err)
+	}
+	return workflows, total, nil
+}
+
+// GetByID 根据ID获取工作流及其节点和边
+func (s *WorkflowService) GetByID(id uint) (*model.Workflow, error) {
+	var workflow model.Workflow
+	err := s.db.Preload("Nodes").Preload("Nodes.Task").Preload("Edges").First(&workflow, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("工作流不存在")
+		}
+		return nil, fmt.Errorf("查询工作流失败: %w", err)
+	}
+	return &workflow, nil
+}
+
+// Create 创建工作流并加入调度
+func (s *WorkflowService) Create(workflow *model.Workflow) error {
+	if err := s.validate(workflow); err != nil {
+		return err
+	}
+	if err := s.db.Create(workflow).Error; err != nil {
+		return fmt.Errorf("创建工作流失败: %w", err)
+	}
+	return s.Schedule(context.Background(), workflow, time.Now())
+}
+
+// Update 更新工作流，nodes 不为空时整体替换节点和边
+func (s *WorkflowService) Update(id uint, updates map[string]interface{}, nodes []model.WorkflowNode, edges []model.WorkflowEdge) (*model.Workflow, error) {
+	current, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以更新后的定义整体校验
+	merged := *current
+	if v, ok := updates["cron_expr"].(string); ok {
+		merged.CronExpr = v
+	}
+	if v, ok := updates["timezone"].(string); ok {
+		merged.Timezone = v
+	}
+	if nodes != nil {
+		merged.Nodes, merged.Edges = nodes, edges
+	}
+	if err := s.validate(&merged); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&model.Workflow{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if nodes == nil {
+			return nil
+		}
+		if err := tx.Where("workflow_id = ?", id).Delete(&model.WorkflowNode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workflow_id = ?", id).Delete(&model.WorkflowEdge{}).Error; err != nil {
+			return err
+		}
+		for i := range nodes {
+			nodes[i].ID = 0
+			nodes[i].WorkflowID = id
+		}
+		if err := tx.Create(&nodes).Error; err != nil {
+			return err
+		}
+		if len(edges) == 0 {
+			return nil
+		}
+		for i := range edges {
+			edges[i].ID = 0
+			edges[i].WorkflowID = id
+		}
+		return tx.Create(&edges).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新工作流失败: %w", err)
+	}
+
+	workflow, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Schedule(context.Background(), workflow, time.Now()); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// SetStatus 启用或禁用工作流
+func (s *WorkflowService) SetStatus(id uint, status int) (*model.Workflow, error) {
+	return s.Update(id, map[string]interface{}{"status": status}, nil, nil)
+}
+
+// Delete 删除工作流并移出调度队列
+func (s *WorkflowService) Delete(id uint) error {
+	result := s.db.Delete(&model.Workflow{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除工作流失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("工作流不存在")
+	}
+	return s.Unschedule(context.Background(), id)
+}
+
+// validate 校验工作流的触发配置和图结构
+func (s *WorkflowService) validate(workflow *model.Workflow) error {
+	if workflow.CronExpr != "" {
+		if err := ValidateCron(workflow.CronExpr); err != nil {
+			return err
+		}
+	}
+	if _, err := cronLocation(workflow.Timezone); err != nil {
+		return err
+	}
+	if _, err := buildWorkflowGraph(workflow.Nodes, workflow.Edges); err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	for _, n := range workflow.Nodes {
+		ids[n.TaskID] = true
+	}
+	taskIDs := make([]uint, 0, len(ids))
+	for id := range ids {
+		taskIDs = append(taskIDs, id)
+	}
+	var count int64
+	if err := s.db.Model(&model.Task{}).Where("id IN ?", taskIDs).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询任务失败: %w", err)
+	}
+	if int(count) != len(taskIDs) {
+		return errors.New("工作流引用了不存在的任务")
+	}
+	return nil
+}
+
+// ListRuns 获取工作流的运行记录
+func (s *WorkflowService) ListRuns(workflowID uint, page, pageSize int) ([]model.WorkflowRun, int64, error) {
+	var runs []model.WorkflowRun
+	var total int64
+
+	query := s.db.Model(&model.WorkflowRun{}).Where("workflow_id = ?", workflowID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询运行记录总数失败: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Nodes").Offset(offset).Limit(pageSize).Order("id DESC").Find(&runs).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询运行记录失败: %w", err)
+	}
+	return runs, total, nil
+}
+
+// GetRun 获取运行记录及节点状态
+func (s *WorkflowService) GetRun(runID uint) (*model.WorkflowRun, error) {
+	var run model.WorkflowRun
+	if err := s.db.Preload("Nodes").First(&run, runID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("运行记录不存在")
+		}
+		return nil, fmt.Errorf("查询运行记录失败: %w", err)
+	}
+	return &run, nil
+}
+
+// WorkflowGraphNode 执行图中的节点
+type WorkflowGraphNode struct {
+	Key         string     `json:"key"`
+	TaskID      uint       `json:"task_id"`
+	TaskName    string     `json:"task_name"`
+	Level       int        `json:"level"` // 拓扑层级，根节点为0，供前端分层布局
+	Status      int        `json:"status"`
+	ExecutionID *uint      `json:"execution_id"`
+	Reused      bool       `json:"reused"`
+	StartTime   *time.Time `json:"start_time"`
+	EndTime     *time.Time `json:"end_time"`
+}
+
+// WorkflowRunGraph 运行的执行图，节点附带本次运行的状态
+type WorkflowRunGraph struct {
+	Run   *model.WorkflowRun   `json:"run"`
+	Nodes []WorkflowGraphNode  `json:"nodes"`
+	Edges []model.WorkflowEdge `json:"edges"`
+}
+
+// RunGraph 获取运行的执行图
+func (s *WorkflowService) RunGraph(runID uint) (*WorkflowRunGraph, error) {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := s.GetByID(run.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := buildWorkflowGraph(workflow.Nodes, workflow.Edges)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]model.WorkflowRunNode, len(run.Nodes))
+	for _, n := range run.Nodes {
+		states[n.NodeKey] = n
+	}
+	nodes := run.Nodes
+	run.Nodes = nil
+
+	result := &WorkflowRunGraph{Run: run, Edges: workflow.Edges}
+	for _, key := range graph.order {
+		node := graph.nodes[key]
+		item := WorkflowGraphNode{
+			Key:      key,
+			TaskID:   node.TaskID,
+			TaskName: node.Task.Name,
+			Level:    graph.levels[key],
+		}
+		if state, ok := states[key]; ok {
+			item.Status = state.Status
+			item.ExecutionID = state.ExecutionID
+			item.Reused = state.Reused
+			item.StartTime = state.StartTime
+			item.EndTime = state.EndTime
+		}
+		result.Nodes = append(result.Nodes, item)
+	}
+	// 定义修改后已删除的节点仍保留在执行图中
+	for _, n := range nodes {
+		if _, ok := graph.nodes[n.NodeKey]; !ok {
+			result.Nodes = append(result.Nodes, WorkflowGraphNode{
+				Key:         n.NodeKey,
+				TaskID:      n.TaskID,
+				Status:      n.Status,
+				ExecutionID: n.ExecutionID,
+				Reused:      n.Reused,
+				StartTime:   n.StartTime,
+				EndTime:     n.EndTime,
+			})
+		}
+	}
+	return result, nil
+}
+
+// WorkflowRunOptions 工作流运行选项
+type WorkflowRunOptions struct {
+	Trigger      string
+	ScheduledAt  *time.Time
+	FencingToken int64
+	TriggeredBy  uint
+}
+
+// Start 创建运行记录，所有节点初始为等待状态
+func (s *WorkflowService) Start(workflow *model.Workflow, opts WorkflowRunOptions) (*model.WorkflowRun, error) {
+	graph, err := buildWorkflowGraph(workflow.Nodes, workflow.Edges)
+	if err != nil {
+		return nil, err
+	}
+	run := &model.WorkflowRun{
+		WorkflowID:   workflow.ID,
+		Status:       model.WorkflowRunRunning,
+		Trigger:      opts.Trigger,
+		ScheduledAt:  opts.ScheduledAt,
+		FencingToken: opts.FencingToken,
+		TriggeredBy:  opts.TriggeredBy,
+		StartTime:    time.Now(),
+	}
+	for _, key := range graph.order {
+		run.Nodes = append(run.Nodes, model.WorkflowRunNode{
+			NodeKey: key,
+			TaskID:  graph.nodes[key].TaskID,
+			Status:  model.WorkflowNodePending,
+		})
+	}
+	if err := s.create(workflow, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Trigger 手动运行工作流，异步执行
+func (s *WorkflowService) Trigger(workflowID, userID uint) (*model.WorkflowRun, error) {
+	workflow, err := s.GetByID(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.Start(workflow, WorkflowRunOptions{Trigger: model.TaskTriggerManual, TriggeredBy: userID})
+	if err != nil {
+		return nil, err
+	}
+	go s.Execute(context.Background(), workflow, run)
+	return run, nil
+}
+
+// Rerun 从失败节点重新运行：该节点及其下游重新执行，其余节点沿用原运行的结果
+func (s *WorkflowService) Rerun(runID uint, nodeKey string, userID uint) (*model.WorkflowRun, error) {
+	previous, err := s.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	if previous.Status == model.WorkflowRunRunning {
+		return nil, errors.New("运行尚未结束")
+	}
+	states := make(map[string]model.WorkflowRunNode, len(previous.Nodes))
+	for _, n := range previous.Nodes {
+		states[n.NodeKey] = n
+	}
+	if state, ok := states[nodeKey]; !ok || state.Status != model.WorkflowNodeFailed {
+		return nil, fmt.Errorf("节点 %s 不是失败节点", nodeKey)
+	}
+
+	workflow, err := s.GetByID(previous.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := buildWorkflowGraph(workflow.Nodes, workflow.Edges)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := graph.nodes[nodeKey]; !ok {
+		return nil, fmt.Errorf("节点 %s 已从工作流中删除", nodeKey)
+	}
+
+	reset := graph.descendants(nodeKey)
+	rerunOf := previous.ID
+	run := &model.WorkflowRun{
+		WorkflowID:  workflow.ID,
+		Status:      model.WorkflowRunRunning,
+		Trigger:     model.WorkflowTriggerRerun,
+		RerunOf:     &rerunOf,
+		ScheduledAt: previous.ScheduledAt,
+		TriggeredBy: userID,
+		StartTime:   time.Now(),
+	}
+	for _, key := range graph.order {
+		node := model.WorkflowRunNode{
+			NodeKey: key,
+			TaskID:  graph.nodes[key].TaskID,
+			Status:  model.WorkflowNodePending,
+		}
+		if state, ok := states[key]; ok && !reset[key] && nodeDone(state.Status) {
+			node.Status = state.Status
+			node.ExecutionID = state.ExecutionID
+			node.StartTime = state.StartTime
+			node.EndTime = state.EndTime
+			node.Reused = true
+		}
+		run.Nodes = append(run.Nodes, node)
+	}
+	if err := s.create(workflow, run); err != nil {
+		return nil, err
+	}
+
+	go s.Execute(context.Background(), workflow, run)
+	return run, nil
+}
+
+// RecordSkipped 写入一条跳过的运行记录
+func (s *WorkflowService) RecordSkipped(workflow *model.Workflow, scheduledAt time.Time, reason string, fencingToken int64) error {
+	now := time.Now()
+	run := &model.WorkflowRun{
+		WorkflowID:   workflow.ID,
+		Status:       model.WorkflowRunSkipped,
+		Reason:       reason,
+		Trigger:      model.TaskTriggerSchedule,
+		ScheduledAt:  &scheduledAt,
+		FencingToken: fencingToken,
+		StartTime:    now,
+		EndTime:      &now,
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		return fmt.Errorf("写入跳过记录失败: %w", err)
+	}
+	return nil
+}
+
+// create 写入运行记录及节点状态
+func (s *WorkflowService) create(workflow *model.Workflow, run *model.WorkflowRun) error {
+	if err := s.db.Create(run).Error; err != nil {
+		return fmt.Errorf("创建运行记录失败: %w", err)
+	}
+	s.db.Model(&model.Workflow{}).Where("id = ?", workflow.ID).Update("last_run", run.StartTime)
+	return nil
+}
+
+// nodeResult 节点执行结果
+type nodeResult struct {
+	key       string
+	status    int
+	execution *model.TaskExecution
+}
+
+// Execute 按依赖关系执行运行中的节点，互不依赖的节点并行执行
+// 节点的所有上游结束后，入边条件全部满足时执行，否则跳过；有节点失败时运行记为失败
+func (s *WorkflowService) Execute(ctx context.Context, workflow *model.Workflow, run *model.WorkflowRun) *model.WorkflowRun {
+	graph, err := buildWorkflowGraph(workflow.Nodes, workflow.Edges)
+	if err != nil {
+		log.Printf("工作流 %d 定义无效: %v", workflow.ID, err)
+		s.finish(run, model.WorkflowRunFailed)
+		return run
+	}
+
+	nodes := make(map[string]*model.WorkflowRunNode, len(run.Nodes))
+	status := make(map[string]int, len(run.Nodes))
+	for i := range run.Nodes {
+		nodes[run.Nodes[i].NodeKey] = &run.Nodes[i]
+		status[run.Nodes[i].NodeKey] = run.Nodes[i].Status
+	}
+
+	results := make(chan nodeResult)
+	running := 0
+	for {
+		// 反复推进直到没有新节点可以决定去留，跳过会级联到下游
+		for progressed := true; progressed && ctx.Err() == nil; {
+			progressed = false
+			for _, key := range graph.order {
+				if status[key] != model.WorkflowNodePending {
+					continue
+				}
+				ready, shouldRun := graph.resolve(key, status)
+				if !ready {
+					continue
+				}
+				progressed = true
+				if !shouldRun {
+					status[key] = model.WorkflowNodeSkipped
+					s.updateNode(nodes[key], model.WorkflowNodeSkipped, nil)
+					continue
+				}
+				status[key] = model.WorkflowNodeRunning
+				s.updateNode(nodes[key], model.WorkflowNodeRunning, nil)
+				running++
+				go func(node model.WorkflowNode) {
+					results <- s.executeNode(ctx, node)
+				}(graph.nodes[key])
+			}
+		}
+		if running == 0 {
+			break
+		}
+		r := <-results
+		running--
+		status[r.key] = r.status
+		s.updateNode(nodes[r.key], r.status, r.execution)
+	}
+
+	// 被取消时尚未执行的节点记为跳过
+	result := model.WorkflowRunSuccess
+	for _, key := range graph.order {
+		if status[key] == model.WorkflowNodePending {
+			status[key] = model.WorkflowNodeSkipped
+			s.updateNode(nodes[key], model.WorkflowNodeSkipped, nil)
+			result = model.WorkflowRunFailed
+		}
+		if status[key] == model.WorkflowNodeFailed {
+			result = model.WorkflowRunFailed
+		}
+	}
+	s.finish(run, result)
+	return run
+}
+
+// executeNode 执行节点引用的任务，重试按任务自身的策略进行
+func (s *WorkflowService) executeNode(ctx context.Context, node model.WorkflowNode) nodeResult {
+	task, err := s.tasks.GetByID(node.TaskID)
+	if err != nil {
+		log.Printf("工作流节点 %s 的任务 %d 加载失败: %v", node.Key, node.TaskID, err)
+		return nodeResult{key: node.Key, status: model.WorkflowNodeFailed}
+	}
+	execution := s.tasks.Execute(ctx, task, ExecuteOptions{Trigger: model.TaskTriggerWorkflow})
+	result := nodeResult{key: node.Key, status: model.WorkflowNodeFailed, execution: execution}
+	if execution.Status == model.TaskExecutionSuccess {
+		result.status = model.WorkflowNodeSuccess
+	}
+	return result
+}
+
+// updateNode 更新节点状态
+func (s *WorkflowService) updateNode(node *model.WorkflowRunNode, status int, execution *model.TaskExecution) {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case model.WorkflowNodeRunning:
+		node.StartTime = &now
+		updates["start_time"] = now
+	default:
+		node.EndTime = &now
+		updates["end_time"] = now
+	}
+	if execution != nil && execution.ID != 0 {
+		id := execution.ID
+		node.ExecutionID = &id
+		updates["execution_id"] = id
+	}
+	node.Status = status
+	s.db.Model(&model.WorkflowRunNode{}).Where("id = ?", node.ID).Updates(updates)
+}
+
+// finish 结束运行
+func (s *WorkflowService) finish(run *model.WorkflowRun, status int) {
+	now := time.Now()
+	run.Status = status
+	run.EndTime = &now
+	run.Duration = int(now.Sub(run.StartTime).Seconds())
+	s.db.Model(&model.WorkflowRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"status":   status,
+		"end_time": now,
+		"duration": run.Duration,
+	})
+}
+
+// ListSchedulable 获取所有启用且配置了 cron 表达式的工作流
+func (s *WorkflowService) ListSchedulable() ([]model.Workflow, error) {
+	var workflows []model.Workflow
+	err := s.db.Where("status = ? AND cron_expr <> ''", model.TaskStatusEnabled).Find(&workflows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询工作流失败: %w", err)
+	}
+	return workflows, nil
+}
+
+// Schedule 计算工作流的下一次触发时间并写入调度队列
+// 禁用或没有 cron 表达式的工作流会从队列中移除
+func (s *WorkflowService) Schedule(ctx context.Context, workflow *model.Workflow, after time.Time) error {
+	if workflow.Status != model.TaskStatusEnabled || workflow.CronExpr == "" {
+		return s.Unschedule(ctx, workflow.ID)
+	}
+
+	next, err := cronSchedule(workflow.CronExpr, workflow.Timezone)
+	if err != nil {
+		s.Unschedule(ctx, workflow.ID)
+		return fmt.Errorf("工作流 %d 调度失败: %w", workflow.ID, err)
+	}
+	t := next(after)
+	if t.IsZero() {
+		s.Unschedule(ctx, workflow.ID)
+		return fmt.Errorf("工作流 %d 调度失败: cron 表达式不会再触发", workflow.ID)
+	}
+
+	member := strconv.FormatUint(uint64(workflow.ID), 10)
+	if err := s.cache.AddToSortedSet(ctx, s.keys.WorkflowNextRun(), float64(t.Unix()), member); err != nil {
+		return fmt.Errorf("写入调度队列失败: %w", err)
+	}
+	workflow.NextRun = &t
+	return s.db.Model(&model.Workflow{}).Where("id = ?", workflow.ID).Update("next_run", t).Error
+}
+
+// EnsureScheduled 工作流不在调度队列中时加入，已在队列中的保留原触发时间
+func (s *WorkflowService) EnsureScheduled(ctx context.Context, workflow *model.Workflow, now time.Time) error {
+	member := strconv.FormatUint(uint64(workflow.ID), 10)
+	_, err := s.cache.GetSortedSetScore(ctx, s.keys.WorkflowNextRun(), member)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("查询调度队列失败: %w", err)
+	}
+	return s.Schedule(ctx, workflow, now)
+}
+
+// Unschedule 将工作流从调度队列中移除
+func (s *WorkflowService) Unschedule(ctx context.Context, workflowID uint) error {
+	member := strconv.FormatUint(uint64(workflowID), 10)
+	if err := s.cache.RemoveFromSortedSet(ctx, s.keys.WorkflowNextRun(), member); err != nil {
+		return fmt.Errorf("移除调度队列失败: %w", err)
+	}
+	return s.db.Model(&model.Workflow{}).Where("id = ?", workflowID).Update("next_run", nil).Error
+}
+
+// DueWorkflow 到期的工作流触发
+type DueWorkflow struct {
+	WorkflowID uint
+	FireAt     time.Time
+}
+
+// DueWorkflows 返回到期的工作流及其计划触发时间
+func (s *WorkflowService) DueWorkflows(ctx context.Context, now time.Time) ([]DueWorkflow, error) {
+	members, err := s.cache.GetSortedSetRangeByScore(ctx, s.keys.WorkflowNextRun(), 0, float64(now.Unix()), 100)
+	if err != nil {
+		return nil, err
+	}
+	due := make([]DueWorkflow, 0, len(members))
+	for _, m := range members {
+		member, _ := m.Member.(string)
+		id, err := strconv.ParseUint(member, 10, 32)
+		if err != nil {
+			s.cache.RemoveFromSortedSet(ctx, s.keys.WorkflowNextRun(), m.Member)
+			continue
+		}
+		due = append(due, DueWorkflow{WorkflowID: uint(id), FireAt: time.Unix(int64(m.Score), 0)})
+	}
+	return due, nil
+}
+
+// AcquireFire 为一次计划触发获取幂等标记，语义同 TaskService.AcquireFire
+func (s *WorkflowService) AcquireFire(ctx context.Context, workflowID uint, fireAt time.Time, leader string) (bool, error) {
+	return s.cache.FencedAdvance(ctx, s.keys.SchedulerLeader(), leader, s.keys.WorkflowLock(workflowID), fireAt.Unix(), cache.TTLTaskLock)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"devops/internal/model"
+)
+
+// workflowGraph 校验后的工作流有向无环图
+type workflowGraph struct {
+	nodes    map[string]model.WorkflowNode
+	order    []string // 拓扑序
+	levels   map[string]int
+	incoming map[string][]model.WorkflowEdge
+	outgoing map[string][]model.WorkflowEdge
+}
+
+// buildWorkflowGraph 校验节点和边并构建图，存在重复节点、悬空的边或环时返回错误
+func buildWorkflowGraph(nodes []model.WorkflowNode, edges []model.WorkflowEdge) (*workflowGraph, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("工作流至少需要一个节点")
+	}
+
+	g := &workflowGraph{
+		nodes:    make(map[string]model.WorkflowNode, len(nodes)),
+		levels:   make(map[string]int, len(nodes)),
+		incoming: make(map[string][]model.WorkflowEdge),
+		outgoing: make(map[string][]model.WorkflowEdge),
+	}
+	keys := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Key == "" {
+			return nil, errors.New("节点标识不能为空")
+		}
+		if _, ok := g.nodes[n.Key]; ok {
+			return nil, fmt.Errorf("节点标识重复: %s", n.Key)
+		}
+		g.nodes[n.Key] = n
+		keys = append(keys, n.Key)
+	}
+
+	seen := make(map[[2]string]bool, len(edges))
+	for _, e := range edges {
+		if _, ok := g.nodes[e.From]; !ok {
+			return nil, fmt.Errorf("边引用了不存在的节点: %s", e.From)
+		}
+		if _, ok := g.nodes[e.To]; !ok {
+			return nil, fmt.Errorf("边引用了不存在的节点: %s", e.To)
+		}
+		if e.From == e.To {
+			return nil, fmt.Errorf("节点 %s 不能依赖自身", e.From)
+		}
+		switch e.Condition {
+		case model.WorkflowOnSuccess, model.WorkflowOnFailure, model.WorkflowAlways:
+		default:
+			return nil, fmt.Errorf("不支持的边条件: %s", e.Condition)
+		}
+		pair := [2]string{e.From, e.To}
+		if seen[pair] {
+			return nil, fmt.Errorf("重复的边: %s -> %s", e.From, e.To)
+		}
+		seen[pair] = true
+		g.incoming[e.To] = append(g.incoming[e.To], e)
+		g.outgoing[e.From] = append(g.outgoing[e.From], e)
+	}
+
+	// Kahn 算法求拓扑序，同时计算节点层级供前端布局
+	indegree := make(map[string]int, len(keys))
+	for _, k := range keys {
+		indegree[k] = len(g.incoming[k])
+	}
+	queue := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if indegree[k] == 0 {
+			queue = append(queue, k)
+		}
+	}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		g.order = append(g.order, k)
+		for _, e := range g.outgoing[k] {
+			if g.levels[k]+1 > g.levels[e.To] {
+				g.levels[e.To] = g.levels[k] + 1
+			}
+			indegree[e.To]--
+			if indegree[e.To] == 0 {
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	if len(g.order) != len(keys) {
+		return nil, errors.New("工作流存在循环依赖")
+	}
+	return g, nil
+}
+
+// nodeDone 判断节点是否已结束
+func nodeDone(status int) bool {
+	return status == model.WorkflowNodeSuccess || status == model.WorkflowNodeFailed || status == model.WorkflowNodeSkipped
+}
+
+// edgeSatisfied 判断上游节点的结果是否满足边的条件
+func edgeSatisfied(condition string, upstream int) bool {
+	switch condition {
+	case model.WorkflowOnSuccess:
+		return upstream == model.WorkflowNodeSuccess
+	case model.WorkflowOnFailure:
+		return upstream == model.WorkflowNodeFailed
+	case model.WorkflowAlways:
+		return nodeDone(upstream)
+	}
+	return false
+}
+
+// resolve 判断节点是否可以决定去留：ready 表示所有上游都已结束，
+// run 表示所有入边条件都满足需要执行，否则节点被跳过
+func (g *workflowGraph) resolve(key string, status map[string]int) (ready, run bool) {
+	run = true
+	for _, e := range g.incoming[key] {
+		upstream := status[e.From]
+		if !nodeDone(upstream) {
+			return false, false
+		}
+		if !edgeSatisfied(e.Condition, upstream) {
+			run = false
+		}
+	}
+	return true, run
+}
+
+// descendants 返回节点及其所有下游节点
+func (g *workflowGraph) descendants(key string) map[string]bool {
+	result := map[string]bool{key: true}
+	stack := []string{key}
+	for len(stack) > 0 {
+		k := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, e := range g.outgoing[k] {
+			if !result[e.To] {
+				result[e.To] = true
+				stack = append(stack, e.To)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"devops/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backupWorkflow 备份数据库 → 压缩 → 上传 → 清理，失败时通知
+func backupWorkflow() ([]model.WorkflowNode, []model.WorkflowEdge) {
+	nodes := []model.WorkflowNode{
+		{Key: "dump", TaskID: 1},
+		{Key: "compress", TaskID: 2},
+		{Key: "upload", TaskID: 3},
+		{Key: "cleanup", TaskID: 4},
+		{Key: "notify", TaskID: 5},
+	}
+	edges := []model.WorkflowEdge{
+		{From: "dump", To: "compress", Condition: model.WorkflowOnSuccess},
+		{From: "compress", To: "upload", Condition: model.WorkflowOnSuccess},
+		{From: "upload", To: "cleanup", Condition: model.WorkflowAlways},
+		{From: "dump", To: "notify", Condition: model.WorkflowOnFailure},
+	}
+	return nodes, edges
+}
+
+func TestBuildWorkflowGraph(t *testing.T) {
+	nodes, edges := backupWorkflow()
+	g, err := buildWorkflowGraph(nodes, edges)
+	require.NoError(t, err)
+	assert.Equal(t, "dump", g.order[0])
+	assert.Equal(t, 3, g.levels["cleanup"])
+	assert.Equal(t, 1, g.levels["notify"])
+
+	cases := map[string][]model.WorkflowEdge{
+		"循环依赖": append(edges, model.WorkflowEdge{From: "cleanup", To: "dump", Condition: model.WorkflowAlways}),
+		"悬空的边": {{From: "dump", To: "missing", Condition: model.WorkflowOnSuccess}},
+		"依赖自身": {{From: "dump", To: "dump", Condition: model.WorkflowOnSuccess}},
+		"无效条件": {{From: "dump", To: "compress", Condition: "maybe"}},
+		"重复的边": {edges[0], edges[0]},
+	}
+	for name, e := range cases {
+		_, err := buildWorkflowGraph(nodes, e)
+		assert.Error(t, err, name)
+	}
+
+	_, err = buildWorkflowGraph(append(nodes, model.WorkflowNode{Key: "dump", TaskID: 6}), nil)
+	assert.Error(t, err)
+	_, err = buildWorkflowGraph(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestWorkflowGraphResolve(t *testing.T) {
+	nodes, edges := backupWorkflow()
+	g, err := buildWorkflowGraph(nodes, edges)
+	require.NoError(t, err)
+
+	status := map[string]int{"dump": model.WorkflowNodeRunning}
+	ready, _ := g.resolve("compress", status)
+	assert.False(t, ready)
+
+	// 备份失败：压缩被跳过，通知执行
+	status["dump"] = model.WorkflowNodeFailed
+	ready, run := g.resolve("compress", status)
+	assert.True(t, ready)
+	assert.False(t, run)
+	ready, run = g.resolve("notify", status)
+	assert.True(t, ready)
+	assert.True(t, run)
+
+	// always 边在上游被跳过时同样执行
+	status["upload"] = model.WorkflowNodeSkipped
+	ready, run = g.resolve("cleanup", status)
+	assert.True(t, ready)
+	assert.True(t, run)
+
+	assert.Equal(t, map[string]bool{"compress": true, "upload": true, "cleanup": true}, g.descendants("compress"))
+}
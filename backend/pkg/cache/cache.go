@@ -3,12 +3,17 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrNotOwner 已不再持有锁
+var ErrNotOwner = errors.New("已失去锁的持有权")
+
 // CacheService 缓存服务
 type CacheService struct {
 	client *redis.Client
@@ -141,11 +146,110 @@ func (c *CacheService) GetSortedSetRange(ctx context.Context, key string, start,
 	return c.client.ZRange(ctx, c.buildKey(key), start, stop).Result()
 }
 
+// GetSortedSetScore 获取有序集合成员的分数，成员不存在时返回 redis.Nil
+func (c *CacheService) GetSortedSetScore(ctx context.Context, key string, member string) (float64, error) {
+	return c.client.ZScore(ctx, c.buildKey(key), member).Result()
+}
+
 // RemoveFromSortedSet 从有序集合删除
 func (c *CacheService) RemoveFromSortedSet(ctx context.Context, key string, members ...interface{}) error {
 	return c.client.ZRem(ctx, c.buildKey(key), members...).Err()
 }
 
+// GetSortedSetRangeByScore 获取分数在 [min, max] 内的成员及分数，按分数升序，count 为0时不限制数量
+func (c *CacheService) GetSortedSetRangeByScore(ctx context.Context, key string, min, max float64, count int64) ([]redis.Z, error) {
+	return c.client.ZRangeByScoreWithScores(ctx, c.buildKey(key), &redis.ZRangeBy{
+		Min:   strconv.FormatFloat(min, 'f', -1, 64),
+		Max:   strconv.FormatFloat(max, 'f', -1, 64),
+		Count: count,
+	}).Result()
+}
+
+// SetNX 仅当键不存在时设置，返回是否设置成功
+func (c *CacheService) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, c.buildKey(key), value, expiration).Result()
+}
+
+// GetString 获取原始字符串值
+func (c *CacheService) GetString(ctx context.Context, key string) (string, error) {
+	return c.client.Get(ctx, c.buildKey(key)).Result()
+}
+
+// compareAndDeleteScript 值匹配时才删除键
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// compareAndExpireScript 值匹配时才续期键
+var compareAndExpireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// CompareAndDelete 值匹配时删除键，用于释放自己持有的锁
+func (c *CacheService) CompareAndDelete(ctx context.Context, key string, value string) (bool, error) {
+	n, err := compareAndDeleteScript.Run(ctx, c.client, []string{c.buildKey(key)}, value).Int()
+	return n == 1, err
+}
+
+// CompareAndExpire 值匹配时续期键，用于锁续约
+func (c *CacheService) CompareAndExpire(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	n, err := compareAndExpireScript.Run(ctx, c.client, []string{c.buildKey(key)}, value, expiration.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// fencedAdvanceScript 持有者校验通过且新值大于当前值时写入
+var fencedAdvanceScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return -1
+end
+local current = redis.call("GET", KEYS[2])
+if current and tonumber(current) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+// FencedAdvance 仅当 fenceKey 的值仍为 fenceValue 且 value 大于 key 当前值时写入 value
+// 返回 ErrNotOwner 表示已失去 fenceKey 的持有权；返回 false 表示该值已被写入过
+func (c *CacheService) FencedAdvance(ctx context.Context, fenceKey, fenceValue, key string, value int64, expiration time.Duration) (bool, error) {
+	n, err := fencedAdvanceScript.Run(ctx, c.client,
+		[]string{c.buildKey(fenceKey), c.buildKey(key)},
+		fenceValue, value, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	if n < 0 {
+		return false, ErrNotOwner
+	}
+	return n == 1, nil
+}
+
+// TTL 获取键的剩余过期时间
+func (c *CacheService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.client.PTTL(ctx, c.buildKey(key)).Result()
+}
+
+// Publish 发布消息
+func (c *CacheService) Publish(ctx context.Context, channel string, message interface{}) error {
+	return c.client.Publish(ctx, c.buildKey(channel), message).Err()
+}
+
+// Subscribe 订阅频道
+func (c *CacheService) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	cacheChannels := make([]string, len(channels))
+	for i, channel := range channels {
+		cacheChannels[i] = c.buildKey(channel)
+	}
+	return c.client.Subscribe(ctx, cacheChannels...)
+}
+
 // FlushAll 清空所有缓存（谨慎使用）
 func (c *CacheService) FlushAll(ctx context.Context) error {
 	return c.client.FlushAll(ctx).Err()
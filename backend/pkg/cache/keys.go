@@ -11,19 +11,25 @@ const (
 	PrefixServer     = "server"
 	PrefixDeployment = "deployment"
 	PrefixTask       = "task"
+	PrefixWorkflow   = "workflow"
 	PrefixMetrics    = "metrics"
 	PrefixSession    = "session"
 )
 
 // 缓存过期时间
 const (
-	TTLUserInfo      = 1 * time.Hour      // 用户信息缓存1小时
-	TTLServerList    = 10 * time.Minute   // 服务器列表缓存10分钟
-	TTLServerMetrics = 5 * time.Minute    // 服务器监控数据缓存5分钟
-	TTLDeployStatus  = 30 * time.Minute   // 部署状态缓存30分钟
-	TTLTaskNextRun   = 0                  // 任务执行队列永不过期
-	TTLSession       = 24 * time.Hour     // 会话缓存24小时
-	TTLRefreshToken  = 7 * 24 * time.Hour // 刷新令牌缓存7天
+	TTLUserInfo       = 1 * time.Hour      // 用户信息缓存1小时
+	TTLServerList     = 10 * time.Minute   // 服务器列表缓存10分钟
+	TTLServerMetrics  = 5 * time.Minute    // 服务器监控数据缓存5分钟
+	TTLDeployStatus   = 30 * time.Minute   // 部署状态缓存30分钟
+	TTLDeployLock     = 1 * time.Minute    // 部署锁租约1分钟，执行期间持续续约
+	TTLDeployCancel   = 1 * time.Hour      // 部署取消标记保留1小时
+	TTLTaskNextRun    = 0                  // 任务执行队列永不过期
+	TTLTaskLock       = 24 * time.Hour     // 任务最近一次触发的幂等标记保留24小时
+	TTLSchedulerLease = 10 * time.Second   // 调度器主节点租约10秒
+	TTLNotifyLimit    = 2 * time.Minute    // 通知渠道每分钟发送计数保留2分钟
+	TTLSession        = 24 * time.Hour     // 会话缓存24小时
+	TTLRefreshToken   = 7 * 24 * time.Hour // 刷新令牌缓存7天
 )
 
 // CacheKeys 缓存键生成器
@@ -59,6 +65,11 @@ func (k *CacheKeys) ServerMetrics(serverID uint) string {
 	return fmt.Sprintf("%s:%d", PrefixMetrics, serverID)
 }
 
+// AlertPending 服务器告警规则持续满足条件的状态缓存键
+func (k *CacheKeys) AlertPending(serverID uint) string {
+	return fmt.Sprintf("%s:alert_pending:%d", PrefixMetrics, serverID)
+}
+
 // ServerStatus 服务器状态缓存键
 func (k *CacheKeys) ServerStatus(serverID uint) string {
 	return fmt.Sprintf("%s:status:%d", PrefixServer, serverID)
@@ -74,6 +85,21 @@ func (k *CacheKeys) DeploymentLogs(deploymentID uint) string {
 	return fmt.Sprintf("%s:logs:%d", PrefixDeployment, deploymentID)
 }
 
+// DeploymentLock 部署互斥锁缓存键
+func (k *CacheKeys) DeploymentLock(deploymentID uint) string {
+	return fmt.Sprintf("%s:lock:%d", PrefixDeployment, deploymentID)
+}
+
+// DeploymentRunCancel 部署运行取消标记缓存键
+func (k *CacheKeys) DeploymentRunCancel(runID uint) string {
+	return fmt.Sprintf("%s:run:cancel:%d", PrefixDeployment, runID)
+}
+
+// DeploymentCancelChannel 部署取消通知频道
+func (k *CacheKeys) DeploymentCancelChannel() string {
+	return fmt.Sprintf("%s:cancel", PrefixDeployment)
+}
+
 // TaskNextRun 任务执行队列缓存键
 func (k *CacheKeys) TaskNextRun() string {
 	return fmt.Sprintf("%s:next_run", PrefixTask)
@@ -84,11 +110,46 @@ func (k *CacheKeys) TaskExecution(taskID uint) string {
 	return fmt.Sprintf("%s:execution:%d", PrefixTask, taskID)
 }
 
+// TaskOutputChannel 任务执行实时输出频道
+func (k *CacheKeys) TaskOutputChannel(executionID uint) string {
+	return fmt.Sprintf("%s:output:%d", PrefixTask, executionID)
+}
+
+// TaskNoSuccessNotified 任务“长时间没有成功执行”已通知标记
+func (k *CacheKeys) TaskNoSuccessNotified(taskID uint) string {
+	return fmt.Sprintf("%s:notify:no_success:%d", PrefixTask, taskID)
+}
+
 // TaskLock 任务锁缓存键
 func (k *CacheKeys) TaskLock(taskID uint) string {
 	return fmt.Sprintf("%s:lock:%d", PrefixTask, taskID)
 }
 
+// WorkflowNextRun 工作流触发队列缓存键
+func (k *CacheKeys) WorkflowNextRun() string {
+	return fmt.Sprintf("%s:next_run", PrefixWorkflow)
+}
+
+// WorkflowLock 工作流最近一次触发的幂等标记缓存键
+func (k *CacheKeys) WorkflowLock(workflowID uint) string {
+	return fmt.Sprintf("%s:lock:%d", PrefixWorkflow, workflowID)
+}
+
+// SchedulerLeader 调度器主节点租约缓存键
+func (k *CacheKeys) SchedulerLeader() string {
+	return fmt.Sprintf("%s:scheduler:leader", PrefixTask)
+}
+
+// SchedulerEpoch 调度器主节点任期计数缓存键，自增值作为fencing令牌
+func (k *CacheKeys) SchedulerEpoch() string {
+	return fmt.Sprintf("%s:scheduler:epoch", PrefixTask)
+}
+
+// NotifyRateLimit 通知渠道在某一分钟内的发送计数缓存键，minute 为 Unix 时间的分钟数
+func (k *CacheKeys) NotifyRateLimit(channelID uint, minute int64) string {
+	return fmt.Sprintf("notify:rate_limit:%d:%d", channelID, minute)
+}
+
 // UserSession 用户会话缓存键
 func (k *CacheKeys) UserSession(sessionID string) string {
 	return fmt.Sprintf("%s:session:%s", PrefixSession, sessionID)
@@ -0,0 +1,302 @@
+// Package cron 解析 cron 表达式并计算触发时间
+//
+// 支持标准5字段（分 时 日 月 周）和带秒的6字段（秒 分 时 日 月 周）表达式，
+// 以及 @yearly、@monthly、@weekly、@daily、@hourly 等预定义写法。
+//
+// 夏令时切换按 Vixie cron 的约定处理：限定了小时的计划在本地时间被跳过时于跳变后立即触发，
+// 在本地时间重复时只触发一次；小时为通配的计划按实际经过的时间触发。
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit 查找下一次触发时间的最大跨度，超过视为表达式永不触发（如 2月30日）
+const searchLimit = 5
+
+// field 单个字段的取值范围
+type field struct {
+	name  string
+	min   uint
+	max   uint
+	names map[string]uint
+}
+
+var (
+	secondField = field{name: "秒", min: 0, max: 59}
+	minuteField = field{name: "分", min: 0, max: 59}
+	hourField   = field{name: "时", min: 0, max: 23}
+	domField    = field{name: "日", min: 1, max: 31}
+	monthField  = field{name: "月", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 周字段允许 7 表示周日
+	dowField = field{name: "周", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors 预定义表达式
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Schedule 解析后的 cron 计划，每个字段以位图表示允许的取值
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// 日和周都被限制时按标准 cron 语义取并集
+	domRestricted bool
+	dowRestricted bool
+	// 限定了小时的计划在夏令时切换时需要补偿或去重
+	hourRestricted bool
+}
+
+// Parse 解析 cron 表达式
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("cron 表达式不能为空")
+	}
+	if strings.HasPrefix(expr, "@") {
+		full, ok := descriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("不支持的预定义表达式 %q", expr)
+		}
+		expr = full
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron 表达式应为5个字段（分 时 日 月 周）或6个字段（秒 分 时 日 月 周），实际为%d个", len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.second, err = parseField(fields[0], secondField); err != nil {
+		return nil, err
+	}
+	if s.minute, err = parseField(fields[1], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[2], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[3], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[4], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[5], dowField); err != nil {
+		return nil, err
+	}
+	// 7 与 0 均表示周日
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domRestricted = !isWildcard(fields[3])
+	s.dowRestricted = !isWildcard(fields[5])
+	s.hourRestricted = !isWildcard(fields[2])
+
+	return s, nil
+}
+
+// Next 返回严格晚于 t 的下一次触发时间，按 t 所在时区计算
+// 表达式在合理范围内永不触发时返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// 从下一整秒开始查找
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + searchLimit
+
+	for t.Year() <= limit {
+		if s.hourRestricted && s.skippedBefore(t) {
+			return t
+		}
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = startOfDay(t.Year(), t.Month()+1, 1, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = startOfDay(t.Year(), t.Month(), t.Day()+1, loc)
+			continue
+		}
+		// 时分秒以绝对时长推进，避免夏令时回拨时 time.Date 选中较早的重复时刻导致倒退
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		if s.hourRestricted && repeated(t) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// skippedBefore 判断 t 是否恰好位于夏令时开始的跳变点，且被跳过的本地时间内有应触发的时刻
+func (s *Schedule) skippedBefore(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-time.Second).Zone()
+	if before >= offset {
+		return false
+	}
+	// 以 UTC 表示本地时间，逐秒检查被跳过的区间
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	for w := wall.Add(-time.Duration(offset-before) * time.Second); w.Before(wall); w = w.Add(time.Second) {
+		if s.month&(1<<uint(w.Month())) != 0 && s.dayMatches(w) &&
+			s.hour&(1<<uint(w.Hour())) != 0 && s.minute&(1<<uint(w.Minute())) != 0 && s.second&(1<<uint(w.Second())) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated 判断 t 是否是夏令时结束后第二次出现的本地时间
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	// 时区切换幅度不超过2小时
+	_, earlier := t.Add(-2 * time.Hour).Zone()
+	if earlier <= offset {
+		return false
+	}
+	first := t.Add(-time.Duration(earlier-offset) * time.Second)
+	return first.Hour() == t.Hour() && first.Minute() == t.Minute() && first.Second() == t.Second()
+}
+
+// startOfDay 返回本地日期的第一个时刻
+// 零点因夏令时不存在时 time.Date 可能返回前一天，此时推进到跳变后的整点
+func startOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	want := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	for t.Day() != want.Day() {
+		t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+	}
+	return t
+}
+
+// dayMatches 判断日期是否满足日和周字段
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// isWildcard 判断字段是否不限制取值
+func isWildcard(expr string) bool {
+	return expr == "*" || expr == "?"
+}
+
+// parseField 解析单个字段，支持 *、?、列表、范围和步长
+func parseField(expr string, f field) (uint64, error) {
+	var bitsSet uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parsePart(part, f)
+		if err != nil {
+			return 0, fmt.Errorf("%s字段 %q 无效: %w", f.name, expr, err)
+		}
+		bitsSet |= b
+	}
+	return bitsSet, nil
+}
+
+// parsePart 解析列表中的单项
+func parsePart(part string, f field) (uint64, error) {
+	if part == "" {
+		return 0, fmt.Errorf("存在空的列表项")
+	}
+
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+	step := uint(1)
+	if hasStep {
+		n, err := strconv.ParseUint(stepPart, 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("步长 %q 必须为正整数", stepPart)
+		}
+		step = uint(n)
+	}
+
+	var start, end uint
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		if rangePart == "?" && hasStep {
+			return 0, fmt.Errorf("? 不能使用步长")
+		}
+		start, end = f.min, f.max
+		// 周字段的 * 不包含 7，避免与 0 重复
+		if f.max == 7 {
+			end = 6
+		}
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = parseValue(lo, f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(hi, f); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("范围 %s 的起点大于终点", rangePart)
+		}
+	default:
+		var err error
+		if start, err = parseValue(rangePart, f); err != nil {
+			return 0, err
+		}
+		end = start
+		// a/n 表示从 a 开始到最大值
+		if hasStep {
+			end = f.max
+		}
+	}
+
+	var b uint64
+	for v := start; v <= end; v += step {
+		b |= 1 << v
+	}
+	return b, nil
+}
+
+// parseValue 解析数字或名称并检查范围
+func parseValue(s string, f field) (uint, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析 %q", s)
+	}
+	v := uint(n)
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("取值 %d 超出范围 %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
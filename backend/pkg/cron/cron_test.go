@@ -0,0 +1,102 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"1,,2 * * * *",
+		"@every",
+		"* * * foo *",
+	}
+	for _, expr := range cases {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestNext(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 15, 30, 0, time.UTC) // 周三
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 16, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2024, 1, 31, 10, 15, 40, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"30 2 29 2 *", time.Date(2024, 2, 29, 2, 30, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 2, 4, 12, 0, 0, 0, time.UTC)},
+		// 日和周同时限制时取并集：每月1日或每个周五
+		{"0 0 1 * 5", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"15/20 10 * * *", time.Date(2024, 1, 31, 10, 35, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.expr)
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, c.want, s.Next(base), c.expr)
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 2024-03-10 02:00 EST 跳到 03:00 EDT，02:30 不存在，于跳变后立即触发
+	s, err := Parse("30 2 * * *")
+	require.NoError(t, err)
+	next := s.Next(time.Date(2024, 3, 10, 0, 0, 0, 0, ny))
+	assert.Equal(t, time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), next.UTC())
+	next = s.Next(next)
+	assert.Equal(t, time.Date(2024, 3, 11, 6, 30, 0, 0, time.UTC), next.UTC())
+
+	// 跳过的区间内没有触发时刻时不受影响
+	s, err = Parse("0 3 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), s.Next(time.Date(2024, 3, 10, 0, 0, 0, 0, ny)).UTC())
+
+	// 2024-11-03 02:00 EDT 回拨到 01:00 EST，01:30 出现两次，只触发第一次
+	s, err = Parse("30 1 * * *")
+	require.NoError(t, err)
+	next = s.Next(time.Date(2024, 11, 3, 0, 0, 0, 0, ny))
+	assert.Equal(t, time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), next.UTC())
+	next = s.Next(next)
+	assert.Equal(t, time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC), next.UTC())
+
+	// 小时为通配的计划按实际经过的时间触发，重复的一小时内照常触发
+	s, err = Parse("*/30 * * * *")
+	require.NoError(t, err)
+	next = s.Next(time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC).In(ny))
+	assert.Equal(t, time.Date(2024, 11, 3, 6, 0, 0, 0, time.UTC), next.UTC())
+
+	// 圣地亚哥 2024-09-08 零点跳到 01:00，零点触发的计划于 01:00 触发
+	santiago, err := time.LoadLocation("America/Santiago")
+	require.NoError(t, err)
+	s, err = Parse("@daily")
+	require.NoError(t, err)
+	next = s.Next(time.Date(2024, 9, 7, 12, 0, 0, 0, santiago))
+	assert.Equal(t, time.Date(2024, 9, 8, 1, 0, 0, 0, santiago), next)
+}
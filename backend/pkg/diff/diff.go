@@ -0,0 +1,188 @@
+// Package diff 按行比较文本并生成统一格式（unified）的差异
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCells 逐行比较的最大计算量（两侧不同部分行数之积），超过时把不同部分整体视为替换
+const maxCells = 4 << 20
+
+// Op 差异行的类型
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Line 差异中的一行
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines 计算从 a 到 b 的逐行差异
+func Lines(a, b string) []Line {
+	x, y := splitLines(a), splitLines(b)
+
+	// 去掉相同的首尾，只比较中间不同的部分
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for _, s := range x[:prefix] {
+		lines = append(lines, Line{Equal, s})
+	}
+	lines = append(lines, lcsDiff(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, s := range x[len(x)-suffix:] {
+		lines = append(lines, Line{Equal, s})
+	}
+	return lines
+}
+
+// lcsDiff 基于最长公共子序列的逐行差异
+func lcsDiff(x, y []string) []Line {
+	var lines []Line
+	if len(x)*len(y) > maxCells {
+		for _, s := range x {
+			lines = append(lines, Line{Delete, s})
+		}
+		for _, s := range y {
+			lines = append(lines, Line{Insert, s})
+		}
+		return lines
+	}
+
+	// lcs[i][j] 为 x[i:] 与 y[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, Line{Equal, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Delete, x[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, Line{Delete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, Line{Insert, y[j]})
+	}
+	return lines
+}
+
+// Unified 生成统一格式的差异，context 为每处变更前后保留的相同行数；没有差异时返回空字符串
+func Unified(fromName, toName, a, b string, context int) string {
+	lines := Lines(a, b)
+
+	var out strings.Builder
+	// 依次找出变更行，前后各扩展 context 行，相互重叠的合并为一个 hunk
+	for start := 0; start < len(lines); {
+		first := start
+		for first < len(lines) && lines[first].Op == Equal {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+
+		begin := max(first-context, start)
+		end := first
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+			// 相同行超过 2*context 时 hunk 结束
+			next := end
+			for next < len(lines) && lines[next].Op == Equal {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = next
+		}
+		writeHunk(&out, lines, begin, end)
+		start = end
+	}
+	return out.String()
+}
+
+// writeHunk 写入 lines[begin:end] 组成的 hunk，行号从1开始
+func writeHunk(out *strings.Builder, lines []Line, begin, end int) {
+	fromLine, toLine := 1, 1
+	for _, l := range lines[:begin] {
+		if l.Op != Insert {
+			fromLine++
+		}
+		if l.Op != Delete {
+			toLine++
+		}
+	}
+	fromCount, toCount := 0, 0
+	for _, l := range lines[begin:end] {
+		if l.Op != Insert {
+			fromCount++
+		}
+		if l.Op != Delete {
+			toCount++
+		}
+	}
+	// 统一格式中行数为0时起始行号指向前一行
+	if fromCount == 0 {
+		fromLine--
+	}
+	if toCount == 0 {
+		toLine--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+	for _, l := range lines[begin:end] {
+		out.WriteByte(byte(l.Op))
+		out.WriteString(l.Text)
+		out.WriteByte('\n')
+	}
+}
+
+// splitLines 按行拆分文本，末尾的换行不产生空行
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
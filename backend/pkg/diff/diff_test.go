@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	lines := Lines("a\nb\nc\n", "a\nc\nd\n")
+	assert.Equal(t, []Line{
+		{Equal, "a"},
+		{Delete, "b"},
+		{Equal, "c"},
+		{Insert, "d"},
+	}, lines)
+
+	assert.Empty(t, Lines("", ""))
+	assert.Equal(t, []Line{{Insert, "x"}}, Lines("", "x"))
+}
+
+func TestUnified(t *testing.T) {
+	assert.Equal(t, "", Unified("v1", "v2", "same\n", "same\n", 3))
+
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		line := string(rune('a' + i - 1))
+		a = append(a, line)
+		switch i {
+		case 3:
+			b = append(b, "C")
+		case 17:
+		default:
+			b = append(b, line)
+		}
+	}
+
+	got := Unified("v1", "v2", strings.Join(a, "\n"), strings.Join(b, "\n"), 2)
+	want := `--- v1
++++ v2
+@@ -1,5 +1,5 @@
+ a
+ b
+-c
++C
+ d
+ e
+@@ -15,5 +15,4 @@
+ o
+ p
+-q
+ r
+ s
+`
+	assert.Equal(t, want, got)
+
+	// 新增内容到空文本
+	assert.Equal(t, "--- v1\n+++ v2\n@@ -0,0 +1,1 @@\n+x\n", Unified("v1", "v2", "", "x", 3))
+}
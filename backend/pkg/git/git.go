@@ -0,0 +1,233 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mirrorLocks 同一镜像目录的操作串行执行
+var mirrorLocks sync.Map
+
+// Commit 提交信息
+type Commit struct {
+	Hash        string    `json:"hash"`
+	Author      string    `json:"author"`
+	Email       string    `json:"email"`
+	CommittedAt time.Time `json:"committed_at"`
+	Subject     string    `json:"subject"`
+}
+
+// FileStat 单个文件的变更统计
+type FileStat struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary"`
+}
+
+// DiffStat 变更统计
+type DiffStat struct {
+	Files     []FileStat `json:"files"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+}
+
+// Mirror 远程仓库的本地镜像克隆
+type Mirror struct {
+	url string
+	dir string
+}
+
+// NewMirror 创建仓库镜像，镜像目录由仓库地址决定
+func NewMirror(baseDir, url string) *Mirror {
+	sum := sha1.Sum([]byte(url))
+	return &Mirror{
+		url: url,
+		dir: filepath.Join(baseDir, hex.EncodeToString(sum[:])+".git"),
+	}
+}
+
+// Dir 镜像目录
+func (m *Mirror) Dir() string {
+	return m.dir
+}
+
+// Sync 同步镜像，不存在时先克隆
+func (m *Mirror) Sync(ctx context.Context) error {
+	lock, _ := mirrorLocks.LoadOrStore(m.dir, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, err := os.Stat(m.dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(m.dir), 0755); err != nil {
+			return fmt.Errorf("创建镜像目录失败: %w", err)
+		}
+		if _, err := run(ctx, "", "clone", "--mirror", "--", m.url, m.dir); err != nil {
+			os.RemoveAll(m.dir)
+			return fmt.Errorf("克隆仓库失败: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := m.git(ctx, "remote", "update", "--prune"); err != nil {
+		return fmt.Errorf("更新仓库镜像失败: %w", err)
+	}
+	return nil
+}
+
+// checkRef 拒绝空引用和以 - 开头的引用，避免被git当作选项解析
+func checkRef(refs ...string) error {
+	for _, ref := range refs {
+		if ref == "" || strings.HasPrefix(ref, "-") {
+			return fmt.Errorf("无效的引用 %q", ref)
+		}
+	}
+	return nil
+}
+
+// ResolveCommit 将分支、标签或提交解析为完整提交哈希
+func (m *Mirror) ResolveCommit(ctx context.Context, ref string) (string, error) {
+	if err := checkRef(ref); err != nil {
+		return "", err
+	}
+	out, err := m.git(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("无法解析引用 %s", ref)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// GetCommit 获取单个提交信息
+func (m *Mirror) GetCommit(ctx context.Context, ref string) (*Commit, error) {
+	if err := checkRef(ref); err != nil {
+		return nil, err
+	}
+	out, err := m.git(ctx, "log", "-1", "--format="+logFormat, "--end-of-options", ref)
+	if err != nil {
+		return nil, fmt.Errorf("查询提交失败: %w", err)
+	}
+	commits := parseLog(out)
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("提交 %s 不存在", ref)
+	}
+	return &commits[0], nil
+}
+
+// Log 获取 from（不含）到 to 之间的提交，from 为空时返回 to 的历史
+func (m *Mirror) Log(ctx context.Context, from, to string, limit int) ([]Commit, error) {
+	if err := checkRef(to); err != nil {
+		return nil, err
+	}
+	args := []string{"log", "--format=" + logFormat}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	args = append(args, "--end-of-options")
+	if from != "" {
+		if err := checkRef(from); err != nil {
+			return nil, err
+		}
+		args = append(args, from+".."+to)
+	} else {
+		args = append(args, to)
+	}
+
+	out, err := m.git(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询提交历史失败: %w", err)
+	}
+	return parseLog(out), nil
+}
+
+// DiffStat 获取两个提交之间的变更统计
+func (m *Mirror) DiffStat(ctx context.Context, from, to string) (*DiffStat, error) {
+	if err := checkRef(from, to); err != nil {
+		return nil, err
+	}
+	out, err := m.git(ctx, "diff", "--numstat", "--end-of-options", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("查询变更统计失败: %w", err)
+	}
+	return parseNumstat(out), nil
+}
+
+// git 在镜像目录中执行git命令
+func (m *Mirror) git(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, m.dir, args...)
+}
+
+// logFormat 提交日志格式，字段间以 \x1f 分隔
+const logFormat = "%H%x1f%an%x1f%ae%x1f%ct%x1f%s"
+
+// run 执行git命令
+func run(ctx context.Context, gitDir string, args ...string) (string, error) {
+	name := args[0]
+	if gitDir != "" {
+		args = append([]string{"--git-dir", gitDir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %s", name, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// parseLog 解析提交日志
+func parseLog(out string) []Commit {
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(fields[3], 10, 64)
+		commits = append(commits, Commit{
+			Hash:        fields[0],
+			Author:      fields[1],
+			Email:       fields[2],
+			CommittedAt: time.Unix(ts, 0),
+			Subject:     fields[4],
+		})
+	}
+	return commits
+}
+
+// parseNumstat 解析 git diff --numstat 输出
+func parseNumstat(out string) *DiffStat {
+	stat := &DiffStat{Files: []FileStat{}}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		file := FileStat{Path: fields[2]}
+		if fields[0] == "-" && fields[1] == "-" {
+			file.Binary = true
+		} else {
+			file.Additions, _ = strconv.Atoi(fields[0])
+			file.Deletions, _ = strconv.Atoi(fields[1])
+		}
+
+		stat.Files = append(stat.Files, file)
+		stat.Additions += file.Additions
+		stat.Deletions += file.Deletions
+	}
+	return stat
+}
@@ -0,0 +1,97 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRepo 创建本地裸仓库及其工作副本
+func setupRepo(t *testing.T) (bare, work string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available, skipping git tests")
+	}
+
+	root := t.TempDir()
+	bare = filepath.Join(root, "origin.git")
+	work = filepath.Join(root, "work")
+
+	gitCmd(t, root, "init", "--bare", "-b", "main", bare)
+	gitCmd(t, root, "clone", bare, work)
+	return bare, work
+}
+
+// commitFile 写入文件并提交推送
+func commitFile(t *testing.T, work, name, content, message string) {
+	require.NoError(t, os.WriteFile(filepath.Join(work, name), []byte(content), 0644))
+	gitCmd(t, work, "add", name)
+	gitCmd(t, work, "commit", "-m", message)
+	gitCmd(t, work, "push", "origin", "HEAD:main")
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=tester", "-c", "user.email=tester@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+func TestMirror(t *testing.T) {
+	bare, work := setupRepo(t)
+	ctx := context.Background()
+
+	commitFile(t, work, "app.txt", "v1\n", "initial")
+
+	mirror := NewMirror(t.TempDir(), bare)
+	require.NoError(t, mirror.Sync(ctx))
+
+	deployed, err := mirror.ResolveCommit(ctx, "main")
+	require.NoError(t, err)
+	assert.Len(t, deployed, 40)
+
+	commitFile(t, work, "app.txt", "v1\nv2\n", "add v2")
+	commitFile(t, work, "README.md", "hello\n", "add readme")
+	require.NoError(t, mirror.Sync(ctx))
+
+	t.Run("Log", func(t *testing.T) {
+		commits, err := mirror.Log(ctx, deployed, "main", 0)
+		require.NoError(t, err)
+		require.Len(t, commits, 2)
+		assert.Equal(t, "add readme", commits[0].Subject)
+		assert.Equal(t, "add v2", commits[1].Subject)
+		assert.Equal(t, "tester", commits[0].Author)
+	})
+
+	t.Run("DiffStat", func(t *testing.T) {
+		stat, err := mirror.DiffStat(ctx, deployed, "main")
+		require.NoError(t, err)
+		assert.Len(t, stat.Files, 2)
+		assert.Equal(t, 2, stat.Additions)
+		assert.Equal(t, 0, stat.Deletions)
+	})
+
+	t.Run("InvalidRef", func(t *testing.T) {
+		_, err := mirror.ResolveCommit(ctx, "no-such-branch")
+		assert.Error(t, err)
+
+		_, err = mirror.ResolveCommit(ctx, "--all")
+		assert.Error(t, err)
+
+		output := filepath.Join(t.TempDir(), "out")
+		_, err = mirror.GetCommit(ctx, "--output="+output)
+		assert.Error(t, err)
+		assert.NoFileExists(t, output)
+
+		_, err = mirror.Log(ctx, "--output="+output, "main", 0)
+		assert.Error(t, err)
+		_, err = mirror.DiffStat(ctx, deployed, "--output="+output)
+		assert.Error(t, err)
+		assert.NoFileExists(t, output)
+	})
+}
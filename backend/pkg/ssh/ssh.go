@@ -0,0 +1,233 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// pgidMarker 远程脚本启动后回显进程组ID所用的标记
+const pgidMarker = "__DEVOPS_PGID__:"
+
+// Config SSH连接配置
+type Config struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	PrivateKey string
+	Timeout    time.Duration
+}
+
+// Client SSH客户端
+type Client struct {
+	client *ssh.Client
+}
+
+// Dial 建立SSH连接
+func Dial(ctx context.Context, cfg Config) (*Client, error) {
+	var auths []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %w", err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auths = append(auths, ssh.Password(cfg.Password))
+	}
+	if len(auths) == 0 {
+		return nil, errors.New("未配置SSH认证方式")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User: cfg.Username,
+		Auth: auths,
+		// 服务器表暂未保存主机指纹，这里不做校验
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         timeout,
+	}
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接服务器 %s 失败: %w", addr, err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SSH握手失败: %w", err)
+	}
+
+	return &Client{client: ssh.NewClient(c, chans, reqs)}, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	return c.client.Close()
+}
+
+// Run 执行远程命令，返回退出码
+// ctx 取消时会关闭会话，但不保证远程进程随之退出，需要可靠终止时使用 RunGroup
+func (c *Client) Run(ctx context.Context, cmd string, stdout, stderr io.Writer) (int, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("创建SSH会话失败: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	if err := session.Start(cmd); err != nil {
+		return -1, fmt.Errorf("启动远程命令失败: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return exitCode(err)
+	case <-ctx.Done():
+		session.Close()
+		<-done
+		return -1, ctx.Err()
+	}
+}
+
+// Output 执行远程命令并返回标准输出
+func (c *Client) Output(ctx context.Context, cmd string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := c.Run(ctx, cmd, &stdout, &stderr)
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		return stdout.String(), fmt.Errorf("命令退出码 %d: %s", code, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// RunGroup 在独立的进程组中执行远程命令
+// 远程命令通过 setsid 启动，启动后 onStart 会收到进程组ID，
+// 其他会话（甚至其他后端实例）可以用 KillGroup 终止整组进程
+func (c *Client) RunGroup(ctx context.Context, cmd string, onStart func(pgid int), stdout, stderr io.Writer) (int, error) {
+	w := &pgidWriter{out: stdout, onPGID: onStart}
+	return c.Run(ctx, groupCommand(cmd), w, stderr)
+}
+
+// groupCommand 生成在新会话中执行命令的shell命令
+// 新会话的首个进程先输出自身PID（即进程组ID）再 exec 执行命令，标记一定是标准输出的第一行
+func groupCommand(cmd string) string {
+	return fmt.Sprintf("setsid sh -c %s devops %s & wait $!",
+		Quote("echo "+pgidMarker+`$$; exec sh -c "$1"`), Quote(cmd))
+}
+
+// KillGroup 向远程进程组发送信号
+func (c *Client) KillGroup(ctx context.Context, pgid int, signal string) error {
+	if pgid <= 0 {
+		return fmt.Errorf("无效的进程组ID: %d", pgid)
+	}
+	if signal == "" {
+		signal = "TERM"
+	}
+	cmd := fmt.Sprintf("kill -s %s -- -%d", signal, pgid)
+	if _, err := c.Output(ctx, cmd); err != nil {
+		return fmt.Errorf("终止进程组 %d 失败: %w", pgid, err)
+	}
+	return nil
+}
+
+// Quote 将字符串转义为单引号包裹的shell参数
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// exitCode 从会话错误中提取退出码
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	var missingErr *ssh.ExitMissingError
+	if errors.As(err, &missingErr) {
+		return -1, errors.New("远程命令未返回退出状态")
+	}
+	return -1, err
+}
+
+// pgidWriter 截获首行进程组ID标记，其余输出原样转发
+type pgidWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	onPGID func(int)
+	buf    []byte
+	done   bool
+}
+
+func (w *pgidWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return w.forward(p, len(p))
+	}
+
+	w.buf = append(w.buf, p...)
+	idx := bytes.IndexByte(w.buf, '\n')
+	if idx < 0 {
+		return len(p), nil
+	}
+
+	line := string(w.buf[:idx])
+	rest := w.buf[idx+1:]
+	w.buf = nil
+	w.done = true
+
+	if strings.HasPrefix(line, pgidMarker) {
+		if pgid, err := strconv.Atoi(strings.TrimPrefix(line, pgidMarker)); err == nil && w.onPGID != nil {
+			w.onPGID(pgid)
+		}
+	} else {
+		rest = append([]byte(line+"\n"), rest...)
+	}
+
+	return w.forward(rest, len(p))
+}
+
+func (w *pgidWriter) forward(p []byte, n int) (int, error) {
+	if w.out == nil || len(p) == 0 {
+		return n, nil
+	}
+	if _, err := w.out.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
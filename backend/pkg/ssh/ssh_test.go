@@ -0,0 +1,70 @@
+package ssh
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "'ls -la'", Quote("ls -la"))
+	assert.Equal(t, `'echo '\''hi'\'''`, Quote("echo 'hi'"))
+}
+
+func TestPGIDWriter(t *testing.T) {
+	t.Run("MarkerSplitAcrossWrites", func(t *testing.T) {
+		var out bytes.Buffer
+		var pgid int
+		w := &pgidWriter{out: &out, onPGID: func(id int) { pgid = id }}
+
+		w.Write([]byte("__DEVOPS_PG"))
+		w.Write([]byte("ID__:4321\nbuild "))
+		w.Write([]byte("done\n"))
+
+		assert.Equal(t, 4321, pgid)
+		assert.Equal(t, "build done\n", out.String())
+	})
+
+	t.Run("NoMarker", func(t *testing.T) {
+		var out bytes.Buffer
+		called := false
+		w := &pgidWriter{out: &out, onPGID: func(int) { called = true }}
+
+		w.Write([]byte("hello\nworld\n"))
+
+		assert.False(t, called)
+		assert.Equal(t, "hello\nworld\n", out.String())
+	})
+}
+
+func TestGroupCommand(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not available")
+	}
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("/proc not available")
+	}
+
+	// 命令立即输出，标记仍是第一行；命令输出自身的进程组ID（/proc/<pid>/stat 第5个字段）
+	var out bytes.Buffer
+	var pgid int
+	w := &pgidWriter{out: &out, onPGID: func(id int) { pgid = id }}
+	cmd := exec.Command("sh", "-c", groupCommand(`echo ready; cut -d' ' -f5 /proc/$$/stat; exit 3`))
+	cmd.Stdout = w
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "ready", lines[0])
+	require.Positive(t, pgid)
+	assert.Equal(t, strconv.Itoa(pgid), lines[1])
+}